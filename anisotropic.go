@@ -0,0 +1,36 @@
+// +build wasm
+
+package webgl
+
+// EXT_texture_filter_anisotropic
+const (
+	TEXTURE_MAX_ANISOTROPY_EXT     = 0x84FE
+	MAX_TEXTURE_MAX_ANISOTROPY_EXT = 0x84FF
+)
+
+// Returns the largest anisotropy level textures can be filtered with, or
+// an *ExtensionError if EXT_texture_filter_anisotropic is not supported.
+func (c *Context) MaxAnisotropy() (float32, error) {
+	_, err := c.extension("EXT_texture_filter_anisotropic", "WEBKIT_EXT_texture_filter_anisotropic", "MOZ_EXT_texture_filter_anisotropic")
+	if err != nil {
+		return 1, err
+	}
+	return float32(c.callResult("getParameter", MAX_TEXTURE_MAX_ANISOTROPY_EXT).Float()), nil
+}
+
+// Sets the anisotropy level of the texture bound to target, clamped to
+// what the device supports. Nothing is done if anisotropic filtering is
+// not supported or level is 1 or less.
+func (c *Context) SetAnisotropy(target int, level float32) {
+	if level <= 1 {
+		return
+	}
+	max, err := c.MaxAnisotropy()
+	if err != nil {
+		return
+	}
+	if level > max {
+		level = max
+	}
+	c.TexParameterf(target, TEXTURE_MAX_ANISOTROPY_EXT, level)
+}
@@ -0,0 +1,353 @@
+// Package basis transcodes Basis Universal (.basis) textures to the GPU
+// compressed formats that WebGL supports, or to plain RGBA, in pure Go.
+//
+// Only ETC1S encoded files can be transcoded so far. UASTC transcoding is
+// not implemented yet: UASTC files are parsed, so their sizes can be read,
+// but return ErrUASTC when transcoded. Until it is, encode textures with
+// basisu's default ETC1S mode, or transcode UASTC files with the Basis
+// Universal JavaScript transcoder.
+package basis
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	headerSize    = 77
+	sliceDescSize = 23
+	signature     = 0x4273
+
+	// Header flags
+	flagETC1S          = 1
+	flagYFlipped       = 2
+	flagHasAlphaSlices = 4
+
+	// Slice flags
+	sliceHasAlpha = 1
+
+	// Texture formats
+	texFormatETC1S = 0
+	texFormatUASTC = 1
+
+	// Texture types
+	texTypeVideoFrames = 3
+)
+
+// ErrUASTC is returned when transcoding a file encoded as UASTC, which
+// this package can not transcode yet.
+var ErrUASTC = errors.New("basis: UASTC transcoding is not implemented")
+
+type sliceDesc struct {
+	image   int
+	level   int
+	alpha   bool
+	width   int
+	height  int
+	blocksX int
+	blocksY int
+	offset  int
+	size    int
+}
+
+// endpoint is an ETC1S color endpoint: a 5 bit per channel base color and
+// an index into the ETC1 intensity table.
+type endpoint struct {
+	color5 [3]uint8
+	inten  uint8
+}
+
+// selector holds the 2 bit selectors of a 4x4 block, one byte per row with
+// the selector of column x at bit 2*x. Selector values are ordered from
+// darkest to brightest.
+type selector [4]uint8
+
+func (s *selector) get(x, y int) uint8 {
+	return (s[y] >> uint(x*2)) & 3
+}
+
+// File is a parsed .basis file.
+type File struct {
+	// UASTC is true if the file is encoded as UASTC rather than ETC1S.
+	UASTC bool
+
+	// HasAlpha is true if the file has alpha.
+	HasAlpha bool
+
+	// YFlipped is true if the images were flipped vertically on encoding.
+	YFlipped bool
+
+	data   []byte
+	slices []sliceDesc
+	images int
+
+	endpoints []endpoint
+	selectors []selector
+
+	endpointPred       *huffman
+	deltaEndpoint      *huffman
+	selectorModel      *huffman
+	selectorHistoryRLE *huffman
+	historySize        int
+}
+
+// Parse reads the header, slice descriptions and codebooks of a .basis
+// file. Transcoding is done later, one mip level at a time. The returned
+// File refers to data rather than copying it.
+func Parse(data []byte) (*File, error) {
+	if len(data) < headerSize {
+		return nil, errors.New("basis: header is truncated")
+	}
+	le := binary.LittleEndian
+	u24 := func(b []byte) int {
+		return int(b[0]) | int(b[1])<<8 | int(b[2])<<16
+	}
+	if le.Uint16(data) != signature {
+		return nil, errors.New("basis: not a .basis file")
+	}
+	if int(le.Uint16(data[4:])) != headerSize {
+		return nil, errors.New("basis: unsupported header size")
+	}
+	totalSlices := u24(data[14:])
+	totalImages := u24(data[17:])
+	texFormat := data[20]
+	flags := le.Uint16(data[21:])
+	texType := data[23]
+	totalEndpoints := int(le.Uint16(data[39:]))
+	endpointOfs := int(le.Uint32(data[41:]))
+	endpointSize := u24(data[45:])
+	totalSelectors := int(le.Uint16(data[48:]))
+	selectorOfs := int(le.Uint32(data[50:]))
+	selectorSize := u24(data[54:])
+	tablesOfs := int(le.Uint32(data[57:]))
+	tablesSize := int(le.Uint32(data[61:]))
+	sliceDescOfs := int(le.Uint32(data[65:]))
+
+	f := &File{
+		UASTC:    texFormat == texFormatUASTC,
+		HasAlpha: flags&flagHasAlphaSlices != 0,
+		YFlipped: flags&flagYFlipped != 0,
+		data:     data,
+		images:   totalImages,
+	}
+	if texFormat != texFormatETC1S && texFormat != texFormatUASTC {
+		return nil, errors.New("basis: unknown texture format")
+	}
+	if texType == texTypeVideoFrames {
+		return nil, errors.New("basis: video textures are not supported")
+	}
+
+	if totalSlices == 0 || sliceDescOfs < 0 || sliceDescOfs+totalSlices*sliceDescSize > len(data) {
+		return nil, errCorrupt
+	}
+	for i := 0; i < totalSlices; i++ {
+		b := data[sliceDescOfs+i*sliceDescSize:]
+		s := sliceDesc{
+			image:   u24(b),
+			level:   int(b[3]),
+			alpha:   b[4]&sliceHasAlpha != 0,
+			width:   int(le.Uint16(b[5:])),
+			height:  int(le.Uint16(b[7:])),
+			blocksX: int(le.Uint16(b[9:])),
+			blocksY: int(le.Uint16(b[11:])),
+			offset:  int(le.Uint32(b[13:])),
+			size:    int(le.Uint32(b[17:])),
+		}
+		if s.offset < 0 || s.size < 0 || s.offset+s.size > len(data) ||
+			s.blocksX*4 < s.width || s.blocksY*4 < s.height {
+			return nil, errCorrupt
+		}
+		f.slices = append(f.slices, s)
+	}
+
+	if f.UASTC {
+		return f, nil
+	}
+	if flags&flagETC1S == 0 && texFormat != texFormatETC1S {
+		return nil, errCorrupt
+	}
+	section := func(ofs, size int) ([]byte, error) {
+		if ofs < 0 || size <= 0 || ofs+size > len(data) {
+			return nil, errCorrupt
+		}
+		return data[ofs : ofs+size], nil
+	}
+	endpointData, err := section(endpointOfs, endpointSize)
+	if err != nil {
+		return nil, err
+	}
+	selectorData, err := section(selectorOfs, selectorSize)
+	if err != nil {
+		return nil, err
+	}
+	tableData, err := section(tablesOfs, tablesSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.readEndpoints(endpointData, totalEndpoints); err != nil {
+		return nil, err
+	}
+	if err := f.readSelectors(selectorData, totalSelectors); err != nil {
+		return nil, err
+	}
+	if err := f.readTables(tableData); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Returns how many images the file holds.
+func (f *File) ImageCount() int {
+	return f.images
+}
+
+// Returns how many mip levels an image has.
+func (f *File) LevelCount(image int) int {
+	n := 0
+	for _, s := range f.slices {
+		if s.image == image && !s.alpha && s.level+1 > n {
+			n = s.level + 1
+		}
+	}
+	return n
+}
+
+// Returns the size of a mip level of an image, or zeros if there is no
+// such level.
+func (f *File) Size(image, level int) (width, height int) {
+	if s := f.slice(image, level, false); s != nil {
+		return s.width, s.height
+	}
+	return 0, 0
+}
+
+// Returns the color or alpha slice of a mip level of an image.
+func (f *File) slice(image, level int, alpha bool) *sliceDesc {
+	for i := range f.slices {
+		s := &f.slices[i]
+		if s.image == image && s.level == level && s.alpha == alpha {
+			return s
+		}
+	}
+	return nil
+}
+
+// readEndpoints decodes the endpoint codebook. Each channel of each
+// endpoint is stored as a Huffman coded delta from the previous endpoint,
+// using one of three tables depending on the previous value.
+func (f *File) readEndpoints(data []byte, count int) error {
+	r := newBitReader(data)
+	var models [3]*huffman
+	for i := range models {
+		m, err := r.readHuffman()
+		if err != nil {
+			return err
+		}
+		models[i] = m
+	}
+	intenModel, err := r.readHuffman()
+	if err != nil {
+		return err
+	}
+	grayscale := r.bits(1) != 0
+
+	channels := 3
+	if grayscale {
+		channels = 1
+	}
+	prevColor := [3]int{16, 16, 16}
+	prevInten := 0
+	f.endpoints = make([]endpoint, count)
+	for i := range f.endpoints {
+		e := &f.endpoints[i]
+		delta, err := r.decode(intenModel)
+		if err != nil {
+			return err
+		}
+		prevInten = (prevInten + delta) & 7
+		e.inten = uint8(prevInten)
+		for c := 0; c < channels; c++ {
+			m := models[2]
+			if prevColor[c] <= 9 {
+				m = models[0]
+			} else if prevColor[c] <= 21 {
+				m = models[1]
+			}
+			delta, err := r.decode(m)
+			if err != nil {
+				return err
+			}
+			prevColor[c] = (prevColor[c] + delta) & 31
+			e.color5[c] = uint8(prevColor[c])
+		}
+		if grayscale {
+			e.color5[1], e.color5[2] = e.color5[0], e.color5[0]
+		}
+	}
+	if r.overrun {
+		return errCorrupt
+	}
+	return nil
+}
+
+// readSelectors decodes the selector codebook, which is either stored raw
+// or as Huffman coded XOR deltas from the previous selector.
+func (f *File) readSelectors(data []byte, count int) error {
+	r := newBitReader(data)
+	if r.bits(1) != 0 {
+		return errors.New("basis: files using the global selector codebook are not supported")
+	}
+	if r.bits(1) != 0 {
+		return errors.New("basis: files using a hybrid selector codebook are not supported")
+	}
+	raw := r.bits(1) != 0
+	f.selectors = make([]selector, count)
+	if raw {
+		for i := range f.selectors {
+			for y := 0; y < 4; y++ {
+				f.selectors[i][y] = uint8(r.bits(8))
+			}
+		}
+	} else {
+		model, err := r.readHuffman()
+		if err != nil {
+			return err
+		}
+		var prev selector
+		for i := range f.selectors {
+			for y := 0; y < 4; y++ {
+				if i == 0 {
+					prev[y] = uint8(r.bits(8))
+					continue
+				}
+				sym, err := r.decode(model)
+				if err != nil {
+					return err
+				}
+				prev[y] ^= uint8(sym)
+			}
+			f.selectors[i] = prev
+		}
+	}
+	if r.overrun {
+		return errCorrupt
+	}
+	return nil
+}
+
+// readTables reads the Huffman tables used to decode the slices.
+func (f *File) readTables(data []byte) error {
+	r := newBitReader(data)
+	for _, m := range []**huffman{&f.endpointPred, &f.deltaEndpoint, &f.selectorModel, &f.selectorHistoryRLE} {
+		h, err := r.readHuffman()
+		if err != nil {
+			return err
+		}
+		*m = h
+	}
+	f.historySize = int(r.bits(13))
+	if f.historySize == 0 || r.overrun {
+		return errCorrupt
+	}
+	return nil
+}
@@ -0,0 +1,843 @@
+package basis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/justinclift/webgl/compressed"
+)
+
+var update = flag.Bool("update", false, "rewrite the files in testdata")
+
+// sliceOf builds a slice from rows of endpoint and selector indices, one
+// digit per block.
+func sliceOf(width, height int, endpoints, selectors []string) testSlice {
+	s := testSlice{width: width, height: height}
+	if len(endpoints) != s.blocksY() || len(selectors) != s.blocksY() {
+		panic("basis test: wrong number of rows")
+	}
+	for y := range endpoints {
+		if len(endpoints[y]) != s.blocksX() || len(selectors[y]) != s.blocksX() {
+			panic("basis test: wrong number of blocks in a row")
+		}
+		for x := range endpoints[y] {
+			s.blocks = append(s.blocks, block{int(endpoints[y][x] - '0'), int(selectors[y][x] - '0')})
+		}
+	}
+	return s
+}
+
+func alphaOf(width, height int, endpoints, selectors []string) *testSlice {
+	s := sliceOf(width, height, endpoints, selectors)
+	return &s
+}
+
+// testFiles returns the content of the files in testdata.
+func testFiles() []*testFile {
+	// A 64x64 image with long runs, to use the repeat codes and the
+	// variable length run lengths.
+	long := testSlice{width: 64, height: 64}
+	for i := 0; i < 16*16; i++ {
+		long.blocks = append(long.blocks, block{2, 4})
+	}
+	long.blocks[len(long.blocks)-1].selector = 1
+
+	return []*testFile{
+		{
+			name: "etc1s.basis",
+			endpoints: []endpoint{
+				{[3]uint8{0, 0, 0}, 0},
+				{[3]uint8{31, 0, 0}, 2},
+				{[3]uint8{4, 20, 9}, 5},
+				{[3]uint8{16, 16, 16}, 7},
+				{[3]uint8{25, 12, 30}, 3},
+				{[3]uint8{10, 28, 3}, 1},
+				{[3]uint8{31, 31, 31}, 4},
+			},
+			selectors: []selector{
+				{0x00, 0x00, 0x00, 0x00},
+				{0xFF, 0xFF, 0xFF, 0xFF},
+				{0xE4, 0xE4, 0xE4, 0xE4},
+				{0x1B, 0x4E, 0xB1, 0xE4},
+				{0x55, 0xAA, 0x55, 0xAA},
+			},
+			historySize: 8,
+			images: [][]testLevel{
+				{
+					{color: sliceOf(32, 24,
+						[]string{"33333333", "33333333", "14025556", "11402656", "66666666", "66666666"},
+						[]string{"00001212", "34341111", "22222000", "01234012", "44444444", "30303030"})},
+					{color: sliceOf(16, 12,
+						[]string{"0123", "4561", "2222"},
+						[]string{"0123", "4444", "1032"})},
+					{color: sliceOf(8, 6, []string{"56", "65"}, []string{"21", "12"})},
+					{color: sliceOf(4, 3, []string{"4"}, []string{"3"})},
+				},
+				{{color: long}},
+			},
+		},
+		{
+			name: "etc1s_alpha.basis",
+			endpoints: []endpoint{
+				{[3]uint8{2, 8, 20}, 6},
+				{[3]uint8{30, 20, 2}, 0},
+				{[3]uint8{12, 12, 12}, 3},
+				{[3]uint8{0, 31, 0}, 7},
+			},
+			selectors: []selector{
+				{0x00, 0x00, 0x00, 0x00},
+				{0xFF, 0xFF, 0xFF, 0xFF},
+				{0x1B, 0x1B, 0x1B, 0x1B},
+				{0xE4, 0x1B, 0xE4, 0x1B},
+			},
+			historySize: 6,
+			images: [][]testLevel{{
+				{
+					color: sliceOf(10, 6, []string{"012", "301"}, []string{"012", "310"}),
+					alpha: alphaOf(10, 6, []string{"333", "021"}, []string{"101", "232"}),
+				},
+				{
+					color: sliceOf(5, 3, []string{"21"}, []string{"30"}),
+					alpha: alphaOf(5, 3, []string{"03"}, []string{"12"}),
+				},
+			}},
+		},
+		{
+			name: "etc1s_gray.basis",
+			endpoints: []endpoint{
+				{[3]uint8{5, 5, 5}, 1},
+				{[3]uint8{20, 20, 20}, 5},
+				{[3]uint8{31, 31, 31}, 2},
+			},
+			selectors: []selector{
+				{0x00, 0x55, 0xAA, 0xFF},
+				{0xE4, 0x00, 0xFF, 0x1B},
+				{0x12, 0x34, 0x56, 0x78},
+			},
+			grayscale:    true,
+			rawSelectors: true,
+			yFlipped:     true,
+			historySize:  4,
+			images: [][]testLevel{{
+				{color: sliceOf(8, 8, []string{"01", "21"}, []string{"01", "22"})},
+			}},
+		},
+		{
+			name: "uastc.basis",
+			uastc: []byte{
+				0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF,
+				0x0F, 0x1E, 0x2D, 0x3C, 0x4B, 0x5A, 0x69, 0x78, 0x87, 0x96, 0xA5, 0xB4, 0xC3, 0xD2, 0xE1, 0xF0,
+			},
+			images: [][]testLevel{{{color: testSlice{width: 8, height: 4}}}},
+		},
+	}
+}
+
+func readTestFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func parseTestFile(t *testing.T, name string) *File {
+	t.Helper()
+	f, err := Parse(readTestFile(t, name))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return f
+}
+
+// The files in testdata are made by the encoder in writer_test.go, so they
+// test the decoder against this package's own reading of the format. The
+// files made by upstream basisu are checked by TestReference. This checks
+// that they are up to date, or rewrites them with -update.
+func TestTestdata(t *testing.T) {
+	for _, tf := range testFiles() {
+		data := tf.encode()
+		path := filepath.Join("testdata", tf.name)
+		if *update {
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if !bytes.Equal(readTestFile(t, tf.name), data) {
+			t.Errorf("%s is out of date; run go test -update", path)
+		}
+	}
+}
+
+// Checks that the test files use every way a slice can code its blocks,
+// so that the tests below cover all of decodeSlice.
+func TestTestdataCoverage(t *testing.T) {
+	var predKinds [4]bool
+	var predRepeat, history, historyRLE, longRLE bool
+	for _, tf := range testFiles() {
+		if tf.uastc != nil {
+			continue
+		}
+		for _, levels := range tf.images {
+			for _, l := range levels {
+				slices := []*testSlice{&l.color}
+				if l.alpha != nil {
+					slices = append(slices, l.alpha)
+				}
+				for _, s := range slices {
+					for _, c := range tf.sliceSymbols(s) {
+						switch c.table {
+						case tableEndpointPred:
+							if c.sym == endpointPredRepeatLast {
+								predRepeat = true
+								continue
+							}
+							for i := uint(0); i < 4; i++ {
+								predKinds[c.sym>>(2*i)&3] = true
+							}
+						case tableSelector:
+							switch {
+							case c.sym == len(tf.selectors)+tf.historySize:
+								historyRLE = true
+							case c.sym > len(tf.selectors):
+								history = true
+							}
+						case tableSelectorRLE:
+							if c.sym == selectorHistoryRLETotal-1 {
+								longRLE = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	for i, used := range predKinds {
+		if !used {
+			t.Errorf("endpoint prediction %d is not used", i)
+		}
+	}
+	if !predRepeat || !history || !historyRLE || !longRLE {
+		t.Errorf("repeat: %v, history: %v, history RLE: %v, long RLE: %v; want all true",
+			predRepeat, history, historyRLE, longRLE)
+	}
+}
+
+func TestParse(t *testing.T) {
+	type level struct{ width, height int }
+	tests := []struct {
+		name     string
+		uastc    bool
+		hasAlpha bool
+		yFlipped bool
+		images   [][]level
+	}{
+		{
+			name:   "etc1s.basis",
+			images: [][]level{{{32, 24}, {16, 12}, {8, 6}, {4, 3}}, {{64, 64}}},
+		},
+		{
+			name:     "etc1s_alpha.basis",
+			hasAlpha: true,
+			images:   [][]level{{{10, 6}, {5, 3}}},
+		},
+		{
+			name:     "etc1s_gray.basis",
+			yFlipped: true,
+			images:   [][]level{{{8, 8}}},
+		},
+		{
+			name:   "uastc.basis",
+			uastc:  true,
+			images: [][]level{{{8, 4}}},
+		},
+	}
+	for _, test := range tests {
+		f := parseTestFile(t, test.name)
+		if f.UASTC != test.uastc || f.HasAlpha != test.hasAlpha || f.YFlipped != test.yFlipped {
+			t.Errorf("%s: UASTC, HasAlpha, YFlipped = %v, %v, %v; want %v, %v, %v", test.name,
+				f.UASTC, f.HasAlpha, f.YFlipped, test.uastc, test.hasAlpha, test.yFlipped)
+		}
+		if n := f.ImageCount(); n != len(test.images) {
+			t.Errorf("%s: ImageCount() = %d; want %d", test.name, n, len(test.images))
+			continue
+		}
+		for image, levels := range test.images {
+			if n := f.LevelCount(image); n != len(levels) {
+				t.Errorf("%s: LevelCount(%d) = %d; want %d", test.name, image, n, len(levels))
+				continue
+			}
+			for i, l := range levels {
+				if w, h := f.Size(image, i); w != l.width || h != l.height {
+					t.Errorf("%s: Size(%d, %d) = %d, %d; want %d, %d", test.name, image, i, w, h, l.width, l.height)
+				}
+			}
+		}
+		if w, h := f.Size(len(test.images), 0); w != 0 || h != 0 {
+			t.Errorf("%s: Size of a missing image = %d, %d; want 0, 0", test.name, w, h)
+		}
+	}
+}
+
+func TestParseSlices(t *testing.T) {
+	f := parseTestFile(t, "etc1s_alpha.basis")
+	want := []sliceDesc{
+		{image: 0, level: 0, width: 10, height: 6, blocksX: 3, blocksY: 2},
+		{image: 0, level: 0, alpha: true, width: 10, height: 6, blocksX: 3, blocksY: 2},
+		{image: 0, level: 1, width: 5, height: 3, blocksX: 2, blocksY: 1},
+		{image: 0, level: 1, alpha: true, width: 5, height: 3, blocksX: 2, blocksY: 1},
+	}
+	if len(f.slices) != len(want) {
+		t.Fatalf("got %d slices; want %d", len(f.slices), len(want))
+	}
+	end := 0
+	for i, s := range f.slices {
+		got := s
+		got.offset, got.size = 0, 0
+		if got != want[i] {
+			t.Errorf("slice %d = %+v; want %+v", i, got, want[i])
+		}
+		if i > 0 && s.offset != end {
+			t.Errorf("slice %d starts at %d; want %d", i, s.offset, end)
+		}
+		end = s.offset + s.size
+	}
+	if end != len(f.data) {
+		t.Errorf("slices end at %d; want %d", end, len(f.data))
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	data := readTestFile(t, "etc1s.basis")
+	le := binary.LittleEndian
+	desc := int(le.Uint32(data[65:]))
+	tests := []struct {
+		name   string
+		mutate func(b []byte) []byte
+	}{
+		{"truncated header", func(b []byte) []byte { return b[:headerSize-1] }},
+		{"empty", func(b []byte) []byte { return nil }},
+		{"bad signature", func(b []byte) []byte { b[0] = 'x'; return b }},
+		{"bad header size", func(b []byte) []byte { le.PutUint16(b[4:], headerSize+1); return b }},
+		{"unknown format", func(b []byte) []byte { b[20] = 7; return b }},
+		{"video", func(b []byte) []byte { b[23] = texTypeVideoFrames; return b }},
+		{"no slices", func(b []byte) []byte { b[14], b[15], b[16] = 0, 0, 0; return b }},
+		{"slice descriptions past the end", func(b []byte) []byte { le.PutUint32(b[65:], uint32(len(b))); return b }},
+		{"slice data past the end", func(b []byte) []byte { le.PutUint32(b[desc+17:], uint32(len(b))); return b }},
+		{"too few blocks", func(b []byte) []byte { le.PutUint16(b[desc+9:], 7); return b }},
+		{"no endpoint codebook", func(b []byte) []byte { b[45], b[46], b[47] = 0, 0, 0; return b }},
+		{"selector codebook past the end", func(b []byte) []byte { le.PutUint32(b[50:], uint32(len(b))); return b }},
+		{"no tables", func(b []byte) []byte { le.PutUint32(b[61:], 0); return b }},
+		{"truncated endpoint codebook", func(b []byte) []byte { b[45], b[46], b[47] = 2, 0, 0; return b }},
+		{"global selector codebook", func(b []byte) []byte { b[le.Uint32(b[50:])] |= 1; return b }},
+		{"hybrid selector codebook", func(b []byte) []byte { b[le.Uint32(b[50:])] |= 2; return b }},
+	}
+	for _, test := range tests {
+		b := test.mutate(append([]byte(nil), data...))
+		if _, err := Parse(b); err == nil {
+			t.Errorf("%s: Parse succeeded", test.name)
+		}
+	}
+}
+
+// Transcodes every level of f to every format, and returns the first
+// error. It must never panic.
+func transcodeAll(f *File) error {
+	var first error
+	for image := 0; image < f.ImageCount() && image < 4; image++ {
+		for level := 0; level < f.LevelCount(image) && level < 16; level++ {
+			for _, format := range []Format{FormatRGBA8, FormatETC1, FormatETC2, FormatBC1, FormatBC3} {
+				if _, err := f.TranscodeLevel(image, level, format); err != nil && first == nil {
+					first = err
+				}
+			}
+		}
+	}
+	return first
+}
+
+func TestCorruptDoesNotPanic(t *testing.T) {
+	for _, name := range []string{"etc1s.basis", "etc1s_alpha.basis", "etc1s_gray.basis"} {
+		data := readTestFile(t, name)
+		for n := 0; n < len(data); n++ {
+			if f, err := Parse(data[:n]); err == nil {
+				transcodeAll(f)
+			}
+		}
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 2000; i++ {
+			b := append([]byte(nil), data...)
+			for j := 0; j < 1+i%4; j++ {
+				b[headerSize+rnd.Intn(len(b)-headerSize)] ^= byte(1 + rnd.Intn(255))
+			}
+			if f, err := Parse(b); err == nil {
+				transcodeAll(f)
+			}
+		}
+	}
+}
+
+func TestCorruptSlice(t *testing.T) {
+	data := readTestFile(t, "etc1s.basis")
+	desc := int(binary.LittleEndian.Uint32(data[65:]))
+	// Cut the first slice short.
+	binary.LittleEndian.PutUint32(data[desc+17:], 1)
+	f, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.TranscodeLevel(0, 0, FormatRGBA8); err == nil {
+		t.Error("TranscodeLevel of a truncated slice succeeded")
+	}
+}
+
+func TestCodebooks(t *testing.T) {
+	for _, tf := range testFiles() {
+		if tf.uastc != nil {
+			continue
+		}
+		f := parseTestFile(t, tf.name)
+		if len(f.endpoints) != len(tf.endpoints) {
+			t.Errorf("%s: %d endpoints; want %d", tf.name, len(f.endpoints), len(tf.endpoints))
+		} else {
+			for i, e := range f.endpoints {
+				if e != tf.endpoints[i] {
+					t.Errorf("%s: endpoint %d = %v; want %v", tf.name, i, e, tf.endpoints[i])
+				}
+			}
+		}
+		if len(f.selectors) != len(tf.selectors) {
+			t.Errorf("%s: %d selectors; want %d", tf.name, len(f.selectors), len(tf.selectors))
+		} else {
+			for i, s := range f.selectors {
+				if s != tf.selectors[i] {
+					t.Errorf("%s: selector %d = %x; want %x", tf.name, i, s, tf.selectors[i])
+				}
+			}
+		}
+		if f.historySize != tf.historySize {
+			t.Errorf("%s: history size %d; want %d", tf.name, f.historySize, tf.historySize)
+		}
+	}
+}
+
+func TestDecodeSlice(t *testing.T) {
+	for _, tf := range testFiles() {
+		if tf.uastc != nil {
+			continue
+		}
+		f := parseTestFile(t, tf.name)
+		for image, levels := range tf.images {
+			for level, l := range levels {
+				check := func(want *testSlice, alpha bool) {
+					blocks, err := f.decodeSlice(f.slice(image, level, alpha))
+					if err != nil {
+						t.Errorf("%s: image %d level %d alpha %v: %v", tf.name, image, level, alpha, err)
+						return
+					}
+					for i, b := range blocks {
+						if b != want.blocks[i] {
+							t.Errorf("%s: image %d level %d alpha %v: block %d = %v; want %v",
+								tf.name, image, level, alpha, i, b, want.blocks[i])
+						}
+					}
+				}
+				check(&l.color, false)
+				if l.alpha != nil {
+					check(l.alpha, true)
+				}
+			}
+		}
+	}
+}
+
+// refIntensities is the ETC1 intensity modifier table from the Khronos
+// Data Format Specification: the small and large modifier of each table.
+var refIntensities = [8][2]int{
+	{2, 8}, {5, 17}, {9, 29}, {13, 42}, {18, 60}, {24, 80}, {33, 106}, {47, 183},
+}
+
+func refClamp(v int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return v
+}
+
+// refPixel returns the color of pixel x, y of a block, as the Basis
+// Universal format defines it.
+func refPixel(tf *testFile, b block, x, y int) [3]int {
+	return refColor(tf.endpoints[b.endpoint], tf.selectors[b.selector][y]>>uint(2*x)&3)
+}
+
+// refColor returns the color an endpoint gives selector value s.
+func refColor(e endpoint, s uint8) [3]int {
+	m := refIntensities[e.inten]
+	mod := [4]int{-m[1], -m[0], m[0], m[1]}[s]
+	var out [3]int
+	for c := 0; c < 3; c++ {
+		v := int(e.color5[c])
+		out[c] = refClamp(v<<3 | v>>2 + mod)
+	}
+	return out
+}
+
+// refImage returns the RGBA pixels of a mip level.
+func refImage(tf *testFile, l testLevel) []byte {
+	s := l.color
+	pix := make([]byte, s.width*s.height*4)
+	for y := 0; y < s.height; y++ {
+		for x := 0; x < s.width; x++ {
+			i := y/4*s.blocksX() + x/4
+			c := refPixel(tf, s.blocks[i], x%4, y%4)
+			a := 255
+			if l.alpha != nil {
+				a = refPixel(tf, l.alpha.blocks[i], x%4, y%4)[1]
+			}
+			copy(pix[(y*s.width+x)*4:], []byte{byte(c[0]), byte(c[1]), byte(c[2]), byte(a)})
+		}
+	}
+	return pix
+}
+
+// decodeETC1 decodes an ETC1 block, returning its pixels indexed [y][x].
+func decodeETC1(b []byte) (out [4][4][3]int) {
+	var base [2][3]int
+	if b[3]&2 != 0 {
+		for c := 0; c < 3; c++ {
+			v := int(b[c] >> 3)
+			d := int(b[c] & 7)
+			if d >= 4 {
+				d -= 8
+			}
+			w := v + d
+			base[0][c] = v<<3 | v>>2
+			base[1][c] = w<<3 | w>>2
+		}
+	} else {
+		for c := 0; c < 3; c++ {
+			base[0][c] = int(b[c]>>4) * 17
+			base[1][c] = int(b[c]&15) * 17
+		}
+	}
+	tables := [2]int{int(b[3] >> 5), int(b[3] >> 2 & 7)}
+	flip := b[3]&1 != 0
+	bits := binary.BigEndian.Uint32(b[4:])
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			i := uint(x*4 + y)
+			idx := (bits>>(16+i)&1)<<1 | bits>>i&1
+			half := x / 2
+			if flip {
+				half = y / 2
+			}
+			m := refIntensities[tables[half]]
+			mod := [4]int{m[0], m[1], -m[0], -m[1]}[idx]
+			for c := 0; c < 3; c++ {
+				out[y][x][c] = refClamp(base[half][c] + mod)
+			}
+		}
+	}
+	return out
+}
+
+// decodeBC1 decodes the colors of a BC1 block, returning its pixels and
+// its palette.
+func decodeBC1(b []byte) (out [4][4][3]int, palette [4][3]int) {
+	c0, c1 := binary.LittleEndian.Uint16(b), binary.LittleEndian.Uint16(b[2:])
+	palette[0], palette[1] = from565(c0), from565(c1)
+	for c := 0; c < 3; c++ {
+		if c0 > c1 {
+			palette[2][c] = (2*palette[0][c] + palette[1][c]) / 3
+			palette[3][c] = (palette[0][c] + 2*palette[1][c]) / 3
+		} else {
+			palette[2][c] = (palette[0][c] + palette[1][c]) / 2
+		}
+	}
+	bits := binary.LittleEndian.Uint32(b[4:])
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			out[y][x] = palette[bits>>uint(2*(y*4+x))&3]
+		}
+	}
+	return out, palette
+}
+
+// decodeBC4 decodes a BC4 block, returning its values and its palette.
+func decodeBC4(b []byte) (out [4][4]int, palette [8]int) {
+	a0, a1 := int(b[0]), int(b[1])
+	palette[0], palette[1] = a0, a1
+	if a0 > a1 {
+		for i := 1; i < 7; i++ {
+			palette[i+1] = ((7-i)*a0 + i*a1) / 7
+		}
+	} else {
+		for i := 1; i < 5; i++ {
+			palette[i+1] = ((5-i)*a0 + i*a1) / 5
+		}
+		palette[6], palette[7] = 0, 255
+	}
+	var bits uint64
+	for i := 0; i < 6; i++ {
+		bits |= uint64(b[2+i]) << uint(8*i)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			out[y][x] = palette[bits>>uint(3*(y*4+x))&7]
+		}
+	}
+	return out, palette
+}
+
+// Calls fn for every mip level of every ETC1S test file.
+func forEachLevel(t *testing.T, fn func(name string, f *File, tf *testFile, image, level int, l testLevel)) {
+	for _, tf := range testFiles() {
+		if tf.uastc != nil {
+			continue
+		}
+		f := parseTestFile(t, tf.name)
+		for image, levels := range tf.images {
+			for level, l := range levels {
+				fn(tf.name, f, tf, image, level, l)
+			}
+		}
+	}
+}
+
+func TestTranscodeRGBA8(t *testing.T) {
+	forEachLevel(t, func(name string, f *File, tf *testFile, image, level int, l testLevel) {
+		out, err := f.TranscodeLevel(image, level, FormatRGBA8)
+		if err != nil {
+			t.Errorf("%s: image %d level %d: %v", name, image, level, err)
+			return
+		}
+		if out.Width != l.color.width || out.Height != l.color.height {
+			t.Errorf("%s: image %d level %d: size %dx%d; want %dx%d", name, image, level,
+				out.Width, out.Height, l.color.width, l.color.height)
+		}
+		if want := refImage(tf, l); !bytes.Equal(out.Data, want) {
+			t.Errorf("%s: image %d level %d: pixels differ from the reference", name, image, level)
+		}
+	})
+}
+
+func TestTranscodeETC(t *testing.T) {
+	for _, format := range []Format{FormatETC1, FormatETC2} {
+		forEachLevel(t, func(name string, f *File, tf *testFile, image, level int, l testLevel) {
+			out, err := f.TranscodeLevel(image, level, format)
+			if err != nil {
+				t.Errorf("%s: format %d image %d level %d: %v", name, format, image, level, err)
+				return
+			}
+			s := l.color
+			if len(out.Data) != compressed.LevelSize(format.GLFormat(), s.width, s.height) {
+				t.Errorf("%s: format %d image %d level %d: %d bytes; want %d", name, format, image, level,
+					len(out.Data), compressed.LevelSize(format.GLFormat(), s.width, s.height))
+				return
+			}
+			for i, b := range s.blocks {
+				data := out.Data[i*8 : i*8+8]
+				if format == FormatETC2 {
+					// A differential block whose second color overflows is a
+					// T, H or planar block in ETC2.
+					for c := 0; c < 3; c++ {
+						v := int(data[c]>>3) + int(int8(data[c]<<5)>>5)
+						if v < 0 || v > 31 {
+							t.Errorf("%s: image %d level %d: block %d is not a valid ETC2 ETC1 block", name, image, level, i)
+						}
+					}
+				}
+				pixels := decodeETC1(data)
+				for y := 0; y < 4; y++ {
+					for x := 0; x < 4; x++ {
+						if got, want := pixels[y][x], refPixel(tf, b, x, y); got != want {
+							t.Errorf("%s: format %d image %d level %d: block %d pixel %d,%d = %v; want %v",
+								name, format, image, level, i, x, y, got, want)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// checkBC1 checks that the endpoints of a BC1 block are the darkest and
+// brightest colors of the ETC1S endpoint, and that each pixel is the
+// palette color nearest to the reference.
+func checkBC1(t *testing.T, where string, data []byte, tf *testFile, b block) {
+	t.Helper()
+	pixels, palette := decodeBC1(data)
+	e := tf.endpoints[b.endpoint]
+	for i, want := range [2][3]int{refColor(e, 3), refColor(e, 0)} {
+		for c := 0; c < 3; c++ {
+			if d := palette[i][c] - want[c]; d < -8 || d > 8 {
+				t.Errorf("%s: BC1 endpoint %d = %v; want near %v", where, i, palette[i], want)
+				break
+			}
+		}
+	}
+	dist := func(a, b [3]int) int {
+		d := 0
+		for c := 0; c < 3; c++ {
+			d += (a[c] - b[c]) * (a[c] - b[c])
+		}
+		return d
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			want := refPixel(tf, b, x, y)
+			got := pixels[y][x]
+			for _, p := range palette {
+				if dist(p, want) < dist(got, want) {
+					t.Errorf("%s: pixel %d,%d = %v; %v is nearer to %v", where, x, y, got, p, want)
+				}
+			}
+		}
+	}
+}
+
+func TestTranscodeBC1(t *testing.T) {
+	forEachLevel(t, func(name string, f *File, tf *testFile, image, level int, l testLevel) {
+		out, err := f.TranscodeLevel(image, level, FormatBC1)
+		if err != nil {
+			t.Errorf("%s: image %d level %d: %v", name, image, level, err)
+			return
+		}
+		if len(out.Data) != len(l.color.blocks)*8 {
+			t.Errorf("%s: image %d level %d: %d bytes; want %d", name, image, level, len(out.Data), len(l.color.blocks)*8)
+			return
+		}
+		for i, b := range l.color.blocks {
+			data := out.Data[i*8 : i*8+8]
+			if binary.LittleEndian.Uint16(data) < binary.LittleEndian.Uint16(data[2:]) {
+				t.Errorf("%s: image %d level %d: block %d is a three color block", name, image, level, i)
+			}
+			checkBC1(t, name, data, tf, b)
+		}
+	})
+}
+
+func TestTranscodeBC3(t *testing.T) {
+	forEachLevel(t, func(name string, f *File, tf *testFile, image, level int, l testLevel) {
+		out, err := f.TranscodeLevel(image, level, FormatBC3)
+		if err != nil {
+			t.Errorf("%s: image %d level %d: %v", name, image, level, err)
+			return
+		}
+		if len(out.Data) != len(l.color.blocks)*16 {
+			t.Errorf("%s: image %d level %d: %d bytes; want %d", name, image, level, len(out.Data), len(l.color.blocks)*16)
+			return
+		}
+		for i, b := range l.color.blocks {
+			data := out.Data[i*16 : i*16+16]
+			checkBC1(t, name, data[8:], tf, b)
+			alpha, palette := decodeBC4(data[:8])
+			for y := 0; y < 4; y++ {
+				for x := 0; x < 4; x++ {
+					want := 255
+					if l.alpha != nil {
+						want = refPixel(tf, l.alpha.blocks[i], x, y)[1]
+					}
+					got := alpha[y][x]
+					for _, p := range palette {
+						if abs(p-want) < abs(got-want) {
+							t.Errorf("%s: block %d alpha %d,%d = %d; %d is nearer to %d", name, i, x, y, got, p, want)
+						}
+					}
+					if abs(got-want) > 20 {
+						t.Errorf("%s: block %d alpha %d,%d = %d; want near %d", name, i, x, y, got, want)
+					}
+				}
+			}
+		}
+	})
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestTranscodeImage(t *testing.T) {
+	f := parseTestFile(t, "etc1s.basis")
+	img, err := f.TranscodeImage(0, FormatBC1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Format != compressed.COMPRESSED_RGB_S3TC_DXT1_EXT || img.Width != 32 || img.Height != 24 || len(img.Levels) != 4 {
+		t.Errorf("got format %#x, %dx%d with %d levels; want %#x, 32x24 with 4 levels",
+			img.Format, img.Width, img.Height, len(img.Levels), compressed.COMPRESSED_RGB_S3TC_DXT1_EXT)
+	}
+	for i, l := range img.Levels {
+		want, err := f.TranscodeLevel(0, i, FormatBC1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.Width != want.Width || l.Height != want.Height || !bytes.Equal(l.Data, want.Data) {
+			t.Errorf("level %d differs from TranscodeLevel", i)
+		}
+	}
+	if _, err := f.TranscodeImage(0, FormatRGBA8); err == nil {
+		t.Error("TranscodeImage to FormatRGBA8 succeeded")
+	}
+	if _, err := f.TranscodeImage(2, FormatBC1); err == nil {
+		t.Error("TranscodeImage of a missing image succeeded")
+	}
+	if _, err := f.TranscodeLevel(0, 4, FormatBC1); err == nil {
+		t.Error("TranscodeLevel of a missing level succeeded")
+	}
+}
+
+func TestSelectFormat(t *testing.T) {
+	const (
+		s3tc = "WEBGL_compressed_texture_s3tc"
+		etc1 = "WEBGL_compressed_texture_etc1"
+		etc  = "WEBGL_compressed_texture_etc"
+	)
+	tests := []struct {
+		extensions []string
+		hasAlpha   bool
+		want       Format
+	}{
+		{nil, false, FormatRGBA8},
+		{nil, true, FormatRGBA8},
+		{[]string{"OES_texture_float"}, false, FormatRGBA8},
+		{[]string{s3tc}, false, FormatBC1},
+		{[]string{s3tc}, true, FormatBC3},
+		{[]string{etc}, false, FormatETC2},
+		{[]string{etc}, true, FormatRGBA8},
+		{[]string{etc1}, false, FormatETC1},
+		{[]string{etc1}, true, FormatRGBA8},
+		{[]string{etc1, etc}, false, FormatETC2},
+		{[]string{etc1, etc, s3tc}, false, FormatBC1},
+	}
+	for _, test := range tests {
+		if got := SelectFormat(test.extensions, test.hasAlpha); got != test.want {
+			t.Errorf("SelectFormat(%q, %v) = %d; want %d", test.extensions, test.hasAlpha, got, test.want)
+		}
+	}
+}
+
+func TestUASTC(t *testing.T) {
+	f := parseTestFile(t, "uastc.basis")
+	for _, format := range []Format{FormatRGBA8, FormatETC1, FormatBC1, FormatBC3} {
+		if _, err := f.TranscodeLevel(0, 0, format); err != ErrUASTC {
+			t.Errorf("TranscodeLevel(%d) error = %v; want ErrUASTC", format, err)
+		}
+	}
+	if _, err := f.TranscodeImage(0, FormatBC1); err != ErrUASTC {
+		t.Errorf("TranscodeImage error = %v; want ErrUASTC", err)
+	}
+}
@@ -0,0 +1,219 @@
+package basis
+
+import (
+	"errors"
+)
+
+var errCorrupt = errors.New("basis: file is corrupt")
+
+// bitReader reads a bit stream least significant bit first, the way the
+// Basis Universal encoder writes it. Reading past the end yields zeros and
+// marks the reader as overrun.
+type bitReader struct {
+	data    []byte
+	pos     int
+	buf     uint64
+	n       uint
+	overrun bool
+}
+
+func newBitReader(data []byte) *bitReader {
+	return &bitReader{data: data}
+}
+
+func (r *bitReader) fill(n uint) {
+	for r.n < n {
+		var b byte
+		if r.pos < len(r.data) {
+			b = r.data[r.pos]
+		} else if r.pos > len(r.data)+8 {
+			r.overrun = true
+		}
+		r.pos++
+		r.buf |= uint64(b) << r.n
+		r.n += 8
+	}
+}
+
+func (r *bitReader) peek(n uint) uint32 {
+	r.fill(n)
+	return uint32(r.buf & (1<<n - 1))
+}
+
+func (r *bitReader) skip(n uint) {
+	r.buf >>= n
+	r.n -= n
+}
+
+func (r *bitReader) bits(n uint) uint32 {
+	if n == 0 {
+		return 0
+	}
+	v := r.peek(n)
+	r.skip(n)
+	return v
+}
+
+// vlc reads a variable length number made of chunks of chunkBits bits,
+// each followed by a bit saying whether another chunk follows.
+func (r *bitReader) vlc(chunkBits uint) (uint32, error) {
+	chunkSize := uint32(1) << chunkBits
+	var v uint32
+	var shift uint
+	for {
+		s := r.bits(chunkBits + 1)
+		v |= (s & (chunkSize - 1)) << shift
+		shift += chunkBits
+		if s&chunkSize == 0 {
+			return v, nil
+		}
+		if shift >= 32 {
+			return 0, errCorrupt
+		}
+	}
+}
+
+const (
+	maxCodeSize      = 16
+	maxSymbolsLog2   = 14
+	codeLengthCodes  = 21
+	smallZeroRunCode = 17
+	bigZeroRunCode   = 18
+	smallRepeatCode  = 19
+	bigRepeatCode    = 20
+)
+
+// The order in which the code lengths of the code length alphabet are
+// stored.
+var sortedCodeLengthCodes = [codeLengthCodes]uint8{
+	smallZeroRunCode, bigZeroRunCode, smallRepeatCode, bigRepeatCode,
+	0, 8, 7, 9, 6, 10, 5, 11, 4, 12, 3, 13, 2, 14, 1, 15, 16,
+}
+
+// huffman is a canonical Huffman decoding table. Codes are stored bit
+// reversed in the stream, so the table is indexed by the next maxLen bits
+// read least significant bit first. Each entry holds the symbol shifted
+// left by 5, or'ed with its code length; zero marks an unused code.
+type huffman struct {
+	table  []uint32
+	maxLen uint
+}
+
+func newHuffman(sizes []uint8) (*huffman, error) {
+	var counts [maxCodeSize + 1]int
+	maxLen := uint(0)
+	for _, s := range sizes {
+		if s > maxCodeSize {
+			return nil, errCorrupt
+		}
+		counts[s]++
+		if uint(s) > maxLen {
+			maxLen = uint(s)
+		}
+	}
+	if maxLen == 0 {
+		return nil, errCorrupt
+	}
+	// Codes are assigned in order of length, then symbol.
+	counts[0] = 0
+	var next [maxCodeSize + 1]uint32
+	code := uint32(0)
+	for bits := 1; bits <= maxCodeSize; bits++ {
+		code = (code + uint32(counts[bits-1])) << 1
+		next[bits] = code
+	}
+	h := &huffman{table: make([]uint32, 1<<maxLen), maxLen: maxLen}
+	for sym, s := range sizes {
+		if s == 0 {
+			continue
+		}
+		c := next[s]
+		next[s]++
+		if c >= 1<<s {
+			return nil, errCorrupt
+		}
+		rev := uint32(0)
+		for i := uint8(0); i < s; i++ {
+			rev |= ((c >> i) & 1) << (s - 1 - i)
+		}
+		entry := uint32(sym)<<5 | uint32(s)
+		for i := rev; i < uint32(len(h.table)); i += 1 << s {
+			h.table[i] = entry
+		}
+	}
+	return h, nil
+}
+
+// decode reads one symbol.
+func (r *bitReader) decode(h *huffman) (int, error) {
+	entry := h.table[r.peek(h.maxLen)]
+	if entry == 0 {
+		return 0, errCorrupt
+	}
+	r.skip(uint(entry & 31))
+	return int(entry >> 5), nil
+}
+
+// readHuffman reads a Huffman table, which is stored as the code lengths
+// of its symbols, themselves Huffman coded with run length codes.
+func (r *bitReader) readHuffman() (*huffman, error) {
+	total := int(r.bits(maxSymbolsLog2))
+	if total == 0 {
+		return nil, errCorrupt
+	}
+	var lengthSizes [codeLengthCodes]uint8
+	n := int(r.bits(5))
+	if n < 1 || n > codeLengthCodes {
+		return nil, errCorrupt
+	}
+	for i := 0; i < n; i++ {
+		lengthSizes[sortedCodeLengthCodes[i]] = uint8(r.bits(3))
+	}
+	lengths, err := newHuffman(lengthSizes[:])
+	if err != nil {
+		return nil, err
+	}
+	sizes := make([]uint8, total)
+	for cur := 0; cur < total; {
+		c, err := r.decode(lengths)
+		if err != nil {
+			return nil, err
+		}
+		if c <= maxCodeSize {
+			sizes[cur] = uint8(c)
+			cur++
+			continue
+		}
+		var run int
+		var size uint8
+		switch c {
+		case smallZeroRunCode:
+			run = int(r.bits(3)) + 3
+		case bigZeroRunCode:
+			run = int(r.bits(7)) + 11
+		case smallRepeatCode, bigRepeatCode:
+			if c == smallRepeatCode {
+				run = int(r.bits(2)) + 3
+			} else {
+				run = int(r.bits(7)) + 7
+			}
+			if cur == 0 || sizes[cur-1] == 0 {
+				return nil, errCorrupt
+			}
+			size = sizes[cur-1]
+		default:
+			return nil, errCorrupt
+		}
+		if cur+run > total {
+			return nil, errCorrupt
+		}
+		for i := 0; i < run; i++ {
+			sizes[cur+i] = size
+		}
+		cur += run
+	}
+	if r.overrun {
+		return nil, errCorrupt
+	}
+	return newHuffman(sizes)
+}
@@ -0,0 +1,372 @@
+package basis
+
+// Constants of the ETC1S slice encoding.
+const (
+	endpointPredRepeatLast     = 256
+	endpointPredMinRepeatCount = 3
+	endpointPredCountVLCBits   = 4
+
+	selectorHistoryRLEThreshold = 3
+	selectorHistoryRLETotal     = 64
+)
+
+// block is a decoded ETC1S block: indices into the codebooks.
+type block struct {
+	endpoint int
+	selector int
+}
+
+// historyBuffer is a move to front list of recently used selectors, where
+// a used entry only moves half way to the front.
+type historyBuffer struct {
+	values []int
+	rover  int
+}
+
+func newHistoryBuffer(n int) *historyBuffer {
+	return &historyBuffer{values: make([]int, n), rover: n / 2}
+}
+
+func (h *historyBuffer) add(v int) {
+	h.values[h.rover] = v
+	h.rover++
+	if h.rover == len(h.values) {
+		h.rover = len(h.values) / 2
+	}
+}
+
+func (h *historyBuffer) use(i int) {
+	if i > 0 {
+		h.values[i/2], h.values[i] = h.values[i], h.values[i/2]
+	}
+}
+
+type endpointPred struct {
+	endpoint int
+	predBits int
+}
+
+// decodeSlice decodes the codebook indices of every block of a slice.
+//
+// Endpoint indices are predicted from the block to the left, above or
+// above left, with the predictions of each 2x2 group of blocks coded as
+// one symbol, or coded as a delta from the previous index. Selector
+// indices are coded directly or as a reference into a history of recently
+// used selectors, with runs of the most recent one run length coded.
+func (f *File) decodeSlice(s *sliceDesc) ([]block, error) {
+	r := newBitReader(f.data[s.offset : s.offset+s.size])
+	numEndpoints := len(f.endpoints)
+	numSelectors := len(f.selectors)
+	history := newHistoryBuffer(f.historySize)
+	historyRLESymbol := numSelectors + f.historySize
+
+	blocks := make([]block, s.blocksX*s.blocksY)
+	preds := [2][]endpointPred{
+		make([]endpointPred, s.blocksX),
+		make([]endpointPred, s.blocksX),
+	}
+	var predBits, prevPredSym, predRepeat, prevEndpoint, selectorRLE int
+
+	for by := 0; by < s.blocksY; by++ {
+		cur := by & 1
+		for bx := 0; bx < s.blocksX; bx++ {
+			if bx&1 == 0 {
+				if by&1 == 0 {
+					if predRepeat > 0 {
+						predRepeat--
+						predBits = prevPredSym
+					} else {
+						sym, err := r.decode(f.endpointPred)
+						if err != nil {
+							return nil, err
+						}
+						if sym == endpointPredRepeatLast {
+							n, err := r.vlc(endpointPredCountVLCBits)
+							if err != nil {
+								return nil, err
+							}
+							predRepeat = int(n) + endpointPredMinRepeatCount - 1
+							predBits = prevPredSym
+						} else {
+							predBits = sym
+							prevPredSym = sym
+						}
+					}
+					// The odd row below uses the upper 4 bits.
+					preds[cur^1][bx].predBits = predBits >> 4
+				} else {
+					predBits = preds[cur][bx].predBits
+				}
+			}
+
+			var endpoint int
+			pred := predBits & 3
+			predBits >>= 2
+			switch pred {
+			case 0:
+				endpoint = prevEndpoint
+			case 1:
+				endpoint = preds[cur^1][bx].endpoint
+			case 2:
+				if bx == 0 {
+					return nil, errCorrupt
+				}
+				endpoint = preds[cur^1][bx-1].endpoint
+			default:
+				delta, err := r.decode(f.deltaEndpoint)
+				if err != nil {
+					return nil, err
+				}
+				endpoint = delta + prevEndpoint
+				if endpoint >= numEndpoints {
+					endpoint -= numEndpoints
+				}
+			}
+			if endpoint >= numEndpoints {
+				return nil, errCorrupt
+			}
+			preds[cur][bx].endpoint = endpoint
+			prevEndpoint = endpoint
+
+			var sym int
+			if selectorRLE > 0 {
+				selectorRLE--
+				sym = numSelectors
+			} else {
+				var err error
+				sym, err = r.decode(f.selectorModel)
+				if err != nil {
+					return nil, err
+				}
+				if sym == historyRLESymbol {
+					run, err := r.decode(f.selectorHistoryRLE)
+					if err != nil {
+						return nil, err
+					}
+					if run == selectorHistoryRLETotal-1 {
+						n, err := r.vlc(7)
+						if err != nil {
+							return nil, err
+						}
+						selectorRLE = int(n) + selectorHistoryRLEThreshold
+					} else {
+						selectorRLE = run + selectorHistoryRLEThreshold
+					}
+					if selectorRLE > len(blocks) {
+						return nil, errCorrupt
+					}
+					sym = numSelectors
+					selectorRLE--
+				}
+			}
+			var sel int
+			if sym >= numSelectors {
+				i := sym - numSelectors
+				if i >= f.historySize {
+					return nil, errCorrupt
+				}
+				sel = history.values[i]
+				history.use(i)
+			} else {
+				sel = sym
+				history.add(sel)
+			}
+
+			blocks[by*s.blocksX+bx] = block{endpoint, sel}
+		}
+	}
+	if r.overrun {
+		return nil, errCorrupt
+	}
+	return blocks, nil
+}
+
+// etc1Modifiers holds the ETC1 intensity tables, ordered by selector from
+// darkest to brightest.
+var etc1Modifiers = [8][4]int{
+	{-8, -2, 2, 8},
+	{-17, -5, 5, 17},
+	{-29, -9, 9, 29},
+	{-42, -13, 13, 42},
+	{-60, -18, 18, 60},
+	{-80, -24, 24, 80},
+	{-106, -33, 33, 106},
+	{-183, -47, 47, 183},
+}
+
+// etc1Selectors maps selector values to ETC1 pixel indices.
+var etc1Selectors = [4]uint32{3, 2, 0, 1}
+
+func clamp255(v int) uint8 {
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// colors returns the four colors an endpoint can produce, one for each
+// selector value.
+func (e *endpoint) colors() [4][3]uint8 {
+	var base [3]int
+	for c := 0; c < 3; c++ {
+		base[c] = int(e.color5[c]<<3 | e.color5[c]>>2)
+	}
+	var out [4][3]uint8
+	for i, m := range etc1Modifiers[e.inten] {
+		for c := 0; c < 3; c++ {
+			out[i][c] = clamp255(base[c] + m)
+		}
+	}
+	return out
+}
+
+// etc1Block writes a block as an ETC1 block in differential mode with the
+// same color and table in both halves.
+func (f *File) etc1Block(b block, dst []byte) {
+	e := &f.endpoints[b.endpoint]
+	sel := &f.selectors[b.selector]
+	dst[0] = e.color5[0] << 3
+	dst[1] = e.color5[1] << 3
+	dst[2] = e.color5[2] << 3
+	dst[3] = e.inten<<5 | e.inten<<2 | 2
+	var bits uint32
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			idx := etc1Selectors[sel.get(x, y)]
+			i := uint(x*4 + y)
+			bits |= (idx>>1)<<(16+i) | (idx&1)<<i
+		}
+	}
+	dst[4] = byte(bits >> 24)
+	dst[5] = byte(bits >> 16)
+	dst[6] = byte(bits >> 8)
+	dst[7] = byte(bits)
+}
+
+func to565(c [3]uint8) uint16 {
+	return uint16(c[0]>>3)<<11 | uint16(c[1]>>2)<<5 | uint16(c[2]>>3)
+}
+
+func from565(v uint16) [3]int {
+	r := int(v>>11) & 31
+	g := int(v>>5) & 63
+	b := int(v) & 31
+	return [3]int{r<<3 | r>>2, g<<2 | g>>4, b<<3 | b>>2}
+}
+
+// bc1Block writes a block as a four color BC1 block, using the darkest and
+// brightest colors of the endpoint as the BC1 endpoints and mapping each
+// selector to the nearest of the four BC1 colors.
+func (f *File) bc1Block(b block, dst []byte) {
+	e := &f.endpoints[b.endpoint]
+	sel := &f.selectors[b.selector]
+	colors := e.colors()
+	c0, c1 := to565(colors[3]), to565(colors[0])
+	if c0 < c1 {
+		c0, c1 = c1, c0
+	}
+	var mapping [4]uint32
+	if c0 != c1 {
+		p0, p1 := from565(c0), from565(c1)
+		var palette [4][3]int
+		for c := 0; c < 3; c++ {
+			palette[0][c] = p0[c]
+			palette[1][c] = p1[c]
+			palette[2][c] = (2*p0[c] + p1[c]) / 3
+			palette[3][c] = (p0[c] + 2*p1[c]) / 3
+		}
+		for s, col := range colors {
+			best := -1
+			for i, p := range palette {
+				d := 0
+				for c := 0; c < 3; c++ {
+					diff := int(col[c]) - p[c]
+					d += diff * diff
+				}
+				if best < 0 || d < best {
+					best = d
+					mapping[s] = uint32(i)
+				}
+			}
+		}
+	}
+	dst[0], dst[1] = byte(c0), byte(c0>>8)
+	dst[2], dst[3] = byte(c1), byte(c1>>8)
+	var bits uint32
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			bits |= mapping[sel.get(x, y)] << uint(2*(y*4+x))
+		}
+	}
+	dst[4], dst[5], dst[6], dst[7] = byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24)
+}
+
+// bc4Block writes the green channel of a block, which is where alpha
+// slices keep alpha, as a BC4 style alpha block, as used in BC3.
+func (f *File) bc4Block(b block, dst []byte) {
+	e := &f.endpoints[b.endpoint]
+	sel := &f.selectors[b.selector]
+	colors := e.colors()
+	hi, lo := colors[3][1], colors[0][1]
+	var mapping [4]uint64
+	if hi != lo {
+		var palette [8]int
+		palette[0], palette[1] = int(hi), int(lo)
+		for i := 1; i < 7; i++ {
+			palette[i+1] = ((7-i)*int(hi) + i*int(lo)) / 7
+		}
+		for s, col := range colors {
+			best := -1
+			for i, p := range palette {
+				d := int(col[1]) - p
+				if d < 0 {
+					d = -d
+				}
+				if best < 0 || d < best {
+					best = d
+					mapping[s] = uint64(i)
+				}
+			}
+		}
+	}
+	dst[0], dst[1] = hi, lo
+	var bits uint64
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			bits |= mapping[sel.get(x, y)] << uint(3*(y*4+x))
+		}
+	}
+	for i := 0; i < 6; i++ {
+		dst[2+i] = byte(bits >> uint(8*i))
+	}
+}
+
+// rgbaBlock writes the pixels of a block into an RGBA image. If alpha is
+// true, only the alpha channel is written, from the green channel.
+func (f *File) rgbaBlock(b block, pix []byte, width, height, bx, by int, alpha bool) {
+	e := &f.endpoints[b.endpoint]
+	sel := &f.selectors[b.selector]
+	colors := e.colors()
+	for y := 0; y < 4; y++ {
+		py := by*4 + y
+		if py >= height {
+			break
+		}
+		for x := 0; x < 4; x++ {
+			px := bx*4 + x
+			if px >= width {
+				break
+			}
+			col := colors[sel.get(x, y)]
+			p := pix[(py*width+px)*4:]
+			if alpha {
+				p[3] = col[1]
+			} else {
+				p[0], p[1], p[2], p[3] = col[0], col[1], col[2], 255
+			}
+		}
+	}
+}
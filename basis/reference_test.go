@@ -0,0 +1,119 @@
+package basis
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/justinclift/webgl/compressed"
+)
+
+// referenceFormats maps the file name suffixes of the expected output in
+// testdata/reference to the formats they hold.
+var referenceFormats = map[string]Format{
+	"etc1": FormatETC1,
+	"etc2": FormatETC2,
+	"bc1":  FormatBC1,
+	"bc3":  FormatBC3,
+}
+
+// TestReference checks the transcoder against files encoded and unpacked
+// by the upstream basisu tool. Each testdata/reference/NAME.basis comes
+// with the output of "basisu -unpack NAME.basis" for image 0, level 0,
+// renamed to NAME.rgba.png for RGBA and NAME.FORMAT.ktx for the formats in
+// referenceFormats. At least one of them has to be present.
+func TestReference(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "reference", "*.basis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Skip("testdata/reference holds no files made by basisu")
+	}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		base := strings.TrimSuffix(path, ".basis")
+		checked := 0
+		if want, err := readReferencePNG(base + ".rgba.png"); err == nil {
+			checked++
+			checkReferenceRGBA(t, path, f, want)
+		} else if !os.IsNotExist(err) {
+			t.Errorf("%s: %v", base+".rgba.png", err)
+		}
+		for suffix, format := range referenceFormats {
+			name := base + "." + suffix + ".ktx"
+			want, err := ioutil.ReadFile(name)
+			if os.IsNotExist(err) {
+				continue
+			}
+			checked++
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			img, err := compressed.ParseKTX(want)
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+				continue
+			}
+			got, err := f.TranscodeLevel(0, 0, format)
+			if err != nil {
+				t.Errorf("%s to %s: %v", path, suffix, err)
+				continue
+			}
+			if !bytes.Equal(got.Data, img.Levels[0].Data) {
+				t.Errorf("%s to %s differs from %s", path, suffix, name)
+			}
+		}
+		if checked == 0 {
+			t.Errorf("%s has no expected output next to it", path)
+		}
+	}
+}
+
+func readReferencePNG(name string) (image.Image, error) {
+	r, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return png.Decode(r)
+}
+
+func checkReferenceRGBA(t *testing.T, path string, f *File, want image.Image) {
+	t.Helper()
+	got, err := f.TranscodeLevel(0, 0, FormatRGBA8)
+	if err != nil {
+		t.Errorf("%s to RGBA: %v", path, err)
+		return
+	}
+	b := want.Bounds()
+	if got.Width != b.Dx() || got.Height != b.Dy() {
+		t.Errorf("%s to RGBA: size is %dx%d; want %dx%d", path, got.Width, got.Height, b.Dx(), b.Dy())
+		return
+	}
+	for y := 0; y < got.Height; y++ {
+		for x := 0; x < got.Width; x++ {
+			c := color.NRGBAModel.Convert(want.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			p := got.Data[(y*got.Width+x)*4:]
+			if p[0] != c.R || p[1] != c.G || p[2] != c.B || p[3] != c.A {
+				t.Errorf("%s to RGBA: pixel (%d, %d) is %v; want %v", path, x, y, p[:4], []byte{c.R, c.G, c.B, c.A})
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package basis
+
+import (
+	"errors"
+
+	"github.com/justinclift/webgl/compressed"
+)
+
+// Format is a format that ETC1S data can be transcoded to.
+type Format int
+
+const (
+	// FormatRGBA8 is uncompressed RGBA with 8 bits per channel.
+	FormatRGBA8 Format = iota
+
+	// FormatETC1 is ETC1, from WEBGL_compressed_texture_etc1. It has no
+	// alpha.
+	FormatETC1
+
+	// FormatETC2 is ETC2 RGB8, from WEBGL_compressed_texture_etc. It has
+	// no alpha.
+	FormatETC2
+
+	// FormatBC1 is BC1 (DXT1), from WEBGL_compressed_texture_s3tc. It has
+	// no alpha.
+	FormatBC1
+
+	// FormatBC3 is BC3 (DXT5), from WEBGL_compressed_texture_s3tc.
+	FormatBC3
+)
+
+// Returns the compressed texture format enum of f, or 0 for FormatRGBA8.
+func (f Format) GLFormat() int {
+	switch f {
+	case FormatETC1:
+		return compressed.COMPRESSED_RGB_ETC1_WEBGL
+	case FormatETC2:
+		return compressed.COMPRESSED_RGB8_ETC2
+	case FormatBC1:
+		return compressed.COMPRESSED_RGB_S3TC_DXT1_EXT
+	case FormatBC3:
+		return compressed.COMPRESSED_RGBA_S3TC_DXT5_EXT
+	}
+	return 0
+}
+
+// Returns the number of bytes a 4x4 block takes up in f, or 0 for
+// FormatRGBA8.
+func (f Format) blockSize() int {
+	switch f {
+	case FormatETC1, FormatETC2, FormatBC1:
+		return 8
+	case FormatBC3:
+		return 16
+	}
+	return 0
+}
+
+// SelectFormat picks the format to transcode to from the WebGL extensions
+// a context supports, as returned by GetSupportedExtensions. Formats
+// without alpha are only picked if hasAlpha is false. If none of the
+// compressed formats are supported, FormatRGBA8 is returned.
+func SelectFormat(extensions []string, hasAlpha bool) Format {
+	supported := make(map[string]bool, len(extensions))
+	for _, name := range extensions {
+		supported[name] = true
+	}
+	has := func(f Format) bool {
+		for _, name := range compressed.Extensions(f.GLFormat()) {
+			if supported[name] {
+				return true
+			}
+		}
+		return false
+	}
+	if hasAlpha {
+		if has(FormatBC3) {
+			return FormatBC3
+		}
+		return FormatRGBA8
+	}
+	for _, f := range []Format{FormatBC1, FormatETC2, FormatETC1} {
+		if has(f) {
+			return f
+		}
+	}
+	return FormatRGBA8
+}
+
+// TranscodeLevel transcodes a mip level of an image to format. For
+// FormatRGBA8 the data is cropped to the size of the level, while
+// compressed formats cover whole 4x4 blocks.
+func (f *File) TranscodeLevel(image, level int, format Format) (compressed.Level, error) {
+	if f.UASTC {
+		return compressed.Level{}, ErrUASTC
+	}
+	s := f.slice(image, level, false)
+	if s == nil {
+		return compressed.Level{}, errors.New("basis: no such image or mip level")
+	}
+	colors, err := f.decodeSlice(s)
+	if err != nil {
+		return compressed.Level{}, err
+	}
+	var alphas []block
+	if f.HasAlpha && (format == FormatBC3 || format == FormatRGBA8) {
+		a := f.slice(image, level, true)
+		if a == nil || a.blocksX != s.blocksX || a.blocksY != s.blocksY {
+			return compressed.Level{}, errCorrupt
+		}
+		if alphas, err = f.decodeSlice(a); err != nil {
+			return compressed.Level{}, err
+		}
+	}
+
+	out := compressed.Level{Width: s.width, Height: s.height}
+	if format == FormatRGBA8 {
+		out.Data = make([]byte, s.width*s.height*4)
+		for i, b := range colors {
+			bx, by := i%s.blocksX, i/s.blocksX
+			f.rgbaBlock(b, out.Data, s.width, s.height, bx, by, false)
+			if alphas != nil {
+				f.rgbaBlock(alphas[i], out.Data, s.width, s.height, bx, by, true)
+			}
+		}
+		return out, nil
+	}
+
+	size := format.blockSize()
+	if size == 0 {
+		return compressed.Level{}, errors.New("basis: unknown format")
+	}
+	out.Data = make([]byte, len(colors)*size)
+	for i, b := range colors {
+		dst := out.Data[i*size : (i+1)*size]
+		switch format {
+		case FormatETC1, FormatETC2:
+			f.etc1Block(b, dst)
+		case FormatBC1:
+			f.bc1Block(b, dst)
+		case FormatBC3:
+			if alphas != nil {
+				f.bc4Block(alphas[i], dst[:8])
+			} else {
+				// Opaque: both alpha endpoints at 255.
+				dst[0], dst[1] = 255, 255
+			}
+			f.bc1Block(b, dst[8:])
+		}
+	}
+	return out, nil
+}
+
+// TranscodeImage transcodes every mip level of an image to a compressed
+// format. It can not be used with FormatRGBA8.
+func (f *File) TranscodeImage(image int, format Format) (*compressed.Image, error) {
+	if format.GLFormat() == 0 {
+		return nil, errors.New("basis: format is not a compressed format")
+	}
+	n := f.LevelCount(image)
+	if n == 0 {
+		return nil, errors.New("basis: no such image")
+	}
+	img := &compressed.Image{Format: format.GLFormat()}
+	for level := 0; level < n; level++ {
+		l, err := f.TranscodeLevel(image, level, format)
+		if err != nil {
+			return nil, err
+		}
+		img.Levels = append(img.Levels, l)
+	}
+	img.Width, img.Height = img.Levels[0].Width, img.Levels[0].Height
+	return img, nil
+}
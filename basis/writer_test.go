@@ -0,0 +1,633 @@
+package basis
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// This file holds an ETC1S encoder for the tests. It does no image
+// compression: it writes codebooks and block indices that it is given,
+// using every coding feature of the format, so that the files in testdata
+// can be rebuilt with go test -update.
+
+// bitWriter writes a bit stream least significant bit first, the way
+// bitReader reads it.
+type bitWriter struct {
+	data []byte
+	acc  uint64
+	n    uint
+}
+
+func (w *bitWriter) bits(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.n
+	w.n += n
+	for w.n >= 8 {
+		w.data = append(w.data, byte(w.acc))
+		w.acc >>= 8
+		w.n -= 8
+	}
+}
+
+// vlc writes v in chunks of chunkBits bits, each followed by a bit saying
+// whether another chunk follows.
+func (w *bitWriter) vlc(v uint32, chunkBits uint) {
+	for {
+		chunk := v & (1<<chunkBits - 1)
+		v >>= chunkBits
+		if v == 0 {
+			w.bits(chunk, chunkBits+1)
+			return
+		}
+		w.bits(chunk|1<<chunkBits, chunkBits+1)
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.n > 0 {
+		w.data = append(w.data, byte(w.acc))
+		w.acc, w.n = 0, 0
+	}
+	return w.data
+}
+
+// huffmanCode is a canonical Huffman code, with the codes bit reversed so
+// they can be written least significant bit first.
+type huffmanCode struct {
+	sizes []uint8
+	codes []uint32
+}
+
+// canonicalCode assigns codes to symbols of the given code lengths in
+// order of length, then symbol.
+func canonicalCode(sizes []uint8) *huffmanCode {
+	var counts [maxCodeSize + 1]int
+	for _, s := range sizes {
+		counts[s]++
+	}
+	counts[0] = 0
+	var next [maxCodeSize + 1]uint32
+	code := uint32(0)
+	for bits := 1; bits <= maxCodeSize; bits++ {
+		code = (code + uint32(counts[bits-1])) << 1
+		next[bits] = code
+	}
+	h := &huffmanCode{sizes: sizes, codes: make([]uint32, len(sizes))}
+	for sym, s := range sizes {
+		if s == 0 {
+			continue
+		}
+		c := next[s]
+		next[s]++
+		var rev uint32
+		for i := uint8(0); i < s; i++ {
+			rev |= ((c >> i) & 1) << (s - 1 - i)
+		}
+		h.codes[sym] = rev
+	}
+	return h
+}
+
+// newHuffmanCode builds a Huffman code for symbols with the given
+// frequencies. Unused symbols get no code, except that symbol 0 gets one
+// if nothing is used, since a table can not be empty.
+func newHuffmanCode(freq []int) *huffmanCode {
+	sizes := make([]uint8, len(freq))
+	type node struct {
+		freq int
+		syms []int
+	}
+	var nodes []node
+	for sym, f := range freq {
+		if f > 0 {
+			nodes = append(nodes, node{f, []int{sym}})
+		}
+	}
+	switch len(nodes) {
+	case 0:
+		sizes[0] = 1
+	case 1:
+		sizes[nodes[0].syms[0]] = 1
+	}
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].freq < nodes[j].freq })
+		a, b := nodes[0], nodes[1]
+		merged := node{a.freq + b.freq, append(append([]int(nil), a.syms...), b.syms...)}
+		for _, sym := range merged.syms {
+			sizes[sym]++
+			if sizes[sym] > maxCodeSize {
+				panic("basis test: Huffman code is too long")
+			}
+		}
+		nodes = append(nodes[2:], merged)
+	}
+	return canonicalCode(sizes)
+}
+
+func (w *bitWriter) symbol(h *huffmanCode, sym int) {
+	if h.sizes[sym] == 0 {
+		panic("basis test: symbol has no code")
+	}
+	w.bits(h.codes[sym], uint(h.sizes[sym]))
+}
+
+// huffmanTable writes the code lengths of h the way readHuffman reads
+// them, using the run length codes wherever they apply.
+func (w *bitWriter) huffmanTable(h *huffmanCode) {
+	type lengthCode struct {
+		sym       int
+		extra     uint32
+		extraBits uint
+	}
+	min := func(a, b int) int {
+		if a < b {
+			return a
+		}
+		return b
+	}
+	var seq []lengthCode
+	sizes := h.sizes
+	for i := 0; i < len(sizes); {
+		s := sizes[i]
+		run := 1
+		for i+run < len(sizes) && sizes[i+run] == s {
+			run++
+		}
+		switch {
+		case s == 0 && run >= 11:
+			n := min(run, 138)
+			seq = append(seq, lengthCode{bigZeroRunCode, uint32(n - 11), 7})
+			i += n
+		case s == 0 && run >= 3:
+			n := min(run, 10)
+			seq = append(seq, lengthCode{smallZeroRunCode, uint32(n - 3), 3})
+			i += n
+		case s != 0 && i > 0 && sizes[i-1] == s && run >= 7:
+			n := min(run, 134)
+			seq = append(seq, lengthCode{bigRepeatCode, uint32(n - 7), 7})
+			i += n
+		case s != 0 && i > 0 && sizes[i-1] == s && run >= 3:
+			n := min(run, 6)
+			seq = append(seq, lengthCode{smallRepeatCode, uint32(n - 3), 2})
+			i += n
+		default:
+			seq = append(seq, lengthCode{int(s), 0, 0})
+			i++
+		}
+	}
+
+	// The code length alphabet gets a fixed length code.
+	var used [codeLengthCodes]bool
+	count := 0
+	for _, c := range seq {
+		if !used[c.sym] {
+			used[c.sym] = true
+			count++
+		}
+	}
+	size := uint8(1)
+	for 1<<size < count {
+		size++
+	}
+	lengthSizes := make([]uint8, codeLengthCodes)
+	for sym, u := range used {
+		if u {
+			lengthSizes[sym] = size
+		}
+	}
+	lengths := canonicalCode(lengthSizes)
+	n := 1
+	for i, sym := range sortedCodeLengthCodes {
+		if lengthSizes[sym] != 0 {
+			n = i + 1
+		}
+	}
+
+	w.bits(uint32(len(sizes)), maxSymbolsLog2)
+	w.bits(uint32(n), 5)
+	for _, sym := range sortedCodeLengthCodes[:n] {
+		w.bits(uint32(lengthSizes[sym]), 3)
+	}
+	for _, c := range seq {
+		w.symbol(lengths, c.sym)
+		w.bits(c.extra, c.extraBits)
+	}
+}
+
+// codedSymbol is a symbol to be written with one of the Huffman tables of
+// a file, or a variable length number if table is tableVLC.
+type codedSymbol struct {
+	table int
+	sym   int
+	bits  uint
+}
+
+const (
+	tableVLC = iota
+	tableEndpointPred
+	tableDeltaEndpoint
+	tableSelector
+	tableSelectorRLE
+	tableColor0
+	tableColor1
+	tableColor2
+	tableInten
+	tableSelectorDelta
+	numTables
+)
+
+// symbolWriter writes symbols in two passes: one to count how often each
+// is used, to build the Huffman tables from, and one to write them.
+type symbolWriter struct {
+	freq  [numTables][]int
+	codes [numTables]*huffmanCode
+}
+
+func newSymbolWriter(sizes map[int]int) *symbolWriter {
+	s := &symbolWriter{}
+	for table, n := range sizes {
+		s.freq[table] = make([]int, n)
+	}
+	return s
+}
+
+func (s *symbolWriter) count(syms []codedSymbol) {
+	for _, c := range syms {
+		if c.table != tableVLC {
+			s.freq[c.table][c.sym]++
+		}
+	}
+}
+
+func (s *symbolWriter) build() {
+	for table, freq := range s.freq {
+		if freq != nil {
+			s.codes[table] = newHuffmanCode(freq)
+		}
+	}
+}
+
+func (s *symbolWriter) write(w *bitWriter, syms []codedSymbol) {
+	for _, c := range syms {
+		if c.table == tableVLC {
+			w.vlc(uint32(c.sym), c.bits)
+		} else {
+			w.symbol(s.codes[c.table], c.sym)
+		}
+	}
+}
+
+// testSlice is the content of a slice: the codebook indices of its
+// blocks, in rows.
+type testSlice struct {
+	width  int
+	height int
+	blocks []block
+}
+
+func (s *testSlice) blocksX() int { return (s.width + 3) / 4 }
+func (s *testSlice) blocksY() int { return (s.height + 3) / 4 }
+
+// testLevel is a mip level, with an alpha slice if the file has alpha.
+type testLevel struct {
+	color testSlice
+	alpha *testSlice
+}
+
+// testFile describes the content of a .basis file.
+type testFile struct {
+	name         string
+	endpoints    []endpoint
+	selectors    []selector
+	grayscale    bool
+	rawSelectors bool
+	yFlipped     bool
+	historySize  int
+	images       [][]testLevel
+
+	// If uastc is set, the file is a UASTC file with these bytes as the
+	// data of its one slice, and the codebooks are not written.
+	uastc []byte
+}
+
+func (tf *testFile) hasAlpha() bool {
+	return tf.images[0][0].alpha != nil
+}
+
+// encodeEndpoints writes the endpoint codebook as readEndpoints reads it.
+func (tf *testFile) encodeEndpoints() []byte {
+	var syms []codedSymbol
+	prevColor := [3]int{16, 16, 16}
+	prevInten := 0
+	channels := 3
+	if tf.grayscale {
+		channels = 1
+	}
+	for _, e := range tf.endpoints {
+		syms = append(syms, codedSymbol{table: tableInten, sym: (int(e.inten) - prevInten) & 7})
+		prevInten = int(e.inten)
+		for c := 0; c < channels; c++ {
+			table := tableColor2
+			if prevColor[c] <= 9 {
+				table = tableColor0
+			} else if prevColor[c] <= 21 {
+				table = tableColor1
+			}
+			syms = append(syms, codedSymbol{table: table, sym: (int(e.color5[c]) - prevColor[c]) & 31})
+			prevColor[c] = int(e.color5[c])
+		}
+	}
+	s := newSymbolWriter(map[int]int{tableColor0: 32, tableColor1: 32, tableColor2: 32, tableInten: 8})
+	s.count(syms)
+	s.build()
+	w := &bitWriter{}
+	for _, table := range []int{tableColor0, tableColor1, tableColor2, tableInten} {
+		w.huffmanTable(s.codes[table])
+	}
+	if tf.grayscale {
+		w.bits(1, 1)
+	} else {
+		w.bits(0, 1)
+	}
+	s.write(w, syms)
+	return w.bytes()
+}
+
+// encodeSelectors writes the selector codebook as readSelectors reads it.
+func (tf *testFile) encodeSelectors() []byte {
+	w := &bitWriter{}
+	w.bits(0, 1) // no global codebook
+	w.bits(0, 1) // no hybrid codebook
+	if tf.rawSelectors {
+		w.bits(1, 1)
+		for _, sel := range tf.selectors {
+			for _, row := range sel {
+				w.bits(uint32(row), 8)
+			}
+		}
+		return w.bytes()
+	}
+	w.bits(0, 1)
+	var syms []codedSymbol
+	for i := 1; i < len(tf.selectors); i++ {
+		for y := 0; y < 4; y++ {
+			syms = append(syms, codedSymbol{table: tableSelectorDelta, sym: int(tf.selectors[i][y] ^ tf.selectors[i-1][y])})
+		}
+	}
+	s := newSymbolWriter(map[int]int{tableSelectorDelta: 256})
+	s.count(syms)
+	s.build()
+	w.huffmanTable(s.codes[tableSelectorDelta])
+	for _, row := range tf.selectors[0] {
+		w.bits(uint32(row), 8)
+	}
+	s.write(w, syms)
+	return w.bytes()
+}
+
+// sliceSymbols returns the symbols that code a slice, following the
+// choices decodeSlice makes.
+func (tf *testFile) sliceSymbols(s *testSlice) []codedSymbol {
+	bw, bh := s.blocksX(), s.blocksY()
+	numEndpoints := len(tf.endpoints)
+	numSelectors := len(tf.selectors)
+	at := func(bx, by int) int { return s.blocks[by*bw+bx].endpoint }
+
+	// Pick how each endpoint is predicted: from the block before it, the
+	// one above, the one above left, or as a delta.
+	preds := make([]int, len(s.blocks))
+	deltas := make([]int, len(s.blocks))
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			i := by*bw + bx
+			e := at(bx, by)
+			prev := 0
+			if i > 0 {
+				prev = s.blocks[i-1].endpoint
+			}
+			switch {
+			case e == prev:
+				preds[i] = 0
+			case by > 0 && e == at(bx, by-1):
+				preds[i] = 1
+			case by > 0 && bx > 0 && e == at(bx-1, by-1):
+				preds[i] = 2
+			default:
+				preds[i] = 3
+				deltas[i] = (e - prev + numEndpoints) % numEndpoints
+			}
+		}
+	}
+	// The predictions of each 2x2 group are coded as one symbol.
+	groupSym := func(bx, by int) int {
+		sym := 0
+		for i, d := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			x, y := bx+d[0], by+d[1]
+			if x < bw && y < bh {
+				sym |= preds[y*bw+x] << uint(2*i)
+			}
+		}
+		return sym
+	}
+	var groups []int
+	for by := 0; by < bh; by += 2 {
+		for bx := 0; bx < bw; bx += 2 {
+			groups = append(groups, groupSym(bx, by))
+		}
+	}
+
+	var syms []codedSymbol
+	history := newHistoryBuffer(tf.historySize)
+	group, prevGroupSym, groupRepeat, selectorRun := 0, 0, 0, 0
+	for by := 0; by < bh; by++ {
+		for bx := 0; bx < bw; bx++ {
+			i := by*bw + bx
+			if bx&1 == 0 && by&1 == 0 {
+				if groupRepeat > 0 {
+					groupRepeat--
+				} else {
+					run := 0
+					for group+run < len(groups) && groups[group+run] == prevGroupSym {
+						run++
+					}
+					if run >= endpointPredMinRepeatCount {
+						syms = append(syms,
+							codedSymbol{table: tableEndpointPred, sym: endpointPredRepeatLast},
+							codedSymbol{table: tableVLC, sym: run - endpointPredMinRepeatCount, bits: endpointPredCountVLCBits})
+						groupRepeat = run - 1
+					} else {
+						syms = append(syms, codedSymbol{table: tableEndpointPred, sym: groups[group]})
+						prevGroupSym = groups[group]
+					}
+				}
+				group++
+			}
+			if preds[i] == 3 {
+				syms = append(syms, codedSymbol{table: tableDeltaEndpoint, sym: deltas[i]})
+			}
+
+			sel := s.blocks[i].selector
+			if selectorRun > 0 {
+				selectorRun--
+				continue
+			}
+			if sel == history.values[0] {
+				run := 0
+				for i+run < len(s.blocks) && s.blocks[i+run].selector == sel {
+					run++
+				}
+				if run >= selectorHistoryRLEThreshold {
+					syms = append(syms, codedSymbol{table: tableSelector, sym: numSelectors + tf.historySize})
+					if n := run - selectorHistoryRLEThreshold; n < selectorHistoryRLETotal-1 {
+						syms = append(syms, codedSymbol{table: tableSelectorRLE, sym: n})
+					} else {
+						syms = append(syms,
+							codedSymbol{table: tableSelectorRLE, sym: selectorHistoryRLETotal - 1},
+							codedSymbol{table: tableVLC, sym: n, bits: 7})
+					}
+					selectorRun = run - 1
+					continue
+				}
+			}
+			found := -1
+			for j, v := range history.values {
+				if v == sel {
+					found = j
+					break
+				}
+			}
+			if found >= 0 {
+				syms = append(syms, codedSymbol{table: tableSelector, sym: numSelectors + found})
+				history.use(found)
+			} else {
+				syms = append(syms, codedSymbol{table: tableSelector, sym: sel})
+				history.add(sel)
+			}
+		}
+	}
+	return syms
+}
+
+// encode writes the file.
+func (tf *testFile) encode() []byte {
+	var slices []*testSlice
+	var alphaFlags []bool
+	var levels []int
+	var imageIndex []int
+	for image, lvls := range tf.images {
+		for level := range lvls {
+			l := &tf.images[image][level]
+			slices = append(slices, &l.color)
+			alphaFlags = append(alphaFlags, false)
+			levels = append(levels, level)
+			imageIndex = append(imageIndex, image)
+			if l.alpha != nil {
+				slices = append(slices, l.alpha)
+				alphaFlags = append(alphaFlags, true)
+				levels = append(levels, level)
+				imageIndex = append(imageIndex, image)
+			}
+		}
+	}
+
+	var endpointData, selectorData, tableData []byte
+	sliceData := make([][]byte, len(slices))
+	if tf.uastc != nil {
+		sliceData[0] = tf.uastc
+	} else {
+		endpointData = tf.encodeEndpoints()
+		selectorData = tf.encodeSelectors()
+		s := newSymbolWriter(map[int]int{
+			tableEndpointPred:  endpointPredRepeatLast + 1,
+			tableDeltaEndpoint: len(tf.endpoints),
+			tableSelector:      len(tf.selectors) + tf.historySize + 1,
+			tableSelectorRLE:   selectorHistoryRLETotal,
+		})
+		syms := make([][]codedSymbol, len(slices))
+		for i, sl := range slices {
+			syms[i] = tf.sliceSymbols(sl)
+			s.count(syms[i])
+		}
+		s.build()
+		w := &bitWriter{}
+		for _, table := range []int{tableEndpointPred, tableDeltaEndpoint, tableSelector, tableSelectorRLE} {
+			w.huffmanTable(s.codes[table])
+		}
+		w.bits(uint32(tf.historySize), 13)
+		tableData = w.bytes()
+		for i := range slices {
+			w := &bitWriter{}
+			s.write(w, syms[i])
+			sliceData[i] = w.bytes()
+		}
+	}
+
+	le := binary.LittleEndian
+	put24 := func(b []byte, v int) {
+		b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+	}
+	sliceDescOfs := headerSize
+	endpointOfs := sliceDescOfs + len(slices)*sliceDescSize
+	selectorOfs := endpointOfs + len(endpointData)
+	tablesOfs := selectorOfs + len(selectorData)
+	pos := tablesOfs + len(tableData)
+	out := make([]byte, pos)
+	copy(out[endpointOfs:], endpointData)
+	copy(out[selectorOfs:], selectorData)
+	copy(out[tablesOfs:], tableData)
+	out = append(out, bytesJoin(sliceData)...)
+
+	le.PutUint16(out[0:], signature)
+	le.PutUint16(out[2:], 0x13)
+	le.PutUint16(out[4:], headerSize)
+	le.PutUint32(out[8:], uint32(len(out)-headerSize))
+	put24(out[14:], len(slices))
+	put24(out[17:], len(tf.images))
+	var flags uint16
+	if tf.uastc != nil {
+		out[20] = texFormatUASTC
+	} else {
+		out[20] = texFormatETC1S
+		flags |= flagETC1S
+	}
+	if tf.yFlipped {
+		flags |= flagYFlipped
+	}
+	if tf.hasAlpha() {
+		flags |= flagHasAlphaSlices
+	}
+	le.PutUint16(out[21:], flags)
+	le.PutUint16(out[39:], uint16(len(tf.endpoints)))
+	le.PutUint32(out[41:], uint32(endpointOfs))
+	put24(out[45:], len(endpointData))
+	le.PutUint16(out[48:], uint16(len(tf.selectors)))
+	le.PutUint32(out[50:], uint32(selectorOfs))
+	put24(out[54:], len(selectorData))
+	le.PutUint32(out[57:], uint32(tablesOfs))
+	le.PutUint32(out[61:], uint32(len(tableData)))
+	le.PutUint32(out[65:], uint32(sliceDescOfs))
+
+	for i, sl := range slices {
+		b := out[sliceDescOfs+i*sliceDescSize:]
+		put24(b, imageIndex[i])
+		b[3] = byte(levels[i])
+		if alphaFlags[i] {
+			b[4] = sliceHasAlpha
+		}
+		le.PutUint16(b[5:], uint16(sl.width))
+		le.PutUint16(b[7:], uint16(sl.height))
+		le.PutUint16(b[9:], uint16(sl.blocksX()))
+		le.PutUint16(b[11:], uint16(sl.blocksY()))
+		le.PutUint32(b[13:], uint32(pos))
+		le.PutUint32(b[17:], uint32(len(sliceData[i])))
+		pos += len(sliceData[i])
+	}
+	return out
+}
+
+func bytesJoin(parts [][]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+// +build wasm
+
+package webgl
+
+import (
+	"github.com/justinclift/webgl/basis"
+)
+
+// NewBasisTexture transcodes the first image of a Basis Universal file and
+// uploads it as a texture. The format is picked from the context's
+// supported extensions with basis.SelectFormat, falling back to RGBA8 if
+// none of the compressed formats are supported. The image is uploaded as
+// it was encoded, so FlipY and ResizeToPowerOfTwo are ignored. If opts is
+// nil, DefaultTextureOptions is used.
+func (c *Context) NewBasisTexture(data []byte, opts *TextureOptions) (*Texture, error) {
+	if opts == nil {
+		opts = DefaultTextureOptions()
+	}
+	f, err := basis.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	format := basis.SelectFormat(c.GetSupportedExtensions(), f.HasAlpha)
+	if format != basis.FormatRGBA8 {
+		img, err := f.TranscodeImage(0, format)
+		if err != nil {
+			return nil, err
+		}
+		return c.NewCompressedTexture(img, opts)
+	}
+
+	width, height := f.Size(0, 0)
+	levels := f.LevelCount(0)
+	if !canMipmap(width, height, opts) {
+		levels = 1
+	}
+	tex := &Texture{Object: c.CreateTexture(), Width: width, Height: height, gl: c}
+	c.BindTexture(TEXTURE_2D, tex.Object)
+	for n := 0; n < levels; n++ {
+		level, err := f.TranscodeLevel(0, n, basis.FormatRGBA8)
+		if err != nil {
+			tex.Delete()
+			return nil, err
+		}
+		c.TexImage2DPixels(TEXTURE_2D, n, RGBA, level.Width, level.Height, 0, RGBA, UNSIGNED_BYTE, level.Data)
+	}
+	if levels > 1 && levels == mipLevelCount(width, height) {
+		tex.Mipmapped = true
+		c.setTextureParameters(width, height, opts, true)
+	} else {
+		tex.Mipmapped = c.applyTextureOptions(width, height, opts)
+	}
+	return tex, nil
+}
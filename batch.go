@@ -0,0 +1,297 @@
+// +build wasm
+
+package webgl
+
+import (
+	"math"
+	"syscall/js"
+)
+
+const (
+	// A batch is flushed early once its encoded commands or the objects
+	// they reference grow past these limits.
+	maxBatchBytes = 1 << 20
+	maxBatchRefs  = 4096
+
+	// How many of the most recent object references are checked for a
+	// repeat before a new one is added to the batch.
+	batchRefLookback = 8
+)
+
+// Argument tags of the batch encoding.
+const (
+	tagNumber = iota
+	tagFalse
+	tagTrue
+	tagNull
+	tagRef
+)
+
+// batchOps lists the WebGL methods that can be recorded into a batch. A
+// command refers to its method by its index in this list, so the order has
+// to match what the interpreter was created with. Methods that return a
+// value or fill in one of their arguments are never batched. Neither are
+// uploads, which read their data or image when they run: batched, they
+// would upload whatever the caller had put there by the next flush.
+var batchOps = []string{
+	"activeTexture",
+	"attachShader",
+	"bindAttribLocation",
+	"bindBuffer",
+	"bindFramebuffer",
+	"bindRenderbuffer",
+	"bindTexture",
+	"blendColor",
+	"blendEquation",
+	"blendEquationSeparate",
+	"blendFunc",
+	"blendFuncSeparate",
+	"clear",
+	"clearColor",
+	"clearDepth",
+	"clearStencil",
+	"colorMask",
+	"compileShader",
+	"copyTexImage2D",
+	"copyTexSubImage2D",
+	"cullFace",
+	"deleteBuffer",
+	"deleteFramebuffer",
+	"deleteProgram",
+	"deleteRenderbuffer",
+	"deleteShader",
+	"deleteTexture",
+	"depthFunc",
+	"depthMask",
+	"depthRange",
+	"detachShader",
+	"disable",
+	"disableVertexAttribArray",
+	"drawArrays",
+	"drawElements",
+	"enable",
+	"enableVertexAttribArray",
+	"flush",
+	"framebufferRenderbuffer",
+	"framebufferTexture2D",
+	"frontFace",
+	"generateMipmap",
+	"lineWidth",
+	"linkProgram",
+	"pixelStorei",
+	"polygonOffset",
+	"renderbufferStorage",
+	"scissor",
+	"shaderSource",
+	"texParameterf",
+	"texParameteri",
+	"uniform1f",
+	"uniform1i",
+	"uniform2f",
+	"uniform2i",
+	"uniform3f",
+	"uniform3i",
+	"uniform4f",
+	"uniform4i",
+	"uniformMatrix2fv",
+	"uniformMatrix3fv",
+	"uniformMatrix4fv",
+	"useProgram",
+	"validateProgram",
+	"vertexAttribPointer",
+	"viewport",
+}
+
+var batchOpIndex map[string]int
+
+// batchInterpreter is the JS side of batching. It keeps a byte buffer
+// that the Go side copies the encoded commands into, then decodes the
+// commands and applies them to the WebGL context in one go. Object
+// arguments are passed to run after the byte count, in reference order.
+//
+// Each command is a little endian uint16 method index and a uint8
+// argument count, followed by the arguments. Each argument is a uint8 tag,
+// followed by a float64 for numbers or a uint32 reference index for
+// objects.
+const batchInterpreter = `
+var methods = names.map(function(name) { return gl[name]; });
+var buf = new Uint8Array(0);
+var view = new DataView(buf.buffer);
+var args = [];
+return {
+	grow: function(size) {
+		buf = new Uint8Array(size);
+		view = new DataView(buf.buffer);
+		return buf;
+	},
+	run: function(n) {
+		var refs = arguments;
+		var p = 0;
+		while (p < n) {
+			var op = view.getUint16(p, true);
+			var argc = buf[p+2];
+			p += 3;
+			args.length = argc;
+			for (var i = 0; i < argc; i++) {
+				switch (buf[p++]) {
+				case 0: args[i] = view.getFloat64(p, true); p += 8; break;
+				case 1: args[i] = false; break;
+				case 2: args[i] = true; break;
+				case 3: args[i] = null; break;
+				case 4: args[i] = refs[1 + view.getUint32(p, true)]; p += 4; break;
+				}
+			}
+			methods[op].apply(gl, args);
+		}
+		args.length = 0;
+	}
+};
+`
+
+// batch holds the commands recorded since the last flush.
+type batch struct {
+	interp js.Value
+	run    js.Value
+	dst    js.Value
+	dstCap int
+	buf    []byte
+	refs   []interface{}
+}
+
+func newBatch(gl js.Value) *batch {
+	if batchOpIndex == nil {
+		batchOpIndex = make(map[string]int, len(batchOps))
+		for i, name := range batchOps {
+			batchOpIndex[name] = i
+		}
+	}
+	names := make([]interface{}, len(batchOps))
+	for i, name := range batchOps {
+		names[i] = name
+	}
+	factory := js.Global().Get("Function").New("gl", "names", batchInterpreter)
+	interp := factory.Invoke(gl, names)
+	return &batch{
+		interp: interp,
+		run:    interp.Get("run"),
+		buf:    make([]byte, 0, 64<<10),
+	}
+}
+
+func (b *batch) empty() bool {
+	return len(b.buf) == 0
+}
+
+func (b *batch) full() bool {
+	return len(b.buf) >= maxBatchBytes || len(b.refs) >= maxBatchRefs
+}
+
+func (b *batch) number(f float64) {
+	bits := math.Float64bits(f)
+	b.buf = append(b.buf, tagNumber,
+		byte(bits), byte(bits>>8), byte(bits>>16), byte(bits>>24),
+		byte(bits>>32), byte(bits>>40), byte(bits>>48), byte(bits>>56))
+}
+
+func (b *batch) ref(v js.Value) {
+	idx := -1
+	for i := len(b.refs) - 1; i >= 0 && i >= len(b.refs)-batchRefLookback; i-- {
+		if b.refs[i].(js.Value).Equal(v) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		idx = len(b.refs)
+		b.refs = append(b.refs, v)
+	}
+	b.buf = append(b.buf, tagRef, byte(idx), byte(idx>>8), byte(idx>>16), byte(idx>>24))
+}
+
+// record encodes one call of the method at index op.
+func (b *batch) record(op int, args []interface{}) {
+	b.buf = append(b.buf, byte(op), byte(op>>8), byte(len(args)))
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case nil:
+			b.buf = append(b.buf, tagNull)
+		case bool:
+			if arg {
+				b.buf = append(b.buf, tagTrue)
+			} else {
+				b.buf = append(b.buf, tagFalse)
+			}
+		case int:
+			b.number(float64(arg))
+		case float32:
+			b.number(float64(arg))
+		case float64:
+			b.number(arg)
+		case *js.Value:
+			if arg == nil {
+				b.buf = append(b.buf, tagNull)
+			} else {
+				b.ref(*arg)
+			}
+		case js.Value:
+			b.ref(arg)
+		default:
+			b.ref(js.ValueOf(arg))
+		}
+	}
+}
+
+// flush copies the recorded commands over to JS and runs them.
+func (b *batch) flush() {
+	if b.empty() {
+		return
+	}
+	if b.dstCap < len(b.buf) {
+		b.dstCap = cap(b.buf)
+		b.dst = b.interp.Call("grow", b.dstCap)
+	}
+	js.CopyBytesToJS(b.dst, b.buf)
+	args := make([]interface{}, 0, len(b.refs)+1)
+	args = append(args, len(b.buf))
+	args = append(args, b.refs...)
+	b.run.Invoke(args...)
+
+	b.buf = b.buf[:0]
+	for i := range b.refs {
+		b.refs[i] = nil
+	}
+	b.refs = b.refs[:0]
+}
+
+// EnableBatching switches the Context into batched mode. Calls that do not
+// return anything are recorded in Go instead of being made straight away,
+// and are run by a small JS interpreter when FlushBatch is called, so a
+// whole frame costs a single copy and a single call into JS.
+//
+// Calls that return a value, such as the Create, Get and Is families,
+// flush the batch before they run so that they see everything recorded
+// before them. So do uploads such as BufferData and TexImage2D, which run
+// straight away so that their data can be reused as soon as they return.
+// Call FlushBatch once at the end of each frame.
+func (c *Context) EnableBatching() {
+	if c.batch == nil {
+		c.batch = newBatch(c.Object)
+	}
+}
+
+// DisableBatching runs any recorded commands and switches the Context back
+// to making every call straight away.
+func (c *Context) DisableBatching() {
+	if c.batch != nil {
+		c.batch.flush()
+		c.batch = nil
+	}
+}
+
+// FlushBatch runs all of the commands recorded since the last flush.
+// It does nothing if batching is not enabled.
+func (c *Context) FlushBatch() {
+	if c.batch != nil {
+		c.batch.flush()
+	}
+}
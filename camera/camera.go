@@ -0,0 +1,176 @@
+// Package camera has perspective and orthographic cameras that produce
+// glmath view and projection matrices, and orbit and fly controllers that
+// move them.
+//
+// Everything apart from SetViewportFromCanvas is pure Go. Controllers do
+// not read input themselves; they are fed pointer and key movements, so
+// the same input always moves a camera the same way.
+package camera
+
+import (
+	"math"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+// Camera is implemented by PerspectiveCamera and OrthographicCamera.
+type Camera interface {
+	// Returns the matrix that transforms world space to view space.
+	View() glmath.Mat4
+
+	// Returns the matrix that transforms view space to clip space.
+	Projection() glmath.Mat4
+
+	// Updates the aspect ratio for a viewport of the given size in pixels.
+	SetViewport(width, height int)
+}
+
+// Pose is the position and orientation of a camera. An unrotated camera
+// looks down the negative Z axis, with Y pointing up.
+type Pose struct {
+	Position glmath.Vec3
+	Rotation glmath.Quat
+}
+
+// Returns the world to view matrix of the pose.
+func (p *Pose) View() glmath.Mat4 {
+	inv := p.Rotation.Conjugate()
+	m := inv.Mat4()
+	t := inv.Rotate(p.Position).Mul(-1)
+	m[12], m[13], m[14] = t[0], t[1], t[2]
+	return m
+}
+
+// Returns the direction the camera looks in.
+func (p *Pose) Forward() glmath.Vec3 {
+	return p.Rotation.Rotate(glmath.Vec3{0, 0, -1})
+}
+
+// Returns the direction to the right of the camera.
+func (p *Pose) Right() glmath.Vec3 {
+	return p.Rotation.Rotate(glmath.Vec3{1, 0, 0})
+}
+
+// Returns the direction above the camera.
+func (p *Pose) Up() glmath.Vec3 {
+	return p.Rotation.Rotate(glmath.Vec3{0, 1, 0})
+}
+
+// LookAt turns the camera towards target, keeping it level.
+func (p *Pose) LookAt(target glmath.Vec3) {
+	yaw, pitch := yawPitchToward(target.Sub(p.Position))
+	p.Rotation = yawPitchQuat(yaw, pitch)
+}
+
+// Returns the rotation that turns by yaw radians around the Y axis, after
+// tilting by pitch radians around the X axis.
+func yawPitchQuat(yaw, pitch float32) glmath.Quat {
+	y := glmath.QuatAxisAngle(glmath.Vec3{0, 1, 0}, yaw)
+	return y.Mul(glmath.QuatAxisAngle(glmath.Vec3{1, 0, 0}, pitch))
+}
+
+// Returns the yaw and pitch of a camera looking in direction dir.
+func yawPitchToward(dir glmath.Vec3) (yaw, pitch float32) {
+	dir = dir.Normalize()
+	yaw = float32(math.Atan2(float64(-dir[0]), float64(-dir[2])))
+	pitch = float32(math.Asin(float64(clamp(dir[1], -1, 1))))
+	return yaw, pitch
+}
+
+func clamp(v, lo, hi float32) float32 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// Returns width/height, or 1 if either is not positive.
+func aspect(width, height int) float32 {
+	if width <= 0 || height <= 0 {
+		return 1
+	}
+	return float32(width) / float32(height)
+}
+
+// PerspectiveCamera is a camera with a perspective projection.
+type PerspectiveCamera struct {
+	Pose
+
+	// FovY is the vertical field of view in radians.
+	FovY   float32
+	Aspect float32
+	Near   float32
+
+	// Far is the distance to the far plane, which may be +Inf.
+	Far float32
+}
+
+// NewPerspectiveCamera creates a perspective camera at the origin.
+func NewPerspectiveCamera(fovy, aspect, near, far float32) *PerspectiveCamera {
+	return &PerspectiveCamera{
+		Pose:   Pose{Rotation: glmath.QuatIdent()},
+		FovY:   fovy,
+		Aspect: aspect,
+		Near:   near,
+		Far:    far,
+	}
+}
+
+// Returns the perspective projection matrix.
+func (c *PerspectiveCamera) Projection() glmath.Mat4 {
+	return glmath.Perspective(c.FovY, c.Aspect, c.Near, c.Far)
+}
+
+// Sets the aspect ratio to that of a viewport of the given size.
+func (c *PerspectiveCamera) SetViewport(width, height int) {
+	c.Aspect = aspect(width, height)
+}
+
+// Returns the projection matrix times the view matrix.
+func (c *PerspectiveCamera) ViewProjection() glmath.Mat4 {
+	p, v := c.Projection(), c.View()
+	return p.Mul(&v)
+}
+
+// OrthographicCamera is a camera with an orthographic projection, showing
+// a box Height units tall and Height*Aspect units wide.
+type OrthographicCamera struct {
+	Pose
+
+	Height float32
+	Aspect float32
+	Near   float32
+	Far    float32
+}
+
+// NewOrthographicCamera creates an orthographic camera at the origin.
+func NewOrthographicCamera(height, aspect, near, far float32) *OrthographicCamera {
+	return &OrthographicCamera{
+		Pose:   Pose{Rotation: glmath.QuatIdent()},
+		Height: height,
+		Aspect: aspect,
+		Near:   near,
+		Far:    far,
+	}
+}
+
+// Returns the orthographic projection matrix.
+func (c *OrthographicCamera) Projection() glmath.Mat4 {
+	h := c.Height / 2
+	w := h * c.Aspect
+	return glmath.Ortho(-w, w, -h, h, c.Near, c.Far)
+}
+
+// Sets the aspect ratio to that of a viewport of the given size.
+func (c *OrthographicCamera) SetViewport(width, height int) {
+	c.Aspect = aspect(width, height)
+}
+
+// Returns the projection matrix times the view matrix.
+func (c *OrthographicCamera) ViewProjection() glmath.Mat4 {
+	p, v := c.Projection(), c.View()
+	return p.Mul(&v)
+}
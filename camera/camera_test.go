@@ -0,0 +1,270 @@
+package camera
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+const epsilon = 1e-4
+
+func near(a, b float32) bool {
+	return float32(math.Abs(float64(a-b))) < epsilon
+}
+
+func nearVec(a, b glmath.Vec3) bool {
+	return near(a[0], b[0]) && near(a[1], b[1]) && near(a[2], b[2])
+}
+
+func TestOrbitRotate(t *testing.T) {
+	o := NewOrbitController(glmath.Vec3{1, 2, 3}, 10)
+	var p Pose
+	o.Apply(&p)
+	if want := (glmath.Vec3{1, 2, 13}); !nearVec(p.Position, want) {
+		t.Errorf("position = %v; want %v", p.Position, want)
+	}
+
+	tests := []struct {
+		name     string
+		dx, dy   float32
+		position glmath.Vec3
+	}{
+		// Dragging right swings the camera to the left, onto -X.
+		{"quarter turn right", math.Pi / 2 / 0.005, 0, glmath.Vec3{-10, 0, 0}},
+		{"quarter turn left", -math.Pi / 2 / 0.005, 0, glmath.Vec3{10, 0, 0}},
+		{"half turn", math.Pi / 0.005, 0, glmath.Vec3{0, 0, -10}},
+		// Dragging down raises the camera.
+		{"tilt down", 0, math.Pi / 4 / 0.005, glmath.Vec3{0, 10 * math.Sqrt2 / 2, 10 * math.Sqrt2 / 2}},
+		{"tilt up", 0, -math.Pi / 4 / 0.005, glmath.Vec3{0, -10 * math.Sqrt2 / 2, 10 * math.Sqrt2 / 2}},
+	}
+	for _, test := range tests {
+		o := NewOrbitController(glmath.Vec3{1, 2, 3}, 10)
+		o.Rotate(test.dx, test.dy)
+		var p Pose
+		o.Apply(&p)
+		want := test.position.Add(o.Target)
+		if !nearVec(p.Position, want) {
+			t.Errorf("%s: position = %v; want %v", test.name, p.Position, want)
+		}
+		// The camera always faces the target.
+		toTarget := o.Target.Sub(p.Position).Normalize()
+		if f := p.Forward(); !nearVec(f, toTarget) {
+			t.Errorf("%s: forward = %v; want %v", test.name, f, toTarget)
+		}
+		if d := p.Position.Sub(o.Target).Len(); !near(d, 10) {
+			t.Errorf("%s: distance = %v; want 10", test.name, d)
+		}
+	}
+}
+
+func TestOrbitPitchLimit(t *testing.T) {
+	for _, dy := range []float32{1e5, -1e5} {
+		o := NewOrbitController(glmath.Vec3{}, 5)
+		o.Rotate(0, dy)
+		if o.Pitch < minPitch || o.Pitch > maxPitch {
+			t.Errorf("Rotate(0, %v): pitch = %v; want within [%v, %v]", dy, o.Pitch, minPitch, maxPitch)
+		}
+		var p Pose
+		o.Apply(&p)
+		// Over the pole the camera would turn upside down.
+		if up := p.Up(); up[1] <= 0 {
+			t.Errorf("Rotate(0, %v): up = %v; want it to point upwards", dy, up)
+		}
+	}
+}
+
+func TestOrbitZoom(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max float32
+		amounts  []float32
+		want     float32
+	}{
+		{"in", 0.01, float32(math.Inf(1)), []float32{-100}, 10 * float32(math.Exp(-0.1))},
+		{"out", 0.01, float32(math.Inf(1)), []float32{100}, 10 * float32(math.Exp(0.1))},
+		{"in and out", 0.01, float32(math.Inf(1)), []float32{-250, 250}, 10},
+		{"clamped in", 2, 20, []float32{-1e4}, 2},
+		{"clamped out", 2, 20, []float32{1e4}, 20},
+		{"clamped out then in", 2, 20, []float32{1e4, -500}, 20 * float32(math.Exp(-0.5))},
+		{"unbounded out", 0.01, float32(math.Inf(1)), []float32{1e4}, 10 * float32(math.Exp(10))},
+	}
+	for _, test := range tests {
+		o := NewOrbitController(glmath.Vec3{}, 10)
+		o.MinDistance, o.MaxDistance = test.min, test.max
+		for _, a := range test.amounts {
+			o.Zoom(a)
+		}
+		if math.Abs(float64(o.Distance-test.want)) > epsilon*float64(test.want) {
+			t.Errorf("%s: distance = %v; want %v", test.name, o.Distance, test.want)
+		}
+	}
+}
+
+func TestOrbitPan(t *testing.T) {
+	o := NewOrbitController(glmath.Vec3{}, 10)
+	o.Pan(100, 0)
+	// Dragging right moves the scene right, so the target moves left.
+	if want := (glmath.Vec3{-1, 0, 0}); !nearVec(o.Target, want) {
+		t.Errorf("target = %v; want %v", o.Target, want)
+	}
+	o.Pan(0, 100)
+	if want := (glmath.Vec3{-1, 1, 0}); !nearVec(o.Target, want) {
+		t.Errorf("target = %v; want %v", o.Target, want)
+	}
+}
+
+func TestFlyMove(t *testing.T) {
+	tests := []struct {
+		name               string
+		yaw, pitch         float32
+		forward, right, up float32
+		dt                 time.Duration
+		position           glmath.Vec3
+	}{
+		{"forward", 0, 0, 1, 0, 0, time.Second, glmath.Vec3{0, 0, -5}},
+		{"back", 0, 0, -1, 0, 0, time.Second, glmath.Vec3{0, 0, 5}},
+		{"right", 0, 0, 0, 1, 0, time.Second, glmath.Vec3{5, 0, 0}},
+		{"up", 0, 0, 0, 0, 1, time.Second, glmath.Vec3{0, 5, 0}},
+		{"half a second", 0, 0, 1, 0, 0, time.Second / 2, glmath.Vec3{0, 0, -2.5}},
+		{"half speed", 0, 0, 0.5, 0, 0, time.Second, glmath.Vec3{0, 0, -2.5}},
+		{"turned left", math.Pi / 2, 0, 1, 0, 0, time.Second, glmath.Vec3{-5, 0, 0}},
+		{"looking up", 0, math.Pi / 2, 1, 0, 0, time.Second, glmath.Vec3{0, 5, 0}},
+		{"up is not pitched", 0, math.Pi / 4, 0, 0, 1, time.Second, glmath.Vec3{0, 5, 0}},
+		// Diagonal movement is no faster than straight movement.
+		{"diagonal", 0, 0, 1, 1, 0, time.Second, glmath.Vec3{5 / math.Sqrt2, 0, -5 / math.Sqrt2}},
+	}
+	for _, test := range tests {
+		f := NewFlyController(glmath.Vec3{}, glmath.Vec3{0, 0, -1})
+		f.Yaw, f.Pitch = test.yaw, test.pitch
+		f.Move(test.forward, test.right, test.up, test.dt)
+		if !nearVec(f.Position, test.position) {
+			t.Errorf("%s: position = %v; want %v", test.name, f.Position, test.position)
+		}
+	}
+}
+
+func TestFlyLook(t *testing.T) {
+	f := NewFlyController(glmath.Vec3{1, 1, 1}, glmath.Vec3{1, 1, -5})
+	if !near(f.Yaw, 0) || !near(f.Pitch, 0) {
+		t.Errorf("yaw, pitch = %v, %v; want 0, 0", f.Yaw, f.Pitch)
+	}
+	// Moving the pointer right turns right, and down looks down.
+	f.Look(100, 100)
+	if !near(f.Yaw, -0.3) || !near(f.Pitch, -0.3) {
+		t.Errorf("yaw, pitch = %v, %v; want -0.3, -0.3", f.Yaw, f.Pitch)
+	}
+
+	for _, dy := range []float32{1e5, -1e5} {
+		f := NewFlyController(glmath.Vec3{}, glmath.Vec3{0, 0, -1})
+		f.Look(0, dy)
+		if f.Pitch < minPitch || f.Pitch > maxPitch {
+			t.Errorf("Look(0, %v): pitch = %v; want within [%v, %v]", dy, f.Pitch, minPitch, maxPitch)
+		}
+		var p Pose
+		f.Apply(&p)
+		if up := p.Up(); up[1] <= 0 {
+			t.Errorf("Look(0, %v): up = %v; want it to point upwards", dy, up)
+		}
+	}
+
+	// Yaw is not limited.
+	f = NewFlyController(glmath.Vec3{}, glmath.Vec3{0, 0, -1})
+	f.Look(-4*math.Pi/f.LookSpeed, 0)
+	if !near(f.Yaw, 4*math.Pi) {
+		t.Errorf("yaw = %v; want %v", f.Yaw, 4*math.Pi)
+	}
+}
+
+func TestFlyLookAt(t *testing.T) {
+	targets := []glmath.Vec3{
+		{0, 0, -1}, {1, 0, 0}, {0, 0, 1}, {3, 4, 5}, {-2, -7, 1},
+	}
+	for _, target := range targets {
+		pos := glmath.Vec3{1, 2, 3}
+		f := NewFlyController(pos, pos.Add(target))
+		var p Pose
+		f.Apply(&p)
+		if fwd, want := p.Forward(), target.Normalize(); !nearVec(fwd, want) {
+			t.Errorf("looking at %v: forward = %v; want %v", target, fwd, want)
+		}
+		// The view matrix puts the target straight ahead.
+		v := p.View()
+		ahead := v.MulPoint(pos.Add(target))
+		if want := (glmath.Vec3{0, 0, -target.Len()}); !nearVec(ahead, want) {
+			t.Errorf("looking at %v: target in view space = %v; want %v", target, ahead, want)
+		}
+	}
+}
+
+func TestPerspectiveAspect(t *testing.T) {
+	c := NewPerspectiveCamera(math.Pi/2, 1, 0.1, 100)
+	tests := []struct {
+		width, height int
+		aspect        float32
+	}{
+		{800, 600, 800.0 / 600},
+		{600, 800, 600.0 / 800},
+		{100, 100, 1},
+		{0, 600, 1},
+		{800, 0, 1},
+		{-1, 600, 1},
+	}
+	for _, test := range tests {
+		c.SetViewport(test.width, test.height)
+		if !near(c.Aspect, test.aspect) {
+			t.Errorf("SetViewport(%d, %d): aspect = %v; want %v", test.width, test.height, c.Aspect, test.aspect)
+		}
+		proj := c.Projection()
+		// With a 90 degree field of view, a point as far up as it is
+		// ahead is at the top edge, and one aspect times as far right is
+		// at the right edge.
+		top := proj.MulPoint(glmath.Vec3{0, 1, -1})
+		right := proj.MulPoint(glmath.Vec3{test.aspect, 0, -1})
+		if !near(top[1], 1) || !near(right[0], 1) {
+			t.Errorf("SetViewport(%d, %d): top, right edge at %v, %v; want y and x of 1", test.width, test.height, top, right)
+		}
+		if !near(proj[5], 1) || !near(proj[0], 1/test.aspect) {
+			t.Errorf("SetViewport(%d, %d): x, y scale = %v, %v; want %v, 1", test.width, test.height, proj[0], proj[5], 1/test.aspect)
+		}
+	}
+
+	// Depths map from near to far to [-1, 1].
+	c.SetViewport(800, 600)
+	proj := c.Projection()
+	if z := proj.MulPoint(glmath.Vec3{0, 0, -0.1}); !near(z[2], -1) {
+		t.Errorf("near plane at z = %v; want -1", z[2])
+	}
+	if z := proj.MulPoint(glmath.Vec3{0, 0, -100}); !near(z[2], 1) {
+		t.Errorf("far plane at z = %v; want 1", z[2])
+	}
+}
+
+func TestOrthographicAspect(t *testing.T) {
+	c := NewOrthographicCamera(4, 1, 0, 10)
+	for _, size := range [][2]int{{800, 600}, {600, 800}, {100, 100}} {
+		c.SetViewport(size[0], size[1])
+		a := float32(size[0]) / float32(size[1])
+		proj := c.Projection()
+		// The box is Height tall and Height*Aspect wide.
+		corner := proj.MulPoint(glmath.Vec3{2 * a, 2, -5})
+		if !near(corner[0], 1) || !near(corner[1], 1) || !near(corner[2], 0) {
+			t.Errorf("SetViewport(%d, %d): corner at %v; want 1, 1, 0", size[0], size[1], corner)
+		}
+	}
+}
+
+func TestViewProjection(t *testing.T) {
+	c := NewPerspectiveCamera(1, 1.5, 0.1, 50)
+	c.Position = glmath.Vec3{3, 1, 4}
+	c.LookAt(glmath.Vec3{0, 1, 0})
+	vp := c.ViewProjection()
+	// The target is in the middle of the screen.
+	if p := vp.MulPoint(glmath.Vec3{0, 1, 0}); !near(p[0], 0) || !near(p[1], 0) {
+		t.Errorf("target at %v; want the middle of the screen", p)
+	}
+	if up := c.Up(); !nearVec(up, glmath.Vec3{0, 1, 0}) {
+		t.Errorf("up = %v; want %v", up, glmath.Vec3{0, 1, 0})
+	}
+}
@@ -0,0 +1,13 @@
+// +build wasm
+
+package camera
+
+import (
+	"syscall/js"
+)
+
+// SetViewportFromCanvas updates the aspect ratio of a camera from the
+// drawing buffer size of a canvas.
+func SetViewportFromCanvas(c Camera, canvas js.Value) {
+	c.SetViewport(canvas.Get("width").Int(), canvas.Get("height").Int())
+}
@@ -0,0 +1,138 @@
+package camera
+
+import (
+	"math"
+	"time"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+// Pitch limits that keep controllers from turning over the top.
+const (
+	maxPitch = math.Pi/2 - 0.001
+	minPitch = -maxPitch
+)
+
+// OrbitController moves a camera around a target point, keeping it
+// pointed at the target.
+type OrbitController struct {
+	Target   glmath.Vec3
+	Distance float32
+
+	// Yaw and Pitch are the angles of the camera around the target, in
+	// radians. At zero, the camera is on the positive Z side.
+	Yaw   float32
+	Pitch float32
+
+	MinDistance float32
+	MaxDistance float32
+
+	// RotateSpeed is in radians per pixel of pointer movement.
+	RotateSpeed float32
+
+	// ZoomSpeed scales the distance by e^ZoomSpeed per unit of zoom.
+	ZoomSpeed float32
+
+	// PanSpeed is in units per pixel per unit of distance.
+	PanSpeed float32
+}
+
+// NewOrbitController creates a controller with the camera distance units
+// away from target.
+func NewOrbitController(target glmath.Vec3, distance float32) *OrbitController {
+	return &OrbitController{
+		Target:      target,
+		Distance:    distance,
+		MinDistance: 0.01,
+		MaxDistance: float32(math.Inf(1)),
+		RotateSpeed: 0.005,
+		ZoomSpeed:   0.001,
+		PanSpeed:    0.001,
+	}
+}
+
+// Rotate orbits the camera by a pointer movement in pixels. Dragging right
+// swings the camera to the left, so the scene appears to turn right.
+func (o *OrbitController) Rotate(dx, dy float32) {
+	o.Yaw -= dx * o.RotateSpeed
+	o.Pitch = clamp(o.Pitch+dy*o.RotateSpeed, minPitch, maxPitch)
+}
+
+// Zoom moves the camera towards the target for negative amounts and away
+// from it for positive ones, such as the deltaY of a wheel event.
+func (o *OrbitController) Zoom(amount float32) {
+	o.Distance *= float32(math.Exp(float64(amount * o.ZoomSpeed)))
+	o.Distance = clamp(o.Distance, o.MinDistance, o.MaxDistance)
+}
+
+// Pan moves the target, and the camera with it, by a pointer movement in
+// pixels, so the scene follows the pointer.
+func (o *OrbitController) Pan(dx, dy float32) {
+	rot := yawPitchQuat(o.Yaw, -o.Pitch)
+	right := rot.Rotate(glmath.Vec3{1, 0, 0})
+	up := rot.Rotate(glmath.Vec3{0, 1, 0})
+	scale := o.Distance * o.PanSpeed
+	o.Target = o.Target.Add(right.Mul(-dx * scale)).Add(up.Mul(dy * scale))
+}
+
+// Apply places a camera at the controller's position, facing the target.
+func (o *OrbitController) Apply(p *Pose) {
+	p.Rotation = yawPitchQuat(o.Yaw, -o.Pitch)
+	p.Position = o.Target.Add(p.Rotation.Rotate(glmath.Vec3{0, 0, o.Distance}))
+}
+
+// FlyController moves a camera like a first person camera: pointer
+// movement turns it and movement keys move it along where it faces.
+type FlyController struct {
+	Position glmath.Vec3
+
+	// Yaw and Pitch are the direction of the camera in radians. At zero
+	// it looks down the negative Z axis.
+	Yaw   float32
+	Pitch float32
+
+	// Speed is in units per second.
+	Speed float32
+
+	// LookSpeed is in radians per pixel of pointer movement.
+	LookSpeed float32
+}
+
+// NewFlyController creates a controller at position, looking at target.
+func NewFlyController(position, target glmath.Vec3) *FlyController {
+	yaw, pitch := yawPitchToward(target.Sub(position))
+	return &FlyController{
+		Position:  position,
+		Yaw:       yaw,
+		Pitch:     pitch,
+		Speed:     5,
+		LookSpeed: 0.003,
+	}
+}
+
+// Look turns the camera by a pointer movement in pixels.
+func (f *FlyController) Look(dx, dy float32) {
+	f.Yaw -= dx * f.LookSpeed
+	f.Pitch = clamp(f.Pitch-dy*f.LookSpeed, minPitch, maxPitch)
+}
+
+// Move moves the camera for a time step. forward, right and up are
+// between -1 and 1, such as 1 while W is held and -1 while S is held.
+// Forward movement follows the pitch of the camera; up is always along
+// the Y axis.
+func (f *FlyController) Move(forward, right, up float32, dt time.Duration) {
+	rot := yawPitchQuat(f.Yaw, f.Pitch)
+	dir := rot.Rotate(glmath.Vec3{0, 0, -1}).Mul(forward).
+		Add(rot.Rotate(glmath.Vec3{1, 0, 0}).Mul(right)).
+		Add(glmath.Vec3{0, up, 0})
+	if l := dir.Len(); l > 1 {
+		dir = dir.Mul(1 / l)
+	}
+	f.Position = f.Position.Add(dir.Mul(f.Speed * float32(dt.Seconds())))
+}
+
+// Apply places a camera at the controller's position and direction.
+func (f *FlyController) Apply(p *Pose) {
+	p.Position = f.Position
+	p.Rotation = yawPitchQuat(f.Yaw, f.Pitch)
+}
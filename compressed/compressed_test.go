@@ -0,0 +1,406 @@
+package compressed
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns the bytes 0, 1, 2, ... n-1, wrapping at 256.
+func payload(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	return b
+}
+
+type ddsHeader struct {
+	width, height, levels uint32
+	fourCC                string
+	alpha                 bool
+	caps2                 uint32
+	dxgi                  uint32
+}
+
+func (h ddsHeader) file(data []byte) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, ddsHeaderSize)
+	copy(b, ddsMagic)
+	le.PutUint32(b[4:], 124)
+	var flags uint32 = 0x1007
+	if h.levels != 0 {
+		flags |= ddsMipMapCountFlag
+	}
+	le.PutUint32(b[8:], flags)
+	le.PutUint32(b[12:], h.height)
+	le.PutUint32(b[16:], h.width)
+	le.PutUint32(b[28:], h.levels)
+	le.PutUint32(b[ddsPixelFormatOfs:], 32)
+	pfFlags := uint32(ddsFourCC)
+	if h.alpha {
+		pfFlags |= ddsAlphaPixels
+	}
+	if h.fourCC == "" {
+		pfFlags = 0x40
+	}
+	le.PutUint32(b[ddsPixelFormatOfs+4:], pfFlags)
+	copy(b[ddsPixelFormatOfs+8:], h.fourCC)
+	le.PutUint32(b[112:], h.caps2)
+	if h.fourCC == "DX10" {
+		dx10 := make([]byte, ddsDX10HeaderSize)
+		le.PutUint32(dx10, h.dxgi)
+		le.PutUint32(dx10[4:], 3)
+		le.PutUint32(dx10[12:], 1)
+		b = append(b, dx10...)
+	}
+	return append(b, data...)
+}
+
+type ktxHeader struct {
+	format, width, height, levels uint32
+	kvBytes                       uint32
+	faces                         uint32
+	bigEndian                     bool
+}
+
+// file builds a KTX 1 file with one imageSize field and level per entry of
+// levels.
+func (h ktxHeader) file(levels ...[]byte) []byte {
+	var order binary.ByteOrder = binary.LittleEndian
+	if h.bigEndian {
+		order = binary.BigEndian
+	}
+	faces := h.faces
+	if faces == 0 {
+		faces = 1
+	}
+	b := make([]byte, 64)
+	copy(b, ktxIdentifier)
+	order.PutUint32(b[12:], 0x04030201)
+	fields := []uint32{0, 1, 0, h.format, 0, h.width, h.height, 0, 0, faces, h.levels, h.kvBytes}
+	for i, f := range fields {
+		order.PutUint32(b[16+i*4:], f)
+	}
+	if h.kvBytes < 1024 {
+		b = append(b, make([]byte, h.kvBytes)...)
+	}
+	for _, l := range levels {
+		size := make([]byte, 4)
+		order.PutUint32(size, uint32(len(l)))
+		b = append(b, size...)
+		b = append(b, l...)
+		for len(b)%4 != 0 {
+			b = append(b, 0)
+		}
+	}
+	return b
+}
+
+type ktx2Header struct {
+	vkFormat, width, height, levels uint32
+	scheme                          uint32
+}
+
+const ktx2HeaderSize = 12 + 9*4 + 4*4 + 2*8
+
+// file builds a KTX 2 file with a level index entry per entry of levels.
+func (h ktx2Header) file(levels ...[]byte) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, ktx2HeaderSize+24*len(levels))
+	copy(b, ktx2Identifier)
+	fields := []uint32{h.vkFormat, 1, h.width, h.height, 0, 0, 1, h.levels, h.scheme}
+	for i, f := range fields {
+		le.PutUint32(b[12+i*4:], f)
+	}
+	for i, l := range levels {
+		entry := b[ktx2HeaderSize+i*24:]
+		le.PutUint64(entry, uint64(len(b)))
+		le.PutUint64(entry[8:], uint64(len(l)))
+		le.PutUint64(entry[16:], uint64(len(l)))
+		b = append(b, l...)
+	}
+	return b
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+type parseTest struct {
+	name   string
+	data   []byte
+	format int
+	levels []Level
+	err    string
+}
+
+func checkParse(t *testing.T, parse func([]byte) (*Image, error), tests []parseTest) {
+	t.Helper()
+	for _, tt := range tests {
+		img, err := parse(tt.data)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: got error %v; want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if img.Format != tt.format {
+			t.Errorf("%s: format = %#x; want %#x", tt.name, img.Format, tt.format)
+		}
+		if img.Width != tt.levels[0].Width || img.Height != tt.levels[0].Height {
+			t.Errorf("%s: size = %dx%d; want %dx%d", tt.name, img.Width, img.Height, tt.levels[0].Width, tt.levels[0].Height)
+		}
+		if !reflect.DeepEqual(img.Levels, tt.levels) {
+			t.Errorf("%s: levels = %v; want %v", tt.name, img.Levels, tt.levels)
+		}
+	}
+}
+
+func TestParseDDS(t *testing.T) {
+	mips := payload(64 + 16 + 16 + 16)
+	checkParse(t, ParseDDS, []parseTest{
+		{
+			name:   "DXT1",
+			data:   ddsHeader{width: 4, height: 4, fourCC: "DXT1"}.file(payload(8)),
+			format: COMPRESSED_RGB_S3TC_DXT1_EXT,
+			levels: []Level{{4, 4, payload(8)}},
+		},
+		{
+			name:   "DXT1 with alpha",
+			data:   ddsHeader{width: 5, height: 3, fourCC: "DXT1", alpha: true}.file(payload(16)),
+			format: COMPRESSED_RGBA_S3TC_DXT1_EXT,
+			levels: []Level{{5, 3, payload(16)}},
+		},
+		{
+			name:   "DXT5 mipmaps",
+			data:   ddsHeader{width: 8, height: 8, levels: 4, fourCC: "DXT5"}.file(mips),
+			format: COMPRESSED_RGBA_S3TC_DXT5_EXT,
+			levels: []Level{{8, 8, mips[:64]}, {4, 4, mips[64:80]}, {2, 2, mips[80:96]}, {1, 1, mips[96:]}},
+		},
+		{
+			name:   "DX10 BC7",
+			data:   ddsHeader{width: 4, height: 8, fourCC: "DX10", dxgi: 98}.file(payload(32)),
+			format: COMPRESSED_RGBA_BPTC_UNORM_EXT,
+			levels: []Level{{4, 8, payload(32)}},
+		},
+		{
+			name:   "largest size",
+			data:   ddsHeader{width: 1 << 16, height: 4, fourCC: "DXT1"}.file(payload(1 << 17)),
+			format: COMPRESSED_RGB_S3TC_DXT1_EXT,
+			levels: []Level{{1 << 16, 4, payload(1 << 17)}},
+		},
+		{name: "not DDS", data: []byte("KTX "), err: "not a DDS file"},
+		{name: "truncated header", data: ddsHeader{width: 4, height: 4, fourCC: "DXT1"}.file(nil)[:100], err: "header is truncated"},
+		{name: "truncated DX10 header", data: ddsHeader{width: 4, height: 4, fourCC: "DX10", dxgi: 71}.file(nil)[:ddsHeaderSize+8], err: "DX10 header is truncated"},
+		{name: "truncated data", data: ddsHeader{width: 4, height: 4, fourCC: "DXT1"}.file(payload(7)), err: "truncated"},
+		{name: "truncated mipmap", data: ddsHeader{width: 8, height: 8, levels: 4, fourCC: "DXT5"}.file(mips[:95]), err: "truncated"},
+		{name: "overflowing size", data: ddsHeader{width: 0xFFFFFFFF, height: 0xFFFFFFFF, fourCC: "DXT1"}.file(payload(72)), err: "too large"},
+		{name: "too wide", data: ddsHeader{width: 1<<16 + 1, height: 4, fourCC: "DXT1"}.file(payload(72)), err: "too large"},
+		{name: "no size", data: ddsHeader{width: 0, height: 4, fourCC: "DXT1"}.file(payload(8)), err: "no size"},
+		{name: "too many mipmaps", data: ddsHeader{width: 8, height: 8, levels: 5, fourCC: "DXT5"}.file(mips), err: "too many mip levels"},
+		{name: "huge mipmap count", data: ddsHeader{width: 8, height: 8, levels: 0xFFFFFFFF, fourCC: "DXT5"}.file(mips), err: "too many mip levels"},
+		{name: "uncompressed", data: ddsHeader{width: 4, height: 4}.file(payload(64)), err: "not compressed"},
+		{name: "cube map", data: ddsHeader{width: 4, height: 4, fourCC: "DXT1", caps2: ddsCaps2Cubemap}.file(payload(48)), err: "only 2D"},
+		{name: "unknown FourCC", data: ddsHeader{width: 4, height: 4, fourCC: "ABCD"}.file(payload(8)), err: "unknown texture format"},
+		{name: "unknown DXGI format", data: ddsHeader{width: 4, height: 4, fourCC: "DX10", dxgi: 2}.file(payload(8)), err: "unknown texture format"},
+	})
+}
+
+func TestParseKTX(t *testing.T) {
+	checkParse(t, ParseKTX, []parseTest{
+		{
+			name:   "ETC2",
+			data:   ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4}.file(payload(8)),
+			format: COMPRESSED_RGB8_ETC2,
+			levels: []Level{{4, 4, payload(8)}},
+		},
+		{
+			name:   "big endian with key/value data",
+			data:   ktxHeader{format: COMPRESSED_RGBA_ASTC_4x4_KHR, width: 4, height: 4, kvBytes: 12, bigEndian: true}.file(payload(16)),
+			format: COMPRESSED_RGBA_ASTC_4x4_KHR,
+			levels: []Level{{4, 4, payload(16)}},
+		},
+		{
+			name:   "mipmaps",
+			data:   ktxHeader{format: COMPRESSED_RGB_ETC1_WEBGL, width: 8, height: 4, levels: 3}.file(payload(16), payload(8), payload(8)),
+			format: COMPRESSED_RGB_ETC1_WEBGL,
+			levels: []Level{{8, 4, payload(16)}, {4, 2, payload(8)}, {2, 1, payload(8)}},
+		},
+		{
+			name:   "padded imageSize",
+			data:   ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, levels: 2}.file(payload(10), payload(8)),
+			format: COMPRESSED_RGB8_ETC2,
+			levels: []Level{{4, 4, payload(8)}, {2, 2, payload(8)}},
+		},
+		{name: "truncated header", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4}.file()[:40], err: "header is truncated"},
+		{name: "missing imageSize", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4}.file(), err: "truncated"},
+		{name: "truncated level", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4}.file(payload(8))[:70], err: "truncated"},
+		{name: "missing mipmap", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, levels: 3}.file(payload(8), payload(8)), err: "truncated"},
+		{name: "level too small", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 8, height: 8}.file(payload(16)), err: "too small"},
+		{name: "huge key/value data", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, kvBytes: 0xFFFFFFF0}.file(payload(8)), err: "truncated"},
+		{name: "overflowing size", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 0xFFFFFFFF, height: 0xFFFFFFFF}.file(payload(8)), err: "too large"},
+		{name: "too many mipmaps", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, levels: 4}.file(payload(8), payload(8), payload(8), payload(8)), err: "too many mip levels"},
+		{name: "huge mipmap count", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, levels: 0xFFFFFFFF}.file(payload(8)), err: "too many mip levels"},
+		{name: "cube map", data: ktxHeader{format: COMPRESSED_RGB8_ETC2, width: 4, height: 4, faces: 6}.file(payload(8)), err: "only 2D"},
+		{name: "unknown format", data: ktxHeader{format: 0x1908, width: 4, height: 4}.file(payload(64)), err: "unknown texture format"},
+	})
+}
+
+func TestParseKTX2(t *testing.T) {
+	checkParse(t, ParseKTX2, []parseTest{
+		{
+			name:   "BC1",
+			data:   ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 1}.file(payload(8)),
+			format: COMPRESSED_RGB_S3TC_DXT1_EXT,
+			levels: []Level{{4, 4, payload(8)}},
+		},
+		{
+			name:   "ASTC sRGB mipmaps",
+			data:   ktx2Header{vkFormat: 158, width: 8, height: 4, levels: 2}.file(payload(32), payload(16)),
+			format: COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR,
+			levels: []Level{{8, 4, payload(32)}, {4, 2, payload(16)}},
+		},
+		{
+			name:   "zlib",
+			data:   ktx2Header{vkFormat: 137, width: 8, height: 8, levels: 1, scheme: ktx2SupercompressionZlib}.file(deflate(payload(64))),
+			format: COMPRESSED_RGBA_S3TC_DXT5_EXT,
+			levels: []Level{{8, 8, payload(64)}},
+		},
+		{
+			name:   "zlib stream longer than the level",
+			data:   ktx2Header{vkFormat: 137, width: 4, height: 4, levels: 1, scheme: ktx2SupercompressionZlib}.file(deflate(make([]byte, 1<<20))),
+			format: COMPRESSED_RGBA_S3TC_DXT5_EXT,
+			levels: []Level{{4, 4, make([]byte, 16)}},
+		},
+		{name: "truncated header", data: ktx2Header{vkFormat: 131, width: 4, height: 4}.file()[:60], err: "header is truncated"},
+		{name: "truncated level index", data: ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 2}.file(payload(8)), err: "level index is truncated"},
+		{name: "truncated level", data: ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 1}.file(payload(8))[:ktx2HeaderSize+24+4], err: "truncated"},
+		{name: "level too small", data: ktx2Header{vkFormat: 131, width: 8, height: 8, levels: 1}.file(payload(8)), err: "too small"},
+		{name: "overflowing size", data: ktx2Header{vkFormat: 131, width: 0xFFFFFFFF, height: 0xFFFFFFFF, levels: 1}.file(payload(8)), err: "too large"},
+		{name: "too many mipmaps", data: ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 4}.file(payload(8), payload(8), payload(8), payload(8)), err: "too many mip levels"},
+		{name: "huge mipmap count", data: ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 0xFFFFFFFF}.file(payload(8)), err: "too many mip levels"},
+		{name: "Basis Universal", data: ktx2Header{vkFormat: 0, width: 4, height: 4, levels: 1, scheme: ktx2SupercompressionBasisLZ}.file(payload(8)), err: "Basis Universal"},
+		{name: "unknown format", data: ktx2Header{vkFormat: 37, width: 4, height: 4, levels: 1}.file(payload(64)), err: "unknown texture format"},
+	})
+}
+
+func TestParseOffsetOverflow(t *testing.T) {
+	data := ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 1}.file(payload(8))
+	binary.LittleEndian.PutUint64(data[ktx2HeaderSize:], 1<<63)
+	binary.LittleEndian.PutUint64(data[ktx2HeaderSize+8:], 1<<63)
+	if _, err := ParseKTX2(data); err == nil {
+		t.Errorf("ParseKTX2 accepted a level at offset 1<<63")
+	}
+}
+
+func TestParse(t *testing.T) {
+	files := map[string][]byte{
+		"DDS":  ddsHeader{width: 4, height: 4, fourCC: "DXT1"}.file(payload(8)),
+		"KTX":  ktxHeader{format: COMPRESSED_RGB_S3TC_DXT1_EXT, width: 4, height: 4}.file(payload(8)),
+		"KTX2": ktx2Header{vkFormat: 131, width: 4, height: 4, levels: 1}.file(payload(8)),
+	}
+	for name, data := range files {
+		img, err := Parse(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if img.Format != COMPRESSED_RGB_S3TC_DXT1_EXT || len(img.Levels) != 1 {
+			t.Errorf("%s: got format %#x with %d levels; want DXT1 with 1 level", name, img.Format, len(img.Levels))
+		}
+	}
+	if _, err := Parse([]byte("\x89PNG\r\n\x1a\n")); err == nil {
+		t.Errorf("Parse accepted a PNG file")
+	}
+}
+
+// TestCorrupt checks that truncated and corrupted files return errors
+// instead of panicking.
+func TestCorrupt(t *testing.T) {
+	files := [][]byte{
+		ddsHeader{width: 8, height: 8, levels: 4, fourCC: "DXT5"}.file(payload(112)),
+		ddsHeader{width: 4, height: 4, fourCC: "DX10", dxgi: 98}.file(payload(16)),
+		ktxHeader{format: COMPRESSED_RGB_ETC1_WEBGL, width: 8, height: 4, levels: 3}.file(payload(16), payload(8), payload(8)),
+		ktx2Header{vkFormat: 158, width: 8, height: 4, levels: 2}.file(payload(32), payload(16)),
+		ktx2Header{vkFormat: 137, width: 8, height: 8, levels: 1, scheme: ktx2SupercompressionZlib}.file(deflate(payload(64))),
+	}
+	rng := rand.New(rand.NewSource(1))
+	for _, data := range files {
+		for n := 0; n < len(data); n++ {
+			Parse(data[:n])
+		}
+		for i := 0; i < 2000; i++ {
+			corrupt := append([]byte(nil), data...)
+			for j := rng.Intn(4); j >= 0; j-- {
+				corrupt[12+rng.Intn(len(corrupt)-12)] = byte(rng.Intn(256))
+			}
+			Parse(corrupt)
+		}
+	}
+}
+
+func TestLevelSize(t *testing.T) {
+	tests := []struct {
+		format, width, height, want int
+	}{
+		{COMPRESSED_RGB_S3TC_DXT1_EXT, 1, 1, 8},
+		{COMPRESSED_RGB_S3TC_DXT1_EXT, 5, 5, 32},
+		{COMPRESSED_RGBA_S3TC_DXT5_EXT, 4, 4, 16},
+		{COMPRESSED_RGBA_S3TC_DXT5_EXT, 1 << 14, 1 << 14, 1 << 28},
+		{COMPRESSED_RGBA_BPTC_UNORM_EXT, 8, 4, 32},
+		{COMPRESSED_RGB_PVRTC_4BPPV1_IMG, 1, 1, 32},
+		{COMPRESSED_RGBA_PVRTC_2BPPV1_IMG, 1, 1, 32},
+		{COMPRESSED_RGBA_PVRTC_4BPPV1_IMG, 16, 16, 128},
+		{COMPRESSED_RGBA_ASTC_12x12_KHR, 13, 13, 64},
+		{COMPRESSED_SRGB8_ALPHA8_ASTC_5x4_KHR, 5, 5, 32},
+		{0x1908, 4, 4, 0},
+	}
+	for _, tt := range tests {
+		if got := LevelSize(tt.format, tt.width, tt.height); got != tt.want {
+			t.Errorf("LevelSize(%#x, %d, %d) = %d; want %d", tt.format, tt.width, tt.height, got, tt.want)
+		}
+	}
+}
+
+func TestLevelSizeLarge(t *testing.T) {
+	if got := levelSize(COMPRESSED_RGBA_S3TC_DXT5_EXT, maxSize, maxSize); got != 1<<32 {
+		t.Errorf("levelSize of the largest DXT5 texture = %d; want %d", got, uint64(1)<<32)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	tests := []struct {
+		format int
+		want   string
+	}{
+		{COMPRESSED_RGBA_S3TC_DXT5_EXT, "WEBGL_compressed_texture_s3tc"},
+		{COMPRESSED_SRGB_S3TC_DXT1_EXT, "WEBGL_compressed_texture_s3tc_srgb"},
+		{COMPRESSED_RGB_ETC1_WEBGL, "WEBGL_compressed_texture_etc1"},
+		{COMPRESSED_RGBA8_ETC2_EAC, "WEBGL_compressed_texture_etc"},
+		{COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR, "WEBGL_compressed_texture_astc"},
+		{COMPRESSED_RGBA_PVRTC_2BPPV1_IMG, "WEBGL_compressed_texture_pvrtc"},
+		{COMPRESSED_RGBA_BPTC_UNORM_EXT, "EXT_texture_compression_bptc"},
+		{COMPRESSED_RED_RGTC1_EXT, "EXT_texture_compression_rgtc"},
+	}
+	for _, tt := range tests {
+		if got := Extensions(tt.format); len(got) == 0 || got[0] != tt.want {
+			t.Errorf("Extensions(%#x) = %v; want %s first", tt.format, got, tt.want)
+		}
+	}
+	if got := Extensions(0x1908); got != nil {
+		t.Errorf("Extensions(RGBA) = %v; want nil", got)
+	}
+}
@@ -0,0 +1,135 @@
+package compressed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ddsMagic = []byte("DDS ")
+
+const (
+	ddsHeaderSize      = 4 + 124
+	ddsDX10HeaderSize  = 20
+	ddsPixelFormatOfs  = 4 + 72
+	ddsAlphaPixels     = 0x1
+	ddsFourCC          = 0x4
+	ddsCaps2Cubemap    = 0x200
+	ddsCaps2Volume     = 0x200000
+	ddsMipMapCountFlag = 0x20000
+)
+
+// fourCCs maps the FourCC codes of DDS files to compressed formats.
+var fourCCs = map[string]int{
+	"DXT1": COMPRESSED_RGB_S3TC_DXT1_EXT,
+	"DXT2": COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	"DXT3": COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	"DXT4": COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	"DXT5": COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	"ATI1": COMPRESSED_RED_RGTC1_EXT,
+	"BC4U": COMPRESSED_RED_RGTC1_EXT,
+	"BC4S": COMPRESSED_SIGNED_RED_RGTC1_EXT,
+	"ATI2": COMPRESSED_RED_GREEN_RGTC2_EXT,
+	"BC5U": COMPRESSED_RED_GREEN_RGTC2_EXT,
+	"BC5S": COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT,
+	"ETC1": COMPRESSED_RGB_ETC1_WEBGL,
+}
+
+// dxgiFormats maps the DXGI formats of DDS files with a DX10 header to
+// compressed formats.
+var dxgiFormats = map[uint32]int{
+	71: COMPRESSED_RGBA_S3TC_DXT1_EXT,
+	72: COMPRESSED_SRGB_ALPHA_S3TC_DXT1_EXT,
+	74: COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	75: COMPRESSED_SRGB_ALPHA_S3TC_DXT3_EXT,
+	77: COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	78: COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT,
+	80: COMPRESSED_RED_RGTC1_EXT,
+	81: COMPRESSED_SIGNED_RED_RGTC1_EXT,
+	83: COMPRESSED_RED_GREEN_RGTC2_EXT,
+	84: COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT,
+	95: COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_EXT,
+	96: COMPRESSED_RGB_BPTC_SIGNED_FLOAT_EXT,
+	98: COMPRESSED_RGBA_BPTC_UNORM_EXT,
+	99: COMPRESSED_SRGB_ALPHA_BPTC_UNORM_EXT,
+}
+
+// Returns whether data starts like a DDS file.
+func IsDDS(data []byte) bool {
+	return bytes.HasPrefix(data, ddsMagic)
+}
+
+// ParseDDS reads a compressed 2D texture from a DDS file, including ones
+// with a DX10 header. The level data in the returned Image refers to data
+// rather than being copied.
+func ParseDDS(data []byte) (*Image, error) {
+	if !IsDDS(data) {
+		return nil, errors.New("compressed: not a DDS file")
+	}
+	if len(data) < ddsHeaderSize {
+		return nil, errors.New("compressed: DDS header is truncated")
+	}
+	le := binary.LittleEndian
+	flags := le.Uint32(data[8:])
+	height := int(le.Uint32(data[12:]))
+	width := int(le.Uint32(data[16:]))
+	levels := 1
+	if flags&ddsMipMapCountFlag != 0 {
+		levels = int(le.Uint32(data[28:]))
+	}
+	pfFlags := le.Uint32(data[ddsPixelFormatOfs+4:])
+	fourCC := string(data[ddsPixelFormatOfs+8 : ddsPixelFormatOfs+12])
+	caps2 := le.Uint32(data[112:])
+
+	if pfFlags&ddsFourCC == 0 {
+		return nil, errors.New("compressed: DDS file is not compressed")
+	}
+	if caps2&(ddsCaps2Cubemap|ddsCaps2Volume) != 0 {
+		return nil, errors.New("compressed: only 2D DDS textures are supported")
+	}
+
+	pos := ddsHeaderSize
+	var format int
+	if fourCC == "DX10" {
+		if len(data) < pos+ddsDX10HeaderSize {
+			return nil, errors.New("compressed: DDS DX10 header is truncated")
+		}
+		f, ok := dxgiFormats[le.Uint32(data[pos:])]
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+		if arraySize := le.Uint32(data[pos+12:]); arraySize > 1 {
+			return nil, errors.New("compressed: only 2D DDS textures are supported")
+		}
+		format = f
+		pos += ddsDX10HeaderSize
+	} else {
+		f, ok := fourCCs[fourCC]
+		if !ok {
+			return nil, ErrUnknownFormat
+		}
+		if f == COMPRESSED_RGB_S3TC_DXT1_EXT && pfFlags&ddsAlphaPixels != 0 {
+			f = COMPRESSED_RGBA_S3TC_DXT1_EXT
+		}
+		format = f
+	}
+	if levels == 0 {
+		levels = 1
+	}
+	if err := checkDims("DDS", width, height, levels); err != nil {
+		return nil, err
+	}
+
+	img := &Image{Format: format, Width: width, Height: height}
+	for n := 0; n < levels; n++ {
+		w, h := levelDims(width, height, n)
+		size := levelSize(format, w, h)
+		if size > uint64(len(data)-pos) {
+			return nil, errors.New("compressed: DDS file is truncated")
+		}
+		end := pos + int(size)
+		img.Levels = append(img.Levels, Level{w, h, data[pos:end]})
+		pos = end
+	}
+	return img, nil
+}
@@ -0,0 +1,230 @@
+// Package compressed reads GPU compressed textures from KTX, KTX2 and DDS
+// container files, without needing a browser.
+package compressed
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Compressed texture formats, with the values of the WebGL extension enums.
+const (
+	// WEBGL_compressed_texture_s3tc
+	COMPRESSED_RGB_S3TC_DXT1_EXT  = 0x83F0
+	COMPRESSED_RGBA_S3TC_DXT1_EXT = 0x83F1
+	COMPRESSED_RGBA_S3TC_DXT3_EXT = 0x83F2
+	COMPRESSED_RGBA_S3TC_DXT5_EXT = 0x83F3
+
+	// WEBGL_compressed_texture_s3tc_srgb
+	COMPRESSED_SRGB_S3TC_DXT1_EXT       = 0x8C4C
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT1_EXT = 0x8C4D
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT3_EXT = 0x8C4E
+	COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT = 0x8C4F
+
+	// EXT_texture_compression_rgtc
+	COMPRESSED_RED_RGTC1_EXT              = 0x8DBB
+	COMPRESSED_SIGNED_RED_RGTC1_EXT       = 0x8DBC
+	COMPRESSED_RED_GREEN_RGTC2_EXT        = 0x8DBD
+	COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT = 0x8DBE
+
+	// EXT_texture_compression_bptc
+	COMPRESSED_RGBA_BPTC_UNORM_EXT         = 0x8E8C
+	COMPRESSED_SRGB_ALPHA_BPTC_UNORM_EXT   = 0x8E8D
+	COMPRESSED_RGB_BPTC_SIGNED_FLOAT_EXT   = 0x8E8E
+	COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_EXT = 0x8E8F
+
+	// WEBGL_compressed_texture_etc1
+	COMPRESSED_RGB_ETC1_WEBGL = 0x8D64
+
+	// WEBGL_compressed_texture_etc
+	COMPRESSED_R11_EAC                        = 0x9270
+	COMPRESSED_SIGNED_R11_EAC                 = 0x9271
+	COMPRESSED_RG11_EAC                       = 0x9272
+	COMPRESSED_SIGNED_RG11_EAC                = 0x9273
+	COMPRESSED_RGB8_ETC2                      = 0x9274
+	COMPRESSED_SRGB8_ETC2                     = 0x9275
+	COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2  = 0x9276
+	COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2 = 0x9277
+	COMPRESSED_RGBA8_ETC2_EAC                 = 0x9278
+	COMPRESSED_SRGB8_ALPHA8_ETC2_EAC          = 0x9279
+
+	// WEBGL_compressed_texture_astc
+	COMPRESSED_RGBA_ASTC_4x4_KHR           = 0x93B0
+	COMPRESSED_RGBA_ASTC_5x4_KHR           = 0x93B1
+	COMPRESSED_RGBA_ASTC_5x5_KHR           = 0x93B2
+	COMPRESSED_RGBA_ASTC_6x5_KHR           = 0x93B3
+	COMPRESSED_RGBA_ASTC_6x6_KHR           = 0x93B4
+	COMPRESSED_RGBA_ASTC_8x5_KHR           = 0x93B5
+	COMPRESSED_RGBA_ASTC_8x6_KHR           = 0x93B6
+	COMPRESSED_RGBA_ASTC_8x8_KHR           = 0x93B7
+	COMPRESSED_RGBA_ASTC_10x5_KHR          = 0x93B8
+	COMPRESSED_RGBA_ASTC_10x6_KHR          = 0x93B9
+	COMPRESSED_RGBA_ASTC_10x8_KHR          = 0x93BA
+	COMPRESSED_RGBA_ASTC_10x10_KHR         = 0x93BB
+	COMPRESSED_RGBA_ASTC_12x10_KHR         = 0x93BC
+	COMPRESSED_RGBA_ASTC_12x12_KHR         = 0x93BD
+	COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR   = 0x93D0
+	COMPRESSED_SRGB8_ALPHA8_ASTC_5x4_KHR   = 0x93D1
+	COMPRESSED_SRGB8_ALPHA8_ASTC_5x5_KHR   = 0x93D2
+	COMPRESSED_SRGB8_ALPHA8_ASTC_6x5_KHR   = 0x93D3
+	COMPRESSED_SRGB8_ALPHA8_ASTC_6x6_KHR   = 0x93D4
+	COMPRESSED_SRGB8_ALPHA8_ASTC_8x5_KHR   = 0x93D5
+	COMPRESSED_SRGB8_ALPHA8_ASTC_8x6_KHR   = 0x93D6
+	COMPRESSED_SRGB8_ALPHA8_ASTC_8x8_KHR   = 0x93D7
+	COMPRESSED_SRGB8_ALPHA8_ASTC_10x5_KHR  = 0x93D8
+	COMPRESSED_SRGB8_ALPHA8_ASTC_10x6_KHR  = 0x93D9
+	COMPRESSED_SRGB8_ALPHA8_ASTC_10x8_KHR  = 0x93DA
+	COMPRESSED_SRGB8_ALPHA8_ASTC_10x10_KHR = 0x93DB
+	COMPRESSED_SRGB8_ALPHA8_ASTC_12x10_KHR = 0x93DC
+	COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR = 0x93DD
+
+	// WEBGL_compressed_texture_pvrtc
+	COMPRESSED_RGB_PVRTC_4BPPV1_IMG  = 0x8C00
+	COMPRESSED_RGB_PVRTC_2BPPV1_IMG  = 0x8C01
+	COMPRESSED_RGBA_PVRTC_4BPPV1_IMG = 0x8C02
+	COMPRESSED_RGBA_PVRTC_2BPPV1_IMG = 0x8C03
+)
+
+// ErrUnknownFormat is returned for files holding a texture format that is
+// not one of the compressed formats above.
+var ErrUnknownFormat = errors.New("compressed: unknown texture format")
+
+// Image is a compressed 2D texture with its mip levels.
+type Image struct {
+	// Format is one of the compressed texture formats above.
+	Format int
+	Width  int
+	Height int
+
+	// Levels holds the mip levels, starting with the full size one.
+	Levels []Level
+}
+
+// Level is a single mip level of an Image.
+type Level struct {
+	Width  int
+	Height int
+	Data   []byte
+}
+
+// Parse reads a compressed texture from a KTX, KTX2 or DDS file, telling
+// them apart by their first bytes.
+func Parse(data []byte) (*Image, error) {
+	switch {
+	case IsKTX(data):
+		return ParseKTX(data)
+	case IsKTX2(data):
+		return ParseKTX2(data)
+	case IsDDS(data):
+		return ParseDDS(data)
+	}
+	return nil, errors.New("compressed: unknown container format")
+}
+
+// Returns the WebGL extensions that provide format, preferred name first.
+// It returns nil for unknown formats.
+func Extensions(format int) []string {
+	switch {
+	case format >= COMPRESSED_RGB_S3TC_DXT1_EXT && format <= COMPRESSED_RGBA_S3TC_DXT5_EXT:
+		return []string{"WEBGL_compressed_texture_s3tc", "WEBKIT_WEBGL_compressed_texture_s3tc", "MOZ_WEBGL_compressed_texture_s3tc"}
+	case format >= COMPRESSED_SRGB_S3TC_DXT1_EXT && format <= COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT:
+		return []string{"WEBGL_compressed_texture_s3tc_srgb"}
+	case format >= COMPRESSED_RED_RGTC1_EXT && format <= COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT:
+		return []string{"EXT_texture_compression_rgtc"}
+	case format >= COMPRESSED_RGBA_BPTC_UNORM_EXT && format <= COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_EXT:
+		return []string{"EXT_texture_compression_bptc"}
+	case format == COMPRESSED_RGB_ETC1_WEBGL:
+		return []string{"WEBGL_compressed_texture_etc1"}
+	case format >= COMPRESSED_R11_EAC && format <= COMPRESSED_SRGB8_ALPHA8_ETC2_EAC:
+		return []string{"WEBGL_compressed_texture_etc"}
+	case format >= COMPRESSED_RGBA_ASTC_4x4_KHR && format <= COMPRESSED_RGBA_ASTC_12x12_KHR,
+		format >= COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR && format <= COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR:
+		return []string{"WEBGL_compressed_texture_astc"}
+	case format >= COMPRESSED_RGB_PVRTC_4BPPV1_IMG && format <= COMPRESSED_RGBA_PVRTC_2BPPV1_IMG:
+		return []string{"WEBGL_compressed_texture_pvrtc", "WEBKIT_WEBGL_compressed_texture_pvrtc"}
+	}
+	return nil
+}
+
+// astcBlocks holds the block sizes of the ASTC formats, in enum order.
+var astcBlocks = [...][2]int{
+	{4, 4}, {5, 4}, {5, 5}, {6, 5}, {6, 6}, {8, 5}, {8, 6}, {8, 8},
+	{10, 5}, {10, 6}, {10, 8}, {10, 10}, {12, 10}, {12, 12},
+}
+
+// Returns the number of bytes a mip level of the given size takes up in
+// format, or 0 for unknown formats.
+func LevelSize(format, width, height int) int {
+	return int(levelSize(format, width, height))
+}
+
+// levelSize is LevelSize computed in uint64, so that sizes read from a file
+// can not overflow before they are checked against its length.
+func levelSize(format, width, height int) uint64 {
+	w, h := uint64(width), uint64(height)
+	blocks := func(bw, bh, size uint64) uint64 {
+		return ((w + bw - 1) / bw) * ((h + bh - 1) / bh) * size
+	}
+	switch format {
+	case COMPRESSED_RGB_S3TC_DXT1_EXT, COMPRESSED_RGBA_S3TC_DXT1_EXT,
+		COMPRESSED_SRGB_S3TC_DXT1_EXT, COMPRESSED_SRGB_ALPHA_S3TC_DXT1_EXT,
+		COMPRESSED_RED_RGTC1_EXT, COMPRESSED_SIGNED_RED_RGTC1_EXT,
+		COMPRESSED_RGB_ETC1_WEBGL,
+		COMPRESSED_R11_EAC, COMPRESSED_SIGNED_R11_EAC,
+		COMPRESSED_RGB8_ETC2, COMPRESSED_SRGB8_ETC2,
+		COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2, COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2:
+		return blocks(4, 4, 8)
+	case COMPRESSED_RGBA_S3TC_DXT3_EXT, COMPRESSED_RGBA_S3TC_DXT5_EXT,
+		COMPRESSED_SRGB_ALPHA_S3TC_DXT3_EXT, COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT,
+		COMPRESSED_RED_GREEN_RGTC2_EXT, COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT,
+		COMPRESSED_RGBA_BPTC_UNORM_EXT, COMPRESSED_SRGB_ALPHA_BPTC_UNORM_EXT,
+		COMPRESSED_RGB_BPTC_SIGNED_FLOAT_EXT, COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_EXT,
+		COMPRESSED_RG11_EAC, COMPRESSED_SIGNED_RG11_EAC,
+		COMPRESSED_RGBA8_ETC2_EAC, COMPRESSED_SRGB8_ALPHA8_ETC2_EAC:
+		return blocks(4, 4, 16)
+	case COMPRESSED_RGB_PVRTC_4BPPV1_IMG, COMPRESSED_RGBA_PVRTC_4BPPV1_IMG:
+		return (uint64(max(width, 8))*uint64(max(height, 8))*4 + 7) / 8
+	case COMPRESSED_RGB_PVRTC_2BPPV1_IMG, COMPRESSED_RGBA_PVRTC_2BPPV1_IMG:
+		return (uint64(max(width, 16))*uint64(max(height, 8))*2 + 7) / 8
+	}
+	if format >= COMPRESSED_RGBA_ASTC_4x4_KHR && format <= COMPRESSED_RGBA_ASTC_12x12_KHR {
+		b := astcBlocks[format-COMPRESSED_RGBA_ASTC_4x4_KHR]
+		return blocks(uint64(b[0]), uint64(b[1]), 16)
+	}
+	if format >= COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR && format <= COMPRESSED_SRGB8_ALPHA8_ASTC_12x12_KHR {
+		b := astcBlocks[format-COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR]
+		return blocks(uint64(b[0]), uint64(b[1]), 16)
+	}
+	return 0
+}
+
+// maxSize is the largest width or height accepted from a file. It is well
+// above what WebGL implementations support, and keeps level sizes in range.
+const maxSize = 1 << 16
+
+// Returns an error if a texture of the given size with the given number of
+// mip levels can not be valid. container names the file format.
+func checkDims(container string, width, height, levels int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("compressed: " + container + " texture has no size")
+	}
+	if width > maxSize || height > maxSize {
+		return errors.New("compressed: " + container + " texture is too large")
+	}
+	if levels < 0 || levels > bits.Len(uint(max(width, height))) {
+		return errors.New("compressed: " + container + " texture has too many mip levels")
+	}
+	return nil
+}
+
+// Returns the size of mip level n of a texture of the given size.
+func levelDims(width, height, n int) (int, int) {
+	return max(width>>uint(n), 1), max(height>>uint(n), 1)
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,88 @@
+package compressed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var ktxIdentifier = []byte{0xAB, 'K', 'T', 'X', ' ', '1', '1', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// Returns whether data starts like a KTX 1 file.
+func IsKTX(data []byte) bool {
+	return bytes.HasPrefix(data, ktxIdentifier)
+}
+
+// ParseKTX reads a compressed 2D texture from a KTX 1 file. The level data
+// in the returned Image refers to data rather than being copied.
+func ParseKTX(data []byte) (*Image, error) {
+	if !IsKTX(data) {
+		return nil, errors.New("compressed: not a KTX file")
+	}
+	if len(data) < 64 {
+		return nil, errors.New("compressed: KTX header is truncated")
+	}
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data[12:]) {
+	case 0x04030201:
+		order = binary.LittleEndian
+	case 0x01020304:
+		order = binary.BigEndian
+	default:
+		return nil, errors.New("compressed: KTX file has an invalid endianness")
+	}
+	field := func(i int) int {
+		return int(order.Uint32(data[16+i*4:]))
+	}
+	glType := field(0)
+	glFormat := field(2)
+	glInternalFormat := field(3)
+	width := field(5)
+	height := field(6)
+	depth := field(7)
+	arrayElements := field(8)
+	faces := field(9)
+	levels := field(10)
+	kvBytes := field(11)
+
+	if glType != 0 || glFormat != 0 {
+		return nil, errors.New("compressed: KTX file is not compressed")
+	}
+	if LevelSize(glInternalFormat, 1, 1) == 0 {
+		return nil, ErrUnknownFormat
+	}
+	if depth > 1 || arrayElements > 0 || faces != 1 {
+		return nil, errors.New("compressed: only 2D KTX textures are supported")
+	}
+	if levels == 0 {
+		levels = 1
+	}
+	if err := checkDims("KTX", width, height, levels); err != nil {
+		return nil, err
+	}
+
+	img := &Image{Format: glInternalFormat, Width: width, Height: height}
+	if uint64(kvBytes) > uint64(len(data)-64) {
+		return nil, errors.New("compressed: KTX file is truncated")
+	}
+	pos := 64 + kvBytes
+	for n := 0; n < levels; n++ {
+		if len(data)-pos < 4 {
+			return nil, errors.New("compressed: KTX file is truncated")
+		}
+		size := uint64(order.Uint32(data[pos:]))
+		pos += 4
+		if size > uint64(len(data)-pos) {
+			return nil, errors.New("compressed: KTX file is truncated")
+		}
+		w, h := levelDims(width, height, n)
+		want := levelSize(glInternalFormat, w, h)
+		if size < want {
+			return nil, errors.New("compressed: KTX mip level is too small for its size")
+		}
+		// imageSize may include padding, which the upload must not see.
+		img.Levels = append(img.Levels, Level{w, h, data[pos : pos+int(want)]})
+		pos += int((size + 3) &^ 3)
+	}
+	return img, nil
+}
@@ -0,0 +1,148 @@
+package compressed
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+var ktx2Identifier = []byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// KTX2 supercompression schemes.
+const (
+	ktx2SupercompressionNone    = 0
+	ktx2SupercompressionBasisLZ = 1
+	ktx2SupercompressionZstd    = 2
+	ktx2SupercompressionZlib    = 3
+)
+
+// vkFormats maps the Vulkan formats that can be stored in a KTX2 file to
+// the matching WebGL compressed format.
+var vkFormats = map[uint32]int{
+	131: COMPRESSED_RGB_S3TC_DXT1_EXT,
+	132: COMPRESSED_SRGB_S3TC_DXT1_EXT,
+	133: COMPRESSED_RGBA_S3TC_DXT1_EXT,
+	134: COMPRESSED_SRGB_ALPHA_S3TC_DXT1_EXT,
+	135: COMPRESSED_RGBA_S3TC_DXT3_EXT,
+	136: COMPRESSED_SRGB_ALPHA_S3TC_DXT3_EXT,
+	137: COMPRESSED_RGBA_S3TC_DXT5_EXT,
+	138: COMPRESSED_SRGB_ALPHA_S3TC_DXT5_EXT,
+	139: COMPRESSED_RED_RGTC1_EXT,
+	140: COMPRESSED_SIGNED_RED_RGTC1_EXT,
+	141: COMPRESSED_RED_GREEN_RGTC2_EXT,
+	142: COMPRESSED_SIGNED_RED_GREEN_RGTC2_EXT,
+	143: COMPRESSED_RGB_BPTC_UNSIGNED_FLOAT_EXT,
+	144: COMPRESSED_RGB_BPTC_SIGNED_FLOAT_EXT,
+	145: COMPRESSED_RGBA_BPTC_UNORM_EXT,
+	146: COMPRESSED_SRGB_ALPHA_BPTC_UNORM_EXT,
+	147: COMPRESSED_RGB8_ETC2,
+	148: COMPRESSED_SRGB8_ETC2,
+	149: COMPRESSED_RGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+	150: COMPRESSED_SRGB8_PUNCHTHROUGH_ALPHA1_ETC2,
+	151: COMPRESSED_RGBA8_ETC2_EAC,
+	152: COMPRESSED_SRGB8_ALPHA8_ETC2_EAC,
+	153: COMPRESSED_R11_EAC,
+	154: COMPRESSED_SIGNED_R11_EAC,
+	155: COMPRESSED_RG11_EAC,
+	156: COMPRESSED_SIGNED_RG11_EAC,
+
+	1000054000: COMPRESSED_RGBA_PVRTC_2BPPV1_IMG,
+	1000054001: COMPRESSED_RGBA_PVRTC_4BPPV1_IMG,
+}
+
+func init() {
+	// The ASTC formats come in unorm/sRGB pairs, starting at 157.
+	for i := 0; i < len(astcBlocks); i++ {
+		vkFormats[uint32(157+2*i)] = COMPRESSED_RGBA_ASTC_4x4_KHR + i
+		vkFormats[uint32(158+2*i)] = COMPRESSED_SRGB8_ALPHA8_ASTC_4x4_KHR + i
+	}
+}
+
+// Returns whether data starts like a KTX 2 file.
+func IsKTX2(data []byte) bool {
+	return bytes.HasPrefix(data, ktx2Identifier)
+}
+
+// ParseKTX2 reads a compressed 2D texture from a KTX 2 file. Files without
+// supercompression or with zlib supercompression are supported; Basis
+// Universal and Zstandard supercompressed files are not.
+func ParseKTX2(data []byte) (*Image, error) {
+	if !IsKTX2(data) {
+		return nil, errors.New("compressed: not a KTX2 file")
+	}
+	const headerSize = 12 + 9*4 + 4*4 + 2*8
+	if len(data) < headerSize {
+		return nil, errors.New("compressed: KTX2 header is truncated")
+	}
+	le := binary.LittleEndian
+	field := func(i int) uint32 {
+		return le.Uint32(data[12+i*4:])
+	}
+	vkFormat := field(0)
+	width := int(field(2))
+	height := int(field(3))
+	depth := int(field(4))
+	layers := int(field(5))
+	faces := int(field(6))
+	levels := int(field(7))
+	scheme := field(8)
+
+	switch scheme {
+	case ktx2SupercompressionNone, ktx2SupercompressionZlib:
+	case ktx2SupercompressionBasisLZ:
+		return nil, errors.New("compressed: KTX2 file holds a Basis Universal texture")
+	case ktx2SupercompressionZstd:
+		return nil, errors.New("compressed: Zstandard supercompressed KTX2 files are not supported")
+	default:
+		return nil, errors.New("compressed: KTX2 file has an unknown supercompression scheme")
+	}
+	format, ok := vkFormats[vkFormat]
+	if !ok {
+		return nil, ErrUnknownFormat
+	}
+	if depth > 1 || layers > 0 || faces != 1 {
+		return nil, errors.New("compressed: only 2D KTX2 textures are supported")
+	}
+	if levels == 0 {
+		levels = 1
+	}
+	if err := checkDims("KTX2", width, height, levels); err != nil {
+		return nil, err
+	}
+
+	index := headerSize
+	if index+levels*24 > len(data) {
+		return nil, errors.New("compressed: KTX2 level index is truncated")
+	}
+	img := &Image{Format: format, Width: width, Height: height}
+	for n := 0; n < levels; n++ {
+		entry := data[index+n*24:]
+		offset := le.Uint64(entry)
+		length := le.Uint64(entry[8:])
+		if offset > uint64(len(data)) || length > uint64(len(data))-offset {
+			return nil, errors.New("compressed: KTX2 file is truncated")
+		}
+		level := data[offset : offset+length]
+		w, h := levelDims(width, height, n)
+		size := levelSize(format, w, h)
+		if scheme == ktx2SupercompressionZlib {
+			r, err := zlib.NewReader(bytes.NewReader(level))
+			if err != nil {
+				return nil, err
+			}
+			// Read no more than the level needs, whatever the stream holds.
+			level, err = ioutil.ReadAll(io.LimitReader(r, int64(size)))
+			if err != nil {
+				return nil, err
+			}
+		}
+		if uint64(len(level)) < size {
+			return nil, errors.New("compressed: KTX2 mip level is too small for its size")
+		}
+		img.Levels = append(img.Levels, Level{w, h, level[:size]})
+	}
+	return img, nil
+}
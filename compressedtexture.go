@@ -0,0 +1,55 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+
+	"github.com/justinclift/webgl/compressed"
+)
+
+// NewCompressedTexture uploads a compressed image, such as one read by
+// compressed.Parse, as a texture. It returns an *ExtensionError if the
+// context does not support the image's format. Compressed textures can
+// not have mipmaps generated, so mipmap filtering is only used when the
+// image holds a full set of mip levels. If opts is nil,
+// DefaultTextureOptions is used.
+func (c *Context) NewCompressedTexture(img *compressed.Image, opts *TextureOptions) (*Texture, error) {
+	if opts == nil {
+		opts = DefaultTextureOptions()
+	}
+	names := compressed.Extensions(img.Format)
+	if names == nil {
+		return nil, compressed.ErrUnknownFormat
+	}
+	if _, err := c.extension(names...); err != nil {
+		return nil, err
+	}
+	if len(img.Levels) == 0 {
+		return nil, errors.New("compressed image has no mip levels")
+	}
+
+	tex := &Texture{Object: c.CreateTexture(), Width: img.Width, Height: img.Height, gl: c}
+	c.BindTexture(TEXTURE_2D, tex.Object)
+	levels := img.Levels
+	if !canMipmap(img.Width, img.Height, opts) {
+		levels = levels[:1]
+	}
+	for n, level := range levels {
+		c.CompressedTexImage2D(TEXTURE_2D, n, img.Format, level.Width, level.Height, 0, level.Data)
+	}
+	tex.Mipmapped = len(levels) > 1 && len(levels) == mipLevelCount(img.Width, img.Height)
+	c.setTextureParameters(img.Width, img.Height, opts, tex.Mipmapped)
+	return tex, nil
+}
+
+// Returns how many mip levels a full set for a texture of the given size
+// has, down to 1x1.
+func mipLevelCount(width, height int) int {
+	n := 1
+	for width > 1 || height > 1 {
+		width, height = width/2, height/2
+		n++
+	}
+	return n
+}
@@ -0,0 +1,16 @@
+// +build wasm
+
+package webgl
+
+// WEBGL_depth_texture
+const UNSIGNED_INT_24_8_WEBGL = 0x84FA
+
+// EnableDepthTextures enables WEBGL_depth_texture, which allows textures
+// of format DEPTH_COMPONENT, with type UNSIGNED_SHORT or UNSIGNED_INT, and
+// DEPTH_STENCIL, with type UNSIGNED_INT_24_8_WEBGL. Such textures can be
+// attached to framebuffers and sampled, but not uploaded to. Returns an
+// *ExtensionError if the extension is not supported.
+func (c *Context) EnableDepthTextures() error {
+	_, err := c.extension("WEBGL_depth_texture", "WEBKIT_WEBGL_depth_texture", "MOZ_WEBGL_depth_texture")
+	return err
+}
@@ -0,0 +1,129 @@
+// +build wasm
+
+package webgl
+
+import (
+	"strconv"
+	"syscall/js"
+)
+
+// WEBGL_draw_buffers
+const (
+	COLOR_ATTACHMENT0_WEBGL     = 0x8CE0
+	COLOR_ATTACHMENT1_WEBGL     = 0x8CE1
+	COLOR_ATTACHMENT2_WEBGL     = 0x8CE2
+	COLOR_ATTACHMENT3_WEBGL     = 0x8CE3
+	COLOR_ATTACHMENT4_WEBGL     = 0x8CE4
+	COLOR_ATTACHMENT5_WEBGL     = 0x8CE5
+	COLOR_ATTACHMENT6_WEBGL     = 0x8CE6
+	COLOR_ATTACHMENT7_WEBGL     = 0x8CE7
+	COLOR_ATTACHMENT8_WEBGL     = 0x8CE8
+	COLOR_ATTACHMENT9_WEBGL     = 0x8CE9
+	COLOR_ATTACHMENT10_WEBGL    = 0x8CEA
+	COLOR_ATTACHMENT11_WEBGL    = 0x8CEB
+	COLOR_ATTACHMENT12_WEBGL    = 0x8CEC
+	COLOR_ATTACHMENT13_WEBGL    = 0x8CED
+	COLOR_ATTACHMENT14_WEBGL    = 0x8CEE
+	COLOR_ATTACHMENT15_WEBGL    = 0x8CEF
+	DRAW_BUFFER0_WEBGL          = 0x8825
+	DRAW_BUFFER1_WEBGL          = 0x8826
+	DRAW_BUFFER2_WEBGL          = 0x8827
+	DRAW_BUFFER3_WEBGL          = 0x8828
+	DRAW_BUFFER4_WEBGL          = 0x8829
+	DRAW_BUFFER5_WEBGL          = 0x882A
+	DRAW_BUFFER6_WEBGL          = 0x882B
+	DRAW_BUFFER7_WEBGL          = 0x882C
+	DRAW_BUFFER8_WEBGL          = 0x882D
+	DRAW_BUFFER9_WEBGL          = 0x882E
+	DRAW_BUFFER10_WEBGL         = 0x882F
+	DRAW_BUFFER11_WEBGL         = 0x8830
+	DRAW_BUFFER12_WEBGL         = 0x8831
+	DRAW_BUFFER13_WEBGL         = 0x8832
+	DRAW_BUFFER14_WEBGL         = 0x8833
+	DRAW_BUFFER15_WEBGL         = 0x8834
+	MAX_COLOR_ATTACHMENTS_WEBGL = 0x8CDF
+	MAX_DRAW_BUFFERS_WEBGL      = 0x8824
+)
+
+// DrawBuffersError is returned when more color attachments are asked for
+// than the context can draw to at once.
+type DrawBuffersError struct {
+	Requested int
+	Max       int
+}
+
+func (e *DrawBuffersError) Error() string {
+	return strconv.Itoa(e.Requested) + " color attachments requested, but only " +
+		strconv.Itoa(e.Max) + " are supported"
+}
+
+// DrawBuffers is the WEBGL_draw_buffers extension, which lets a fragment
+// shader write to several color attachments at once through gl_FragData.
+// Its methods run any batched commands first, since they are not part of
+// the batch.
+type DrawBuffers struct {
+	ext js.Value
+	gl  *Context
+}
+
+// Returns the WEBGL_draw_buffers extension, or an *ExtensionError if it is
+// not supported.
+func (c *Context) DrawBuffers() (*DrawBuffers, error) {
+	ext, err := c.extension("WEBGL_draw_buffers")
+	if err != nil {
+		return nil, err
+	}
+	return &DrawBuffers{ext: ext, gl: c}, nil
+}
+
+// Returns how many color attachments can be drawn to at once. This is 1
+// when WEBGL_draw_buffers is not supported, and an *ExtensionError is
+// returned with it.
+func (c *Context) MaxDrawBuffers() (int, error) {
+	d, err := c.DrawBuffers()
+	if err != nil {
+		return 1, err
+	}
+	return d.MaxDrawBuffers(), nil
+}
+
+// Checks that n color attachments can be drawn to at once, returning an
+// *ExtensionError or a *DrawBuffersError if not.
+func (c *Context) checkDrawBuffers(n int) error {
+	if n <= 1 {
+		return nil
+	}
+	d, err := c.DrawBuffers()
+	if err != nil {
+		return err
+	}
+	if max := d.MaxDrawBuffers(); n > max {
+		return &DrawBuffersError{Requested: n, Max: max}
+	}
+	if max := d.MaxColorAttachments(); n > max {
+		return &DrawBuffersError{Requested: n, Max: max}
+	}
+	return nil
+}
+
+// Sets which color attachments of the bound framebuffer the outputs of the
+// fragment shader are written to. Output i goes to buffers[i], which is
+// COLOR_ATTACHMENTi_WEBGL or NONE.
+func (d *DrawBuffers) DrawBuffers(buffers []int) {
+	arr := make([]interface{}, len(buffers))
+	for i, b := range buffers {
+		arr[i] = b
+	}
+	d.gl.FlushBatch()
+	d.ext.Call("drawBuffersWEBGL", arr)
+}
+
+// Returns the maximum number of draw buffers.
+func (d *DrawBuffers) MaxDrawBuffers() int {
+	return d.gl.callResult("getParameter", MAX_DRAW_BUFFERS_WEBGL).Int()
+}
+
+// Returns the maximum number of color attachments of a framebuffer.
+func (d *DrawBuffers) MaxColorAttachments() int {
+	return d.gl.callResult("getParameter", MAX_COLOR_ATTACHMENTS_WEBGL).Int()
+}
@@ -0,0 +1,46 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+)
+
+// ExtensionError is returned when something needs a WebGL extension that
+// the context does not support.
+type ExtensionError struct {
+	Name string
+}
+
+func (e *ExtensionError) Error() string {
+	return "webgl extension " + e.Name + " is not supported"
+}
+
+// Enables the first of the named extensions that is supported and returns
+// its extension object. Names after the first are usually vendor prefixed
+// variants. Extension objects are cached, so asking again is cheap.
+func (c *Context) extension(names ...string) (js.Value, error) {
+	if c.exts == nil {
+		c.exts = make(map[string]js.Value)
+	}
+	for _, name := range names {
+		if ext, ok := c.exts[name]; ok {
+			if isNullish(ext) {
+				continue
+			}
+			return ext, nil
+		}
+		ext := c.callResult("getExtension", name)
+		c.exts[name] = ext
+		if !isNullish(ext) {
+			return ext, nil
+		}
+	}
+	return js.Value{}, &ExtensionError{names[0]}
+}
+
+// Returns whether the named extension is supported, enabling it if so.
+func (c *Context) HasExtension(name string) bool {
+	_, err := c.extension(name)
+	return err == nil
+}
@@ -0,0 +1,194 @@
+// +build wasm
+
+package webgl
+
+import (
+	"math"
+	"syscall/js"
+)
+
+// OES_texture_half_float
+const HALF_FLOAT_OES = 0x8D61
+
+// EXT_color_buffer_half_float and WEBGL_color_buffer_float
+const (
+	RGBA32F_EXT                               = 0x8814
+	RGB32F_EXT                                = 0x8815
+	RGBA16F_EXT                               = 0x881A
+	RGB16F_EXT                                = 0x881B
+	FRAMEBUFFER_ATTACHMENT_COMPONENT_TYPE_EXT = 0x8211
+	UNSIGNED_NORMALIZED_EXT                   = 0x8C17
+)
+
+// FloatTextureSupport says which float texture features a context has.
+type FloatTextureSupport struct {
+	// Float and HalfFloat are true if textures of type FLOAT and
+	// HALF_FLOAT_OES can be created.
+	Float     bool
+	HalfFloat bool
+
+	// FloatLinear and HalfFloatLinear are true if such textures can be
+	// sampled with LINEAR filtering.
+	FloatLinear     bool
+	HalfFloatLinear bool
+
+	// RenderFloat and RenderHalfFloat are true if such textures can be
+	// rendered to. These are found by building a framebuffer, since some
+	// implementations render to float textures without the color buffer
+	// extensions, and others list the extensions but can not.
+	RenderFloat     bool
+	RenderHalfFloat bool
+}
+
+// EnableFloatTextures enables every float texture extension the context
+// supports and returns what can be done with float textures.
+func (c *Context) EnableFloatTextures() FloatTextureSupport {
+	var s FloatTextureSupport
+	s.Float = c.HasExtension("OES_texture_float")
+	s.HalfFloat = c.HasExtension("OES_texture_half_float")
+	s.FloatLinear = s.Float && c.HasExtension("OES_texture_float_linear")
+	s.HalfFloatLinear = s.HalfFloat && c.HasExtension("OES_texture_half_float_linear")
+	if s.Float {
+		c.extension("WEBGL_color_buffer_float", "EXT_color_buffer_float")
+		s.RenderFloat = c.CanRenderToType(FLOAT)
+	}
+	if s.HalfFloat {
+		c.extension("EXT_color_buffer_half_float")
+		s.RenderHalfFloat = c.CanRenderToType(HALF_FLOAT_OES)
+	}
+	return s
+}
+
+// CanRenderToType returns whether an RGBA texture of type typ, such as
+// FLOAT or HALF_FLOAT_OES, can be attached to a complete framebuffer. The
+// texture type extension has to be enabled first. The framebuffer and
+// texture bindings are left as they were, and so are errors raised before
+// the call: GetError still reports them afterwards.
+func (c *Context) CanRenderToType(typ int) bool {
+	// Keep the errors raised before the probe for GetError, so that only
+	// the probe's own are thrown away below.
+	c.savedErrors = append(c.savedErrors, c.readErrors()...)
+
+	framebuffer := c.callResult("getParameter", FRAMEBUFFER_BINDING)
+	texture := c.callResult("getParameter", TEXTURE_BINDING_2D)
+
+	tex := c.CreateTexture()
+	c.BindTexture(TEXTURE_2D, tex)
+	c.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, NEAREST)
+	c.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, NEAREST)
+	c.TexImage2DPixels(TEXTURE_2D, 0, RGBA, 4, 4, 0, RGBA, typ, nil)
+	fb := c.CreateFramebuffer()
+	c.BindFramebuffer(FRAMEBUFFER, fb)
+	c.FramebufferTexture2D(FRAMEBUFFER, COLOR_ATTACHMENT0, TEXTURE_2D, tex, 0)
+	// An unsupported type makes texImage2D fail, which leaves the texture
+	// without storage and the framebuffer incomplete.
+	ok := c.CheckFramebufferStatus(FRAMEBUFFER) == FRAMEBUFFER_COMPLETE
+
+	c.BindFramebuffer(FRAMEBUFFER, nullableRef(framebuffer))
+	c.BindTexture(TEXTURE_2D, nullableRef(texture))
+	c.DeleteFramebuffer(fb)
+	c.DeleteTexture(tex)
+	// A failed upload records an error, which is not the caller's.
+	c.readErrors()
+	return ok
+}
+
+// Reads and clears the error flags of the context. WebGL keeps at most
+// one flag per error code, so the loop is bounded.
+func (c *Context) readErrors() []int {
+	var errs []int
+	for i := 0; i < 8; i++ {
+		err := c.callResult("getError")
+		if err.Type() != js.TypeNumber || err.Int() == NO_ERROR {
+			break
+		}
+		errs = append(errs, err.Int())
+	}
+	return errs
+}
+
+// Returns a pointer to v, or nil if v is null.
+func nullableRef(v js.Value) *js.Value {
+	if isNullish(v) {
+		return nil
+	}
+	return &v
+}
+
+// Float32ToFloat16 converts a float32 to the bits of the nearest IEEE 754
+// half precision float, rounding ties to even. Values too large become
+// infinity, and values too small become zero or a subnormal.
+func Float32ToFloat16(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23) & 0xFF
+	mant := b & 0x7FFFFF
+
+	switch {
+	case exp == 0xFF:
+		if mant != 0 {
+			// Keep NaNs quiet.
+			return sign | 0x7E00 | uint16(mant>>13)
+		}
+		return sign | 0x7C00
+	case exp > 127+15:
+		return sign | 0x7C00
+	case exp >= 127-14:
+		// Normal: rebias the exponent and round the mantissa. A carry out
+		// of the mantissa correctly bumps the exponent, up to infinity.
+		h := uint32(exp-127+15)<<10 | mant>>13
+		rest := mant & 0x1FFF
+		if rest > 0x1000 || rest == 0x1000 && h&1 != 0 {
+			h++
+		}
+		return sign | uint16(h)
+	case exp >= 127-25:
+		// Subnormal: shift the mantissa, with its implicit bit, into place.
+		mant |= 0x800000
+		shift := uint(127 - 14 - exp + 13)
+		h := mant >> shift
+		rest := mant & (1<<shift - 1)
+		half := uint32(1) << (shift - 1)
+		if rest > half || rest == half && h&1 != 0 {
+			h++
+		}
+		return sign | uint16(h)
+	}
+	return sign
+}
+
+// Float16ToFloat32 converts the bits of a half precision float to a
+// float32.
+func Float16ToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1F
+	mant := uint32(h & 0x3FF)
+	switch {
+	case exp == 0x1F:
+		return math.Float32frombits(sign | 0x7F800000 | mant<<13)
+	case exp != 0:
+		return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+	case mant == 0:
+		return math.Float32frombits(sign)
+	}
+	// Subnormal: normalize the mantissa.
+	e := uint32(127 - 14)
+	for mant&0x400 == 0 {
+		mant <<= 1
+		e--
+	}
+	return math.Float32frombits(sign | e<<23 | (mant&0x3FF)<<13)
+}
+
+// Float32sToFloat16s converts src to half precision floats, for uploading
+// with type HALF_FLOAT_OES. If dst is large enough it is reused.
+func Float32sToFloat16s(dst []uint16, src []float32) []uint16 {
+	if cap(dst) < len(src) {
+		dst = make([]uint16, len(src))
+	}
+	dst = dst[:len(src)]
+	for i, f := range src {
+		dst[i] = Float32ToFloat16(f)
+	}
+	return dst
+}
@@ -0,0 +1,107 @@
+// +build wasm
+
+package webgl
+
+import (
+	"math"
+	"testing"
+)
+
+func pow2(e int) float32 {
+	return float32(math.Ldexp(1, e))
+}
+
+func TestFloat32ToFloat16(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float32
+		want uint16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3C00},
+		{"minus two", -2, 0xC000},
+		{"largest normal", 65504, 0x7BFF},
+		{"rounds down to largest normal", 65519, 0x7BFF},
+		{"tie rounds up to infinity", 65520, 0x7C00},
+		{"overflow", 1e10, 0x7C00},
+		{"negative overflow", -1e10, 0xFC00},
+		{"infinity", float32(math.Inf(1)), 0x7C00},
+		{"negative infinity", float32(math.Inf(-1)), 0xFC00},
+		{"tie to even down", 1 + pow2(-11), 0x3C00},
+		{"tie to even up", 1 + 3*pow2(-11), 0x3C02},
+		{"above tie", 1 + pow2(-11) + pow2(-20), 0x3C01},
+		{"smallest normal", pow2(-14), 0x0400},
+		{"largest subnormal", pow2(-14) - pow2(-24), 0x03FF},
+		{"subnormal tie carries into normal", pow2(-14) - pow2(-25), 0x0400},
+		{"smallest subnormal", pow2(-24), 0x0001},
+		{"subnormal tie to even down", pow2(-25), 0x0000},
+		{"subnormal tie to even up", 3 * pow2(-25), 0x0002},
+		{"above subnormal tie", pow2(-25) + pow2(-40), 0x0001},
+		{"underflow", pow2(-26), 0x0000},
+		{"negative underflow", -pow2(-30), 0x8000},
+	}
+	for _, tt := range tests {
+		if got := Float32ToFloat16(tt.f); got != tt.want {
+			t.Errorf("%s: Float32ToFloat16(%g) = %#04x; want %#04x", tt.name, tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestFloat16RoundTrip(t *testing.T) {
+	for i := 0; i <= 0xFFFF; i++ {
+		h := uint16(i)
+		f := Float16ToFloat32(h)
+		got := Float32ToFloat16(f)
+		if h&0x7C00 == 0x7C00 && h&0x3FF != 0 {
+			if !math.IsNaN(float64(f)) {
+				t.Errorf("Float16ToFloat32(%#04x) = %g; want NaN", h, f)
+			}
+			if got&0x7C00 != 0x7C00 || got&0x3FF == 0 || got&0x8000 != h&0x8000 {
+				t.Errorf("NaN %#04x round trips to %#04x; want a NaN with the same sign", h, got)
+			}
+			continue
+		}
+		if got != h {
+			t.Errorf("%#04x round trips to %#04x (%g)", h, got, f)
+		}
+	}
+}
+
+func TestFloat32ToFloat16NaN(t *testing.T) {
+	for _, bits := range []uint32{0x7FC00000, 0xFFC00000, 0x7F800001, 0x7FBFFFFF} {
+		h := Float32ToFloat16(math.Float32frombits(bits))
+		if h&0x7C00 != 0x7C00 || h&0x3FF == 0 {
+			t.Errorf("Float32ToFloat16(%#08x) = %#04x; want a NaN", bits, h)
+		}
+		if h>>15 != uint16(bits>>31) {
+			t.Errorf("Float32ToFloat16(%#08x) = %#04x; want the sign kept", bits, h)
+		}
+	}
+}
+
+func TestFloat16ToFloat32(t *testing.T) {
+	tests := []struct {
+		h    uint16
+		want float32
+	}{
+		{0x0000, 0},
+		{0x3C00, 1},
+		{0xC000, -2},
+		{0x7BFF, 65504},
+		{0x0400, pow2(-14)},
+		{0x03FF, pow2(-14) - pow2(-24)},
+		{0x0001, pow2(-24)},
+		{0x8001, -pow2(-24)},
+		{0x7C00, float32(math.Inf(1))},
+		{0xFC00, float32(math.Inf(-1))},
+	}
+	for _, tt := range tests {
+		if got := Float16ToFloat32(tt.h); got != tt.want {
+			t.Errorf("Float16ToFloat32(%#04x) = %g; want %g", tt.h, got, tt.want)
+		}
+	}
+	if got := math.Float32bits(Float16ToFloat32(0x8000)); got != 0x80000000 {
+		t.Errorf("Float16ToFloat32(0x8000) = %#08x; want negative zero", got)
+	}
+}
@@ -0,0 +1,286 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+	"strconv"
+	"syscall/js"
+)
+
+// FramebufferError is returned when a framebuffer is not complete.
+// Status holds the value returned by CheckFramebufferStatus.
+type FramebufferError struct {
+	Status int
+}
+
+func (e *FramebufferError) Error() string {
+	return "framebuffer is not complete: " + FramebufferStatusString(e.Status)
+}
+
+// Returns the name of a framebuffer status, such as
+// "FRAMEBUFFER_INCOMPLETE_ATTACHMENT".
+func FramebufferStatusString(status int) string {
+	switch status {
+	case FRAMEBUFFER_COMPLETE:
+		return "FRAMEBUFFER_COMPLETE"
+	case FRAMEBUFFER_INCOMPLETE_ATTACHMENT:
+		return "FRAMEBUFFER_INCOMPLETE_ATTACHMENT"
+	case FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT:
+		return "FRAMEBUFFER_INCOMPLETE_MISSING_ATTACHMENT"
+	case FRAMEBUFFER_INCOMPLETE_DIMENSIONS:
+		return "FRAMEBUFFER_INCOMPLETE_DIMENSIONS"
+	case FRAMEBUFFER_UNSUPPORTED:
+		return "FRAMEBUFFER_UNSUPPORTED"
+	case 0:
+		// checkFramebufferStatus returns 0 when the context is lost.
+		return "CONTEXT_LOST_WEBGL"
+	}
+	return "0x" + strconv.FormatInt(int64(status), 16)
+}
+
+// Checks the completeness of the framebuffer bound to target, returning a
+// *FramebufferError if it is not complete.
+func (c *Context) CheckFramebuffer(target int) error {
+	status := c.CheckFramebufferStatus(target)
+	if status != FRAMEBUFFER_COMPLETE {
+		return &FramebufferError{status}
+	}
+	return nil
+}
+
+// RenderTargetFormat describes the attachments of a RenderTarget.
+type RenderTargetFormat struct {
+	// ColorFormat is the format of the color texture, such as RGBA or RGB.
+	// Zero means no color texture, which not every implementation
+	// supports.
+	ColorFormat int
+
+	// ColorType is the data type of the color texture, such as UNSIGNED_BYTE.
+	ColorType int
+
+	// ColorAttachments is the number of color textures, all with the same
+	// format. Zero means one. More than one needs WEBGL_draw_buffers.
+	ColorAttachments int
+
+	// If Depth is true, a depth buffer is attached.
+	Depth bool
+
+	// If Stencil is true, a stencil buffer is attached.
+	Stencil bool
+
+	// If DepthTexture is true, the depth buffer, or the depth and stencil
+	// buffer, is a texture that can be sampled, rather than a
+	// renderbuffer. This implies Depth, and needs WEBGL_depth_texture.
+	DepthTexture bool
+}
+
+// Returns the format of an RGBA render target with a depth buffer.
+func DefaultRenderTargetFormat() RenderTargetFormat {
+	return RenderTargetFormat{ColorFormat: RGBA, ColorType: UNSIGNED_BYTE, Depth: true}
+}
+
+// Returns the number of color textures of the format.
+func (f RenderTargetFormat) colorAttachments() int {
+	if f.ColorFormat == 0 {
+		return 0
+	}
+	if f.ColorAttachments < 1 {
+		return 1
+	}
+	return f.ColorAttachments
+}
+
+// RenderTarget is a framebuffer with color textures and an optional depth
+// and/or stencil renderbuffer or texture, for rendering into textures.
+type RenderTarget struct {
+	Framebuffer *js.Value
+
+	// Texture is the first color texture, and Textures holds all of them
+	// in attachment order.
+	Texture  *js.Value
+	Textures []*js.Value
+
+	// Renderbuffer or DepthTexture holds the depth and/or stencil buffer.
+	Renderbuffer *js.Value
+	DepthTexture *js.Value
+
+	Width  int
+	Height int
+	Format RenderTargetFormat
+
+	gl *Context
+}
+
+// NewRenderTarget creates a framebuffer of the given size with the
+// attachments described by format. If the framebuffer is not complete,
+// everything created is deleted again and a *FramebufferError is returned.
+// Asking for more color attachments than can be drawn to at once returns
+// an *ExtensionError or a *DrawBuffersError.
+func NewRenderTarget(c *Context, width, height int, format RenderTargetFormat) (*RenderTarget, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("render target size must be positive")
+	}
+	if err := c.checkDrawBuffers(format.colorAttachments()); err != nil {
+		return nil, err
+	}
+	if format.DepthTexture {
+		if err := c.EnableDepthTextures(); err != nil {
+			return nil, err
+		}
+	}
+	rt := &RenderTarget{
+		Width:  width,
+		Height: height,
+		Format: format,
+		gl:     c,
+	}
+	rt.Framebuffer = c.CreateFramebuffer()
+	rt.Textures = make([]*js.Value, format.colorAttachments())
+	for i := range rt.Textures {
+		rt.Textures[i] = c.CreateTexture()
+		c.BindTexture(TEXTURE_2D, rt.Textures[i])
+		c.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, LINEAR)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, LINEAR)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, CLAMP_TO_EDGE)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, CLAMP_TO_EDGE)
+	}
+	if len(rt.Textures) > 0 {
+		rt.Texture = rt.Textures[0]
+	}
+	switch {
+	case format.DepthTexture:
+		rt.DepthTexture = c.CreateTexture()
+		c.BindTexture(TEXTURE_2D, rt.DepthTexture)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, NEAREST)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, NEAREST)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, CLAMP_TO_EDGE)
+		c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, CLAMP_TO_EDGE)
+	case format.Depth || format.Stencil:
+		rt.Renderbuffer = c.CreateRenderbuffer()
+	}
+	rt.allocate()
+
+	c.BindFramebuffer(FRAMEBUFFER, rt.Framebuffer)
+	for i, tex := range rt.Textures {
+		c.FramebufferTexture2D(FRAMEBUFFER, COLOR_ATTACHMENT0_WEBGL+i, TEXTURE_2D, tex, 0)
+	}
+	if len(rt.Textures) > 1 {
+		// Which attachments are drawn to is part of the framebuffer state.
+		d, _ := c.DrawBuffers()
+		buffers := make([]int, len(rt.Textures))
+		for i := range buffers {
+			buffers[i] = COLOR_ATTACHMENT0_WEBGL + i
+		}
+		d.DrawBuffers(buffers)
+	}
+	if rt.Renderbuffer != nil {
+		c.FrameBufferRenderBuffer(FRAMEBUFFER, rt.depthStencilAttachment(), RENDERBUFFER, rt.Renderbuffer)
+	}
+	if rt.DepthTexture != nil {
+		c.FramebufferTexture2D(FRAMEBUFFER, rt.depthStencilAttachment(), TEXTURE_2D, rt.DepthTexture, 0)
+	}
+	err := c.CheckFramebuffer(FRAMEBUFFER)
+	c.BindFramebuffer(FRAMEBUFFER, nil)
+	if err != nil {
+		rt.Delete()
+		return nil, err
+	}
+	return rt, nil
+}
+
+// Returns the internal format of the depth and/or stencil renderbuffer.
+func (rt *RenderTarget) depthStencilFormat() int {
+	switch {
+	case rt.Format.Depth && rt.Format.Stencil:
+		return DEPTH_STENCIL
+	case rt.Format.Stencil:
+		return STENCIL_INDEX8
+	}
+	return DEPTH_COMPONENT16
+}
+
+// Returns the attachment point of the depth and/or stencil renderbuffer.
+func (rt *RenderTarget) depthStencilAttachment() int {
+	depth := rt.Format.Depth || rt.Format.DepthTexture
+	switch {
+	case depth && rt.Format.Stencil:
+		return DEPTH_STENCIL_ATTACHMENT
+	case rt.Format.Stencil:
+		return STENCIL_ATTACHMENT
+	}
+	return DEPTH_ATTACHMENT
+}
+
+// Allocates storage for the attachments at the current size. This leaves
+// the last texture and the renderbuffer bound.
+func (rt *RenderTarget) allocate() {
+	c := rt.gl
+	for _, tex := range rt.Textures {
+		c.BindTexture(TEXTURE_2D, tex)
+		c.TexImage2DPixels(TEXTURE_2D, 0, rt.Format.ColorFormat, rt.Width, rt.Height, 0, rt.Format.ColorFormat, rt.Format.ColorType, nil)
+	}
+	if rt.Renderbuffer != nil {
+		c.BindRenderbuffer(RENDERBUFFER, rt.Renderbuffer)
+		c.RenderbufferStorage(RENDERBUFFER, rt.depthStencilFormat(), rt.Width, rt.Height)
+	}
+	if rt.DepthTexture != nil {
+		format, typ := DEPTH_COMPONENT, UNSIGNED_INT
+		if rt.Format.Stencil {
+			format, typ = DEPTH_STENCIL, UNSIGNED_INT_24_8_WEBGL
+		}
+		c.BindTexture(TEXTURE_2D, rt.DepthTexture)
+		c.TexImage2DPixels(TEXTURE_2D, 0, format, rt.Width, rt.Height, 0, format, typ, nil)
+	}
+}
+
+// Resize reallocates the attachments at a new size. Their contents are
+// lost. Nothing is done if the size has not changed.
+func (rt *RenderTarget) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return errors.New("render target size must be positive")
+	}
+	if width == rt.Width && height == rt.Height {
+		return nil
+	}
+	rt.Width, rt.Height = width, height
+	rt.allocate()
+	rt.gl.BindFramebuffer(FRAMEBUFFER, rt.Framebuffer)
+	err := rt.gl.CheckFramebuffer(FRAMEBUFFER)
+	rt.gl.BindFramebuffer(FRAMEBUFFER, nil)
+	return err
+}
+
+// Bind makes the render target the destination of drawing operations and
+// sets the viewport to cover it.
+func (rt *RenderTarget) Bind() {
+	rt.gl.BindFramebuffer(FRAMEBUFFER, rt.Framebuffer)
+	rt.gl.Viewport(0, 0, rt.Width, rt.Height)
+}
+
+// Unbind makes the default framebuffer the destination of drawing
+// operations again. The viewport is left for the caller to restore.
+func (rt *RenderTarget) Unbind() {
+	rt.gl.BindFramebuffer(FRAMEBUFFER, nil)
+}
+
+// Delete deletes the framebuffer and its attachments.
+func (rt *RenderTarget) Delete() {
+	c := rt.gl
+	if rt.Framebuffer != nil {
+		c.DeleteFramebuffer(rt.Framebuffer)
+		rt.Framebuffer = nil
+	}
+	for _, tex := range rt.Textures {
+		c.DeleteTexture(tex)
+	}
+	rt.Texture, rt.Textures = nil, nil
+	if rt.Renderbuffer != nil {
+		c.DeleteRenderbuffer(rt.Renderbuffer)
+		rt.Renderbuffer = nil
+	}
+	if rt.DepthTexture != nil {
+		c.DeleteTexture(rt.DepthTexture)
+		rt.DepthTexture = nil
+	}
+}
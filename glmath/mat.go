@@ -0,0 +1,291 @@
+package glmath
+
+import (
+	"math"
+)
+
+// Mat2 is a column major 2x2 matrix.
+type Mat2 [4]float32
+
+// Mat3 is a column major 3x3 matrix.
+type Mat3 [9]float32
+
+// Mat4 is a column major 4x4 matrix.
+type Mat4 [16]float32
+
+// Returns the 2x2 identity matrix.
+func Ident2() Mat2 {
+	return Mat2{1, 0, 0, 1}
+}
+
+// Returns the 3x3 identity matrix.
+func Ident3() Mat3 {
+	return Mat3{1, 0, 0, 0, 1, 0, 0, 0, 1}
+}
+
+// Returns the 4x4 identity matrix.
+func Ident4() Mat4 {
+	return Mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1}
+}
+
+// Returns the element at row r and column c.
+func (m *Mat2) At(r, c int) float32 {
+	return m[c*2+r]
+}
+
+// Returns the product m*n.
+func (m *Mat2) Mul(n *Mat2) Mat2 {
+	return Mat2{
+		m[0]*n[0] + m[2]*n[1], m[1]*n[0] + m[3]*n[1],
+		m[0]*n[2] + m[2]*n[3], m[1]*n[2] + m[3]*n[3],
+	}
+}
+
+// Returns the product m*v.
+func (m *Mat2) MulVec(v Vec2) Vec2 {
+	return Vec2{m[0]*v[0] + m[2]*v[1], m[1]*v[0] + m[3]*v[1]}
+}
+
+// Returns the transpose of m.
+func (m *Mat2) Transpose() Mat2 {
+	return Mat2{m[0], m[2], m[1], m[3]}
+}
+
+// Returns the determinant of m.
+func (m *Mat2) Det() float32 {
+	return m[0]*m[3] - m[2]*m[1]
+}
+
+// Returns the inverse of m, or false if m is not invertible.
+func (m *Mat2) Inverse() (Mat2, bool) {
+	det := m.Det()
+	if det == 0 {
+		return Mat2{}, false
+	}
+	inv := 1 / det
+	return Mat2{m[3] * inv, -m[1] * inv, -m[2] * inv, m[0] * inv}, true
+}
+
+// Returns the element at row r and column c.
+func (m *Mat3) At(r, c int) float32 {
+	return m[c*3+r]
+}
+
+// Returns the product m*n.
+func (m *Mat3) Mul(n *Mat3) Mat3 {
+	var out Mat3
+	for c := 0; c < 3; c++ {
+		for r := 0; r < 3; r++ {
+			out[c*3+r] = m[r]*n[c*3] + m[3+r]*n[c*3+1] + m[6+r]*n[c*3+2]
+		}
+	}
+	return out
+}
+
+// Returns the product m*v.
+func (m *Mat3) MulVec(v Vec3) Vec3 {
+	return Vec3{
+		m[0]*v[0] + m[3]*v[1] + m[6]*v[2],
+		m[1]*v[0] + m[4]*v[1] + m[7]*v[2],
+		m[2]*v[0] + m[5]*v[1] + m[8]*v[2],
+	}
+}
+
+// Returns the transpose of m.
+func (m *Mat3) Transpose() Mat3 {
+	return Mat3{
+		m[0], m[3], m[6],
+		m[1], m[4], m[7],
+		m[2], m[5], m[8],
+	}
+}
+
+// Returns the determinant of m.
+func (m *Mat3) Det() float32 {
+	return m[0]*(m[4]*m[8]-m[7]*m[5]) -
+		m[3]*(m[1]*m[8]-m[7]*m[2]) +
+		m[6]*(m[1]*m[5]-m[4]*m[2])
+}
+
+// Returns the inverse of m, or false if m is not invertible.
+func (m *Mat3) Inverse() (Mat3, bool) {
+	det := m.Det()
+	if det == 0 {
+		return Mat3{}, false
+	}
+	inv := 1 / det
+	return Mat3{
+		(m[4]*m[8] - m[7]*m[5]) * inv,
+		(m[7]*m[2] - m[1]*m[8]) * inv,
+		(m[1]*m[5] - m[4]*m[2]) * inv,
+		(m[6]*m[5] - m[3]*m[8]) * inv,
+		(m[0]*m[8] - m[6]*m[2]) * inv,
+		(m[3]*m[2] - m[0]*m[5]) * inv,
+		(m[3]*m[7] - m[6]*m[4]) * inv,
+		(m[6]*m[1] - m[0]*m[7]) * inv,
+		(m[0]*m[4] - m[3]*m[1]) * inv,
+	}, true
+}
+
+// Returns the element at row r and column c.
+func (m *Mat4) At(r, c int) float32 {
+	return m[c*4+r]
+}
+
+// Returns the product m*n, which applies n first and then m.
+func (m *Mat4) Mul(n *Mat4) Mat4 {
+	var out Mat4
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			out[c*4+r] = m[r]*n[c*4] + m[4+r]*n[c*4+1] + m[8+r]*n[c*4+2] + m[12+r]*n[c*4+3]
+		}
+	}
+	return out
+}
+
+// Returns the product m*v.
+func (m *Mat4) MulVec(v Vec4) Vec4 {
+	return Vec4{
+		m[0]*v[0] + m[4]*v[1] + m[8]*v[2] + m[12]*v[3],
+		m[1]*v[0] + m[5]*v[1] + m[9]*v[2] + m[13]*v[3],
+		m[2]*v[0] + m[6]*v[1] + m[10]*v[2] + m[14]*v[3],
+		m[3]*v[0] + m[7]*v[1] + m[11]*v[2] + m[15]*v[3],
+	}
+}
+
+// Returns the point p transformed by m, divided by the resulting w.
+func (m *Mat4) MulPoint(p Vec3) Vec3 {
+	v := m.MulVec(p.Vec4(1))
+	if v[3] != 0 && v[3] != 1 {
+		return v.Vec3().Mul(1 / v[3])
+	}
+	return v.Vec3()
+}
+
+// Returns the direction d transformed by m, ignoring translation.
+func (m *Mat4) MulDir(d Vec3) Vec3 {
+	return m.MulVec(d.Vec4(0)).Vec3()
+}
+
+// Returns the transpose of m.
+func (m *Mat4) Transpose() Mat4 {
+	return Mat4{
+		m[0], m[4], m[8], m[12],
+		m[1], m[5], m[9], m[13],
+		m[2], m[6], m[10], m[14],
+		m[3], m[7], m[11], m[15],
+	}
+}
+
+// Returns the upper left 3x3 part of m.
+func (m *Mat4) Mat3() Mat3 {
+	return Mat3{
+		m[0], m[1], m[2],
+		m[4], m[5], m[6],
+		m[8], m[9], m[10],
+	}
+}
+
+// Returns the inverse of m, or false if m is not invertible.
+func (m *Mat4) Inverse() (Mat4, bool) {
+	var inv Mat4
+	inv[0] = m[5]*m[10]*m[15] - m[5]*m[11]*m[14] - m[9]*m[6]*m[15] + m[9]*m[7]*m[14] + m[13]*m[6]*m[11] - m[13]*m[7]*m[10]
+	inv[4] = -m[4]*m[10]*m[15] + m[4]*m[11]*m[14] + m[8]*m[6]*m[15] - m[8]*m[7]*m[14] - m[12]*m[6]*m[11] + m[12]*m[7]*m[10]
+	inv[8] = m[4]*m[9]*m[15] - m[4]*m[11]*m[13] - m[8]*m[5]*m[15] + m[8]*m[7]*m[13] + m[12]*m[5]*m[11] - m[12]*m[7]*m[9]
+	inv[12] = -m[4]*m[9]*m[14] + m[4]*m[10]*m[13] + m[8]*m[5]*m[14] - m[8]*m[6]*m[13] - m[12]*m[5]*m[10] + m[12]*m[6]*m[9]
+	inv[1] = -m[1]*m[10]*m[15] + m[1]*m[11]*m[14] + m[9]*m[2]*m[15] - m[9]*m[3]*m[14] - m[13]*m[2]*m[11] + m[13]*m[3]*m[10]
+	inv[5] = m[0]*m[10]*m[15] - m[0]*m[11]*m[14] - m[8]*m[2]*m[15] + m[8]*m[3]*m[14] + m[12]*m[2]*m[11] - m[12]*m[3]*m[10]
+	inv[9] = -m[0]*m[9]*m[15] + m[0]*m[11]*m[13] + m[8]*m[1]*m[15] - m[8]*m[3]*m[13] - m[12]*m[1]*m[11] + m[12]*m[3]*m[9]
+	inv[13] = m[0]*m[9]*m[14] - m[0]*m[10]*m[13] - m[8]*m[1]*m[14] + m[8]*m[2]*m[13] + m[12]*m[1]*m[10] - m[12]*m[2]*m[9]
+	inv[2] = m[1]*m[6]*m[15] - m[1]*m[7]*m[14] - m[5]*m[2]*m[15] + m[5]*m[3]*m[14] + m[13]*m[2]*m[7] - m[13]*m[3]*m[6]
+	inv[6] = -m[0]*m[6]*m[15] + m[0]*m[7]*m[14] + m[4]*m[2]*m[15] - m[4]*m[3]*m[14] - m[12]*m[2]*m[7] + m[12]*m[3]*m[6]
+	inv[10] = m[0]*m[5]*m[15] - m[0]*m[7]*m[13] - m[4]*m[1]*m[15] + m[4]*m[3]*m[13] + m[12]*m[1]*m[7] - m[12]*m[3]*m[5]
+	inv[14] = -m[0]*m[5]*m[14] + m[0]*m[6]*m[13] + m[4]*m[1]*m[14] - m[4]*m[2]*m[13] - m[12]*m[1]*m[6] + m[12]*m[2]*m[5]
+	inv[3] = -m[1]*m[6]*m[11] + m[1]*m[7]*m[10] + m[5]*m[2]*m[11] - m[5]*m[3]*m[10] - m[9]*m[2]*m[7] + m[9]*m[3]*m[6]
+	inv[7] = m[0]*m[6]*m[11] - m[0]*m[7]*m[10] - m[4]*m[2]*m[11] + m[4]*m[3]*m[10] + m[8]*m[2]*m[7] - m[8]*m[3]*m[6]
+	inv[11] = -m[0]*m[5]*m[11] + m[0]*m[7]*m[9] + m[4]*m[1]*m[11] - m[4]*m[3]*m[9] - m[8]*m[1]*m[7] + m[8]*m[3]*m[5]
+	inv[15] = m[0]*m[5]*m[10] - m[0]*m[6]*m[9] - m[4]*m[1]*m[10] + m[4]*m[2]*m[9] + m[8]*m[1]*m[6] - m[8]*m[2]*m[5]
+
+	det := m[0]*inv[0] + m[1]*inv[4] + m[2]*inv[8] + m[3]*inv[12]
+	if det == 0 {
+		return Mat4{}, false
+	}
+	det = 1 / det
+	for i := range inv {
+		inv[i] *= det
+	}
+	return inv, true
+}
+
+// Returns the matrix that transforms normals the way m transforms
+// positions: the inverse transpose of its upper left 3x3 part. If that
+// part is not invertible, it is returned as it is.
+func (m *Mat4) NormalMatrix() Mat3 {
+	m3 := m.Mat3()
+	inv, ok := m3.Inverse()
+	if !ok {
+		return m3
+	}
+	return inv.Transpose()
+}
+
+// Returns a matrix that translates by v.
+func Translate(v Vec3) Mat4 {
+	return Mat4{1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, v[0], v[1], v[2], 1}
+}
+
+// Returns a matrix that scales by v.
+func Scale(v Vec3) Mat4 {
+	return Mat4{v[0], 0, 0, 0, 0, v[1], 0, 0, 0, 0, v[2], 0, 0, 0, 0, 1}
+}
+
+// Returns a matrix that rotates by angle radians around axis.
+func Rotate(angle float32, axis Vec3) Mat4 {
+	return QuatAxisAngle(axis, angle).Mat4()
+}
+
+// Returns a perspective projection with a vertical field of view of fovy
+// radians, mapping depths from near to far to the [-1, 1] clip range.
+// If far is +Inf, the projection has no far plane.
+func Perspective(fovy, aspect, near, far float32) Mat4 {
+	f := float32(1 / math.Tan(float64(fovy)/2))
+	m := Mat4{
+		f / aspect, 0, 0, 0,
+		0, f, 0, 0,
+		0, 0, -1, -1,
+		0, 0, -2 * near, 0,
+	}
+	if !math.IsInf(float64(far), 1) {
+		nf := 1 / (near - far)
+		m[10] = (far + near) * nf
+		m[14] = 2 * far * near * nf
+	}
+	return m
+}
+
+// Returns an orthographic projection of the given box to clip space.
+func Ortho(left, right, bottom, top, near, far float32) Mat4 {
+	rl := 1 / (right - left)
+	tb := 1 / (top - bottom)
+	fn := 1 / (far - near)
+	return Mat4{
+		2 * rl, 0, 0, 0,
+		0, 2 * tb, 0, 0,
+		0, 0, -2 * fn, 0,
+		-(right + left) * rl, -(top + bottom) * tb, -(far + near) * fn, 1,
+	}
+}
+
+// Returns a view matrix for a camera at eye looking at center, with up
+// pointing roughly upwards.
+func LookAt(eye, center, up Vec3) Mat4 {
+	f := center.Sub(eye).Normalize()
+	s := f.Cross(up).Normalize()
+	u := s.Cross(f)
+	return Mat4{
+		s[0], u[0], -f[0], 0,
+		s[1], u[1], -f[1], 0,
+		s[2], u[2], -f[2], 0,
+		-s.Dot(eye), -u.Dot(eye), f.Dot(eye), 1,
+	}
+}
@@ -0,0 +1,232 @@
+package glmath
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, eps float32) bool {
+	return math.Abs(float64(a-b)) <= float64(eps)
+}
+
+func nearVec3(a, b Vec3, eps float32) bool {
+	for i := range a {
+		if !near(a[i], b[i], eps) {
+			return false
+		}
+	}
+	return true
+}
+
+func nearMat4(a, b Mat4, eps float32) bool {
+	for i := range a {
+		if !near(a[i], b[i], eps) {
+			return false
+		}
+	}
+	return true
+}
+
+// testMatrices returns invertible matrices of the kinds this package
+// builds, and a general one.
+func testMatrices() map[string]Mat4 {
+	rot := Rotate(0.7, Vec3{1, 2, 3})
+	trs := TRS(Vec3{4, -5, 6}, QuatAxisAngle(Vec3{0, 1, 1}, 2), Vec3{2, 0.5, 3})
+	return map[string]Mat4{
+		"identity":    Ident4(),
+		"translate":   Translate(Vec3{1, -2, 3}),
+		"scale":       Scale(Vec3{2, 3, -4}),
+		"rotate":      rot,
+		"TRS":         trs,
+		"look at":     LookAt(Vec3{3, 4, 5}, Vec3{0, 1, 0}, Vec3{0, 1, 0}),
+		"perspective": Perspective(1, 1.5, 0.1, 100),
+		"ortho":       Ortho(-2, 3, -1, 4, 0.5, 20),
+		"general": {
+			2, 1, 0, 0.5,
+			-1, 3, 1, 0,
+			0, 2, 4, 1,
+			1, 0, -2, 5,
+		},
+	}
+}
+
+func TestMat4Inverse(t *testing.T) {
+	ident := Ident4()
+	for name, m := range testMatrices() {
+		inv, ok := m.Inverse()
+		if !ok {
+			t.Errorf("%s: Inverse failed", name)
+			continue
+		}
+		if got := m.Mul(&inv); !nearMat4(got, ident, 1e-4) {
+			t.Errorf("%s: M*Inverse(M) = %v; want the identity", name, got)
+		}
+		if got := inv.Mul(&m); !nearMat4(got, ident, 1e-4) {
+			t.Errorf("%s: Inverse(M)*M = %v; want the identity", name, got)
+		}
+	}
+	singular := Mat4{1, 2, 3, 4, 2, 4, 6, 8, 0, 0, 1, 0, 0, 0, 0, 1}
+	if _, ok := singular.Inverse(); ok {
+		t.Error("Inverse of a singular matrix succeeded")
+	}
+}
+
+func TestMat3Inverse(t *testing.T) {
+	ident := Ident3()
+	for name, m4 := range testMatrices() {
+		m := m4.Mat3()
+		if name == "perspective" || name == "ortho" {
+			continue
+		}
+		inv, ok := m.Inverse()
+		if !ok {
+			t.Errorf("%s: Inverse failed", name)
+			continue
+		}
+		got := m.Mul(&inv)
+		for i := range got {
+			if !near(got[i], ident[i], 1e-4) {
+				t.Errorf("%s: M*Inverse(M) = %v; want the identity", name, got)
+				break
+			}
+		}
+	}
+	if _, ok := (&Mat3{1, 2, 3, 2, 4, 6, 0, 0, 1}).Inverse(); ok {
+		t.Error("Inverse of a singular matrix succeeded")
+	}
+}
+
+func TestMat2Inverse(t *testing.T) {
+	m := Mat2{3, 1, -2, 4}
+	inv, ok := m.Inverse()
+	if !ok {
+		t.Fatal("Inverse failed")
+	}
+	if got := m.Mul(&inv); !near(got[0], 1, 1e-6) || !near(got[1], 0, 1e-6) || !near(got[2], 0, 1e-6) || !near(got[3], 1, 1e-6) {
+		t.Errorf("M*Inverse(M) = %v; want the identity", got)
+	}
+	if _, ok := (&Mat2{1, 2, 2, 4}).Inverse(); ok {
+		t.Error("Inverse of a singular matrix succeeded")
+	}
+}
+
+func TestMat4Mul(t *testing.T) {
+	// Translate*Scale scales first.
+	tr, sc := Translate(Vec3{1, 2, 3}), Scale(Vec3{2, 2, 2})
+	m := tr.Mul(&sc)
+	if got, want := m.MulPoint(Vec3{1, 1, 1}), (Vec3{3, 4, 5}); got != want {
+		t.Errorf("Translate*Scale moves (1, 1, 1) to %v; want %v", got, want)
+	}
+	if got, want := m.MulDir(Vec3{1, 0, 0}), (Vec3{2, 0, 0}); got != want {
+		t.Errorf("Translate*Scale turns direction x into %v; want %v", got, want)
+	}
+	if got := m.At(0, 3); got != 1 {
+		t.Errorf("At(0, 3) = %g; want the x translation 1", got)
+	}
+	if tt := m.Transpose(); tt.At(3, 0) != 1 {
+		t.Errorf("transpose has %g at row 3, column 0; want 1", tt.At(3, 0))
+	}
+}
+
+func TestNormalMatrix(t *testing.T) {
+	m := Scale(Vec3{2, 1, 1})
+	n := m.NormalMatrix()
+	// The normal of the plane x = y keeps being perpendicular to it.
+	normal := n.MulVec(Vec3{1, -1, 0}).Normalize()
+	along := m.MulDir(Vec3{1, 1, 0})
+	if d := normal.Dot(along); !near(d, 0, 1e-6) {
+		t.Errorf("transformed normal %v is not perpendicular to %v", normal, along)
+	}
+}
+
+func TestLookAt(t *testing.T) {
+	eye, center := Vec3{1, 2, 3}, Vec3{1, 2, -5}
+	m := LookAt(eye, center, Vec3{0, 1, 0})
+	want := Mat4{
+		1, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, 1, 0,
+		-1, -2, -3, 1,
+	}
+	if !nearMat4(m, want, 1e-6) {
+		t.Errorf("LookAt down -z = %v; want %v", m, want)
+	}
+
+	eye, center = Vec3{3, 4, 5}, Vec3{-1, 0, 2}
+	up := Vec3{0, 1, 0}
+	m = LookAt(eye, center, up)
+	if got := m.MulPoint(eye); !nearVec3(got, Vec3{}, 1e-5) {
+		t.Errorf("eye maps to %v; want the origin", got)
+	}
+	dist := center.Sub(eye).Len()
+	if got := m.MulPoint(center); !nearVec3(got, Vec3{0, 0, -dist}, 1e-5) {
+		t.Errorf("center maps to %v; want (0, 0, %g)", got, -dist)
+	}
+	// The rows of the rotation are an orthonormal, right handed basis.
+	s := Vec3{m[0], m[4], m[8]}
+	u := Vec3{m[1], m[5], m[9]}
+	b := Vec3{m[2], m[6], m[10]}
+	for _, v := range []Vec3{s, u, b} {
+		if !near(v.Len(), 1, 1e-6) {
+			t.Errorf("basis vector %v does not have length 1", v)
+		}
+	}
+	if !near(s.Dot(u), 0, 1e-6) || !near(s.Dot(b), 0, 1e-6) || !near(u.Dot(b), 0, 1e-6) {
+		t.Errorf("basis %v, %v, %v is not orthogonal", s, u, b)
+	}
+	if !nearVec3(s.Cross(u), b, 1e-6) {
+		t.Errorf("basis %v, %v, %v is not right handed", s, u, b)
+	}
+	if u.Dot(up) <= 0 {
+		t.Errorf("view up %v points away from %v", u, up)
+	}
+}
+
+func TestPerspective(t *testing.T) {
+	m := Perspective(math.Pi/2, 2, 1, 3)
+	want := Mat4{
+		0.5, 0, 0, 0,
+		0, 1, 0, 0,
+		0, 0, -2, -1,
+		0, 0, -3, 0,
+	}
+	if !nearMat4(m, want, 1e-6) {
+		t.Errorf("Perspective = %v; want %v", m, want)
+	}
+	if got := m.MulPoint(Vec3{0, 0, -1}); !near(got[2], -1, 1e-6) {
+		t.Errorf("near plane maps to depth %g; want -1", got[2])
+	}
+	if got := m.MulPoint(Vec3{0, 0, -3}); !near(got[2], 1, 1e-6) {
+		t.Errorf("far plane maps to depth %g; want 1", got[2])
+	}
+	if got := m.MulPoint(Vec3{2, 1, -1}); !nearVec3(got, Vec3{1, 1, -1}, 1e-6) {
+		t.Errorf("top right of the near plane maps to %v; want (1, 1, -1)", got)
+	}
+
+	inf := Perspective(math.Pi/2, 1, 0.5, float32(math.Inf(1)))
+	if inf[10] != -1 || inf[14] != -1 {
+		t.Errorf("Perspective without far plane has %g, %g; want -1, -1", inf[10], inf[14])
+	}
+	if got := inf.MulPoint(Vec3{0, 0, -1e6}); !near(got[2], 1, 1e-5) {
+		t.Errorf("far away point maps to depth %g; want close to 1", got[2])
+	}
+}
+
+func TestOrtho(t *testing.T) {
+	m := Ortho(-1, 3, -2, 2, 1, 5)
+	want := Mat4{
+		0.5, 0, 0, 0,
+		0, 0.5, 0, 0,
+		0, 0, -0.5, 0,
+		-0.5, 0, -1.5, 1,
+	}
+	if !nearMat4(m, want, 1e-6) {
+		t.Errorf("Ortho = %v; want %v", m, want)
+	}
+	if got := m.MulPoint(Vec3{-1, -2, -1}); !nearVec3(got, Vec3{-1, -1, -1}, 1e-6) {
+		t.Errorf("near bottom left maps to %v; want (-1, -1, -1)", got)
+	}
+	if got := m.MulPoint(Vec3{3, 2, -5}); !nearVec3(got, Vec3{1, 1, 1}, 1e-6) {
+		t.Errorf("far top right maps to %v; want (1, 1, 1)", got)
+	}
+}
@@ -0,0 +1,92 @@
+package glmath
+
+import (
+	"math"
+)
+
+// Quat is a quaternion with the vector part first: x, y, z, w.
+type Quat [4]float32
+
+// Returns the identity rotation.
+func QuatIdent() Quat {
+	return Quat{0, 0, 0, 1}
+}
+
+// Returns the rotation by angle radians around axis.
+func QuatAxisAngle(axis Vec3, angle float32) Quat {
+	s, c := math.Sincos(float64(angle) / 2)
+	a := axis.Normalize().Mul(float32(s))
+	return Quat{a[0], a[1], a[2], float32(c)}
+}
+
+// Returns the product q*p, which rotates by p first and then by q.
+func (q Quat) Mul(p Quat) Quat {
+	return Quat{
+		q[3]*p[0] + q[0]*p[3] + q[1]*p[2] - q[2]*p[1],
+		q[3]*p[1] - q[0]*p[2] + q[1]*p[3] + q[2]*p[0],
+		q[3]*p[2] + q[0]*p[1] - q[1]*p[0] + q[2]*p[3],
+		q[3]*p[3] - q[0]*p[0] - q[1]*p[1] - q[2]*p[2],
+	}
+}
+
+// Returns the conjugate of q, which is its inverse if q has length 1.
+func (q Quat) Conjugate() Quat {
+	return Quat{-q[0], -q[1], -q[2], q[3]}
+}
+
+// Returns q scaled to length 1, or the identity if it has length 0.
+func (q Quat) Normalize() Quat {
+	l := Vec4(q).Len()
+	if l == 0 {
+		return QuatIdent()
+	}
+	return Quat(Vec4(q).Mul(1 / l))
+}
+
+// Returns v rotated by q, which must have length 1.
+func (q Quat) Rotate(v Vec3) Vec3 {
+	u := Vec3{q[0], q[1], q[2]}
+	t := u.Cross(v).Mul(2)
+	return v.Add(t.Mul(q[3])).Add(u.Cross(t))
+}
+
+// Returns the rotation matrix of q, which must have length 1.
+func (q Quat) Mat4() Mat4 {
+	x, y, z, w := q[0], q[1], q[2], q[3]
+	return Mat4{
+		1 - 2*(y*y+z*z), 2 * (x*y + z*w), 2 * (x*z - y*w), 0,
+		2 * (x*y - z*w), 1 - 2*(x*x+z*z), 2 * (y*z + x*w), 0,
+		2 * (x*z + y*w), 2 * (y*z - x*w), 1 - 2*(x*x+y*y), 0,
+		0, 0, 0, 1,
+	}
+}
+
+// Returns the spherical linear interpolation from q to p by t, taking the
+// shorter way around.
+func (q Quat) Slerp(p Quat, t float32) Quat {
+	cos := Vec4(q).Dot(Vec4(p))
+	if cos < 0 {
+		p, cos = Quat(Vec4(p).Mul(-1)), -cos
+	}
+	if cos > 0.9995 {
+		return Quat(Vec4(q).Lerp(Vec4(p), t)).Normalize()
+	}
+	theta := math.Acos(float64(cos))
+	sin := math.Sin(theta)
+	a := float32(math.Sin((1-float64(t))*theta) / sin)
+	b := float32(math.Sin(float64(t)*theta) / sin)
+	return Quat(Vec4(q).Mul(a).Add(Vec4(p).Mul(b)))
+}
+
+// Returns the composed transform that scales by s, then rotates by r and
+// then translates by t.
+func TRS(t Vec3, r Quat, s Vec3) Mat4 {
+	m := r.Mat4()
+	for c := 0; c < 3; c++ {
+		for i := 0; i < 3; i++ {
+			m[c*4+i] *= s[c]
+		}
+	}
+	m[12], m[13], m[14] = t[0], t[1], t[2]
+	return m
+}
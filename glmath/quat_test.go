@@ -0,0 +1,150 @@
+package glmath
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func nearQuat(a, b Quat, eps float32) bool {
+	return nearVec3(Vec3{a[0], a[1], a[2]}, Vec3{b[0], b[1], b[2]}, eps) && near(a[3], b[3], eps)
+}
+
+// Returns the quaternion of a rotation matrix, with w >= 0.
+func quatFromMat(m Mat4) Quat {
+	var q Quat
+	trace := m[0] + m[5] + m[10]
+	switch {
+	case trace > 0:
+		s := sqrt(trace+1) * 2
+		q = Quat{(m[6] - m[9]) / s, (m[8] - m[2]) / s, (m[1] - m[4]) / s, s / 4}
+	case m[0] > m[5] && m[0] > m[10]:
+		s := sqrt(1+m[0]-m[5]-m[10]) * 2
+		q = Quat{s / 4, (m[4] + m[1]) / s, (m[8] + m[2]) / s, (m[6] - m[9]) / s}
+	case m[5] > m[10]:
+		s := sqrt(1+m[5]-m[0]-m[10]) * 2
+		q = Quat{(m[4] + m[1]) / s, s / 4, (m[9] + m[6]) / s, (m[8] - m[2]) / s}
+	default:
+		s := sqrt(1+m[10]-m[0]-m[5]) * 2
+		q = Quat{(m[8] + m[2]) / s, (m[9] + m[6]) / s, s / 4, (m[1] - m[4]) / s}
+	}
+	if q[3] < 0 {
+		q = Quat(Vec4(q).Mul(-1))
+	}
+	return q
+}
+
+func randomQuat(rng *rand.Rand) Quat {
+	return Quat{rng.Float32()*2 - 1, rng.Float32()*2 - 1, rng.Float32()*2 - 1, rng.Float32()*2 - 1}.Normalize()
+}
+
+func TestQuatAxisAngle(t *testing.T) {
+	tests := []struct {
+		axis    Vec3
+		angle   float32
+		v, want Vec3
+	}{
+		{Vec3{0, 1, 0}, math.Pi / 2, Vec3{1, 0, 0}, Vec3{0, 0, -1}},
+		{Vec3{0, 0, 1}, math.Pi / 2, Vec3{1, 0, 0}, Vec3{0, 1, 0}},
+		{Vec3{1, 0, 0}, math.Pi / 2, Vec3{0, 1, 0}, Vec3{0, 0, 1}},
+		{Vec3{0, 0, 5}, math.Pi, Vec3{1, 2, 3}, Vec3{-1, -2, 3}},
+		{Vec3{1, 1, 1}, 2 * math.Pi / 3, Vec3{1, 0, 0}, Vec3{0, 1, 0}},
+		{Vec3{1, 0, 0}, 0, Vec3{1, 2, 3}, Vec3{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		q := QuatAxisAngle(tt.axis, tt.angle)
+		if !near(Vec4(q).Len(), 1, 1e-6) {
+			t.Errorf("QuatAxisAngle(%v, %g) = %v does not have length 1", tt.axis, tt.angle, q)
+		}
+		if got := q.Rotate(tt.v); !nearVec3(got, tt.want, 1e-6) {
+			t.Errorf("rotating %v by %g around %v gives %v; want %v", tt.v, tt.angle, tt.axis, got, tt.want)
+		}
+		m := q.Mat4()
+		if got := m.MulDir(tt.v); !nearVec3(got, tt.want, 1e-6) {
+			t.Errorf("rotation matrix turns %v by %g around %v into %v; want %v", tt.v, tt.angle, tt.axis, got, tt.want)
+		}
+		if r := Rotate(tt.angle, tt.axis); r != m {
+			t.Errorf("Rotate(%g, %v) = %v; want %v", tt.angle, tt.axis, r, m)
+		}
+	}
+}
+
+func TestQuatMatRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		q := randomQuat(rng)
+		m := q.Mat4()
+
+		want := q
+		if want[3] < 0 {
+			want = Quat(Vec4(want).Mul(-1))
+		}
+		if got := quatFromMat(m); !nearQuat(got, want, 1e-5) {
+			t.Errorf("quaternion of %v's matrix is %v", q, got)
+		}
+
+		// The matrix is a rotation: orthonormal with determinant 1.
+		mt := m.Transpose()
+		if got := m.Mul(&mt); !nearMat4(got, Ident4(), 1e-5) {
+			t.Errorf("matrix of %v is not orthonormal: M*Mt = %v", q, got)
+		}
+		if m3 := m.Mat3(); !near(m3.Det(), 1, 1e-5) {
+			t.Errorf("matrix of %v has determinant %g; want 1", q, m3.Det())
+		}
+
+		v := Vec3{rng.Float32(), rng.Float32(), rng.Float32()}
+		if a, b := q.Rotate(v), m.MulDir(v); !nearVec3(a, b, 1e-5) {
+			t.Errorf("%v rotates %v to %v, but its matrix to %v", q, v, a, b)
+		}
+
+		p := randomQuat(rng)
+		qp, mp := q.Mul(p).Mat4(), p.Mat4()
+		if want := m.Mul(&mp); !nearMat4(qp, want, 1e-5) {
+			t.Errorf("matrix of %v*%v = %v; want %v", q, p, qp, want)
+		}
+		if got := q.Mul(q.Conjugate()); !nearQuat(got, QuatIdent(), 1e-6) {
+			t.Errorf("%v times its conjugate = %v; want the identity", q, got)
+		}
+	}
+	if got := (Quat{}).Normalize(); got != QuatIdent() {
+		t.Errorf("normalizing a zero quaternion gives %v; want the identity", got)
+	}
+}
+
+func TestQuatSlerp(t *testing.T) {
+	y := Vec3{0, 1, 0}
+	a, b := QuatIdent(), QuatAxisAngle(y, math.Pi/2)
+	tests := []struct {
+		p    Quat
+		t    float32
+		want Quat
+	}{
+		{b, 0, a},
+		{b, 1, b},
+		{b, 0.5, QuatAxisAngle(y, math.Pi/4)},
+		{b, 0.25, QuatAxisAngle(y, math.Pi/8)},
+		// The same rotation with the opposite sign is taken the short way.
+		{Quat(Vec4(b).Mul(-1)), 0.5, QuatAxisAngle(y, math.Pi/4)},
+		// Rotations too close for acos are interpolated linearly.
+		{QuatAxisAngle(y, 0.01), 0.5, QuatAxisAngle(y, 0.005)},
+	}
+	for _, tt := range tests {
+		got := a.Slerp(tt.p, tt.t)
+		if !nearQuat(got, tt.want, 1e-5) {
+			t.Errorf("Slerp(%v, %v, %g) = %v; want %v", a, tt.p, tt.t, got, tt.want)
+		}
+		if !near(Vec4(got).Len(), 1, 1e-5) {
+			t.Errorf("Slerp(%v, %v, %g) = %v does not have length 1", a, tt.p, tt.t, got)
+		}
+	}
+}
+
+func TestTRS(t *testing.T) {
+	tr, r, s := Vec3{1, 2, 3}, QuatAxisAngle(Vec3{1, 1, 0}, 0.8), Vec3{2, 3, 4}
+	tm, rm, sm := Translate(tr), r.Mat4(), Scale(s)
+	rs := rm.Mul(&sm)
+	want := tm.Mul(&rs)
+	if got := TRS(tr, r, s); !nearMat4(got, want, 1e-6) {
+		t.Errorf("TRS = %v; want Translate*Rotate*Scale = %v", got, want)
+	}
+}
@@ -0,0 +1,158 @@
+// Package glmath has the vector, matrix and quaternion types needed to
+// drive WebGL shaders.
+//
+// All types are float32 arrays. Matrices are stored column major, which is
+// the layout UniformMatrix2fv, UniformMatrix3fv and UniformMatrix4fv
+// expect with transpose set to false, so m[:] can be passed to them as it
+// is. Element m[c*N+r] is at column c and row r.
+package glmath
+
+import (
+	"math"
+)
+
+// Vec2 is a 2 component vector.
+type Vec2 [2]float32
+
+// Vec3 is a 3 component vector.
+type Vec3 [3]float32
+
+// Vec4 is a 4 component vector.
+type Vec4 [4]float32
+
+func sqrt(f float32) float32 {
+	return float32(math.Sqrt(float64(f)))
+}
+
+// Returns v+w.
+func (v Vec2) Add(w Vec2) Vec2 {
+	return Vec2{v[0] + w[0], v[1] + w[1]}
+}
+
+// Returns v-w.
+func (v Vec2) Sub(w Vec2) Vec2 {
+	return Vec2{v[0] - w[0], v[1] - w[1]}
+}
+
+// Returns v scaled by s.
+func (v Vec2) Mul(s float32) Vec2 {
+	return Vec2{v[0] * s, v[1] * s}
+}
+
+// Returns the dot product of v and w.
+func (v Vec2) Dot(w Vec2) float32 {
+	return v[0]*w[0] + v[1]*w[1]
+}
+
+// Returns the length of v.
+func (v Vec2) Len() float32 {
+	return sqrt(v.Dot(v))
+}
+
+// Returns v scaled to length 1, or v if it has length 0.
+func (v Vec2) Normalize() Vec2 {
+	if l := v.Len(); l != 0 {
+		return v.Mul(1 / l)
+	}
+	return v
+}
+
+// Returns the linear interpolation from v to w by t.
+func (v Vec2) Lerp(w Vec2, t float32) Vec2 {
+	return v.Add(w.Sub(v).Mul(t))
+}
+
+// Returns v+w.
+func (v Vec3) Add(w Vec3) Vec3 {
+	return Vec3{v[0] + w[0], v[1] + w[1], v[2] + w[2]}
+}
+
+// Returns v-w.
+func (v Vec3) Sub(w Vec3) Vec3 {
+	return Vec3{v[0] - w[0], v[1] - w[1], v[2] - w[2]}
+}
+
+// Returns v scaled by s.
+func (v Vec3) Mul(s float32) Vec3 {
+	return Vec3{v[0] * s, v[1] * s, v[2] * s}
+}
+
+// Returns the dot product of v and w.
+func (v Vec3) Dot(w Vec3) float32 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2]
+}
+
+// Returns the cross product of v and w.
+func (v Vec3) Cross(w Vec3) Vec3 {
+	return Vec3{
+		v[1]*w[2] - v[2]*w[1],
+		v[2]*w[0] - v[0]*w[2],
+		v[0]*w[1] - v[1]*w[0],
+	}
+}
+
+// Returns the length of v.
+func (v Vec3) Len() float32 {
+	return sqrt(v.Dot(v))
+}
+
+// Returns v scaled to length 1, or v if it has length 0.
+func (v Vec3) Normalize() Vec3 {
+	if l := v.Len(); l != 0 {
+		return v.Mul(1 / l)
+	}
+	return v
+}
+
+// Returns the linear interpolation from v to w by t.
+func (v Vec3) Lerp(w Vec3, t float32) Vec3 {
+	return v.Add(w.Sub(v).Mul(t))
+}
+
+// Returns v extended with a w component.
+func (v Vec3) Vec4(w float32) Vec4 {
+	return Vec4{v[0], v[1], v[2], w}
+}
+
+// Returns v+w.
+func (v Vec4) Add(w Vec4) Vec4 {
+	return Vec4{v[0] + w[0], v[1] + w[1], v[2] + w[2], v[3] + w[3]}
+}
+
+// Returns v-w.
+func (v Vec4) Sub(w Vec4) Vec4 {
+	return Vec4{v[0] - w[0], v[1] - w[1], v[2] - w[2], v[3] - w[3]}
+}
+
+// Returns v scaled by s.
+func (v Vec4) Mul(s float32) Vec4 {
+	return Vec4{v[0] * s, v[1] * s, v[2] * s, v[3] * s}
+}
+
+// Returns the dot product of v and w.
+func (v Vec4) Dot(w Vec4) float32 {
+	return v[0]*w[0] + v[1]*w[1] + v[2]*w[2] + v[3]*w[3]
+}
+
+// Returns the length of v.
+func (v Vec4) Len() float32 {
+	return sqrt(v.Dot(v))
+}
+
+// Returns v scaled to length 1, or v if it has length 0.
+func (v Vec4) Normalize() Vec4 {
+	if l := v.Len(); l != 0 {
+		return v.Mul(1 / l)
+	}
+	return v
+}
+
+// Returns the linear interpolation from v to w by t.
+func (v Vec4) Lerp(w Vec4, t float32) Vec4 {
+	return v.Add(w.Sub(v).Mul(t))
+}
+
+// Returns the x, y and z components of v.
+func (v Vec4) Vec3() Vec3 {
+	return Vec3{v[0], v[1], v[2]}
+}
@@ -0,0 +1,283 @@
+package gltf
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Accessor describes how to read typed elements out of a buffer view.
+type Accessor struct {
+	Name          string    `json:"name"`
+	BufferView    *int      `json:"bufferView"`
+	ByteOffset    int       `json:"byteOffset"`
+	ComponentType int       `json:"componentType"`
+	Normalized    bool      `json:"normalized"`
+	Count         int       `json:"count"`
+	Type          string    `json:"type"`
+	Min           []float64 `json:"min"`
+	Max           []float64 `json:"max"`
+	Sparse        *Sparse   `json:"sparse"`
+}
+
+// Sparse holds the elements of an accessor that differ from its buffer
+// view, or from zero if it has none.
+type Sparse struct {
+	Count   int `json:"count"`
+	Indices struct {
+		BufferView    int `json:"bufferView"`
+		ByteOffset    int `json:"byteOffset"`
+		ComponentType int `json:"componentType"`
+	} `json:"indices"`
+	Values struct {
+		BufferView int `json:"bufferView"`
+		ByteOffset int `json:"byteOffset"`
+	} `json:"values"`
+}
+
+// Returns the number of components of each element, such as 3 for
+// "VEC3", or 0 for unknown types.
+func (a *Accessor) Components() int {
+	switch a.Type {
+	case "SCALAR":
+		return 1
+	case "VEC2":
+		return 2
+	case "VEC3":
+		return 3
+	case "VEC4", "MAT2":
+		return 4
+	case "MAT3":
+		return 9
+	case "MAT4":
+		return 16
+	}
+	return 0
+}
+
+// Returns the size in bytes of a component type, or 0 for unknown types.
+func componentSize(componentType int) int {
+	switch componentType {
+	case BYTE, UNSIGNED_BYTE:
+		return 1
+	case SHORT, UNSIGNED_SHORT:
+		return 2
+	case UNSIGNED_INT, FLOAT:
+		return 4
+	}
+	return 0
+}
+
+// layout returns how many rows and columns an element has, and how many
+// bytes a column takes up. Matrix columns are aligned to 4 bytes.
+func (a *Accessor) layout() (rows, cols, colSize int) {
+	size := componentSize(a.ComponentType)
+	switch a.Type {
+	case "MAT2":
+		rows, cols = 2, 2
+	case "MAT3":
+		rows, cols = 3, 3
+	case "MAT4":
+		rows, cols = 4, 4
+	default:
+		return a.Components(), 1, a.Components() * size
+	}
+	return rows, cols, (rows*size + 3) &^ 3
+}
+
+// Returns the component at p as a float, normalizing integers if asked.
+func readComponent(p []byte, componentType int, normalized bool) float32 {
+	le := binary.LittleEndian
+	switch componentType {
+	case BYTE:
+		v := float32(int8(p[0]))
+		if normalized {
+			return float32(math.Max(float64(v)/127, -1))
+		}
+		return v
+	case UNSIGNED_BYTE:
+		if normalized {
+			return float32(p[0]) / 255
+		}
+		return float32(p[0])
+	case SHORT:
+		v := float32(int16(le.Uint16(p)))
+		if normalized {
+			return float32(math.Max(float64(v)/32767, -1))
+		}
+		return v
+	case UNSIGNED_SHORT:
+		if normalized {
+			return float32(le.Uint16(p)) / 65535
+		}
+		return float32(le.Uint16(p))
+	case UNSIGNED_INT:
+		return float32(le.Uint32(p))
+	}
+	return math.Float32frombits(le.Uint32(p))
+}
+
+// Returns the index at p.
+func readIndex(p []byte, componentType int) uint32 {
+	switch componentType {
+	case UNSIGNED_BYTE:
+		return uint32(p[0])
+	case UNSIGNED_SHORT:
+		return uint32(binary.LittleEndian.Uint16(p))
+	}
+	return binary.LittleEndian.Uint32(p)
+}
+
+// maxZeroCount is the largest number of elements accepted for an accessor
+// without a buffer view, whose elements are zeros that no data bounds.
+const maxZeroCount = 1 << 24
+
+// elements returns the bytes the elements of an accessor are read from,
+// starting at the first element, and the distance between elements.
+func (d *Document) elements(view, offset, count, elemSize int) ([]byte, int, error) {
+	data, err := d.BufferViewData(view)
+	if err != nil {
+		return nil, 0, err
+	}
+	stride := d.BufferViews[view].ByteStride
+	if stride == 0 {
+		stride = elemSize
+	}
+	if stride < elemSize {
+		return nil, 0, errors.New("gltf: buffer view stride is smaller than its elements")
+	}
+	// Compare the count with how many elements fit, rather than computing
+	// the end of the last element, which a huge count would overflow.
+	if offset < 0 || offset > len(data) || count < 0 ||
+		count > 0 && (len(data)-offset < elemSize || count > (len(data)-offset-elemSize)/stride+1) {
+		return nil, 0, errors.New("gltf: accessor is out of range of its buffer view")
+	}
+	return data[offset:], stride, nil
+}
+
+// ReadAccessor returns the elements of an accessor as floats, with the
+// components of each element in a row and sparse values applied. Matrices
+// are returned in column major order. Integer components are normalized
+// to [0, 1] or [-1, 1] if the accessor says so.
+func (d *Document) ReadAccessor(index int) ([]float32, error) {
+	if index < 0 || index >= len(d.Accessors) {
+		return nil, errors.New("gltf: accessor index is out of range")
+	}
+	a := &d.Accessors[index]
+	n := a.Components()
+	size := componentSize(a.ComponentType)
+	if n == 0 || size == 0 || a.Count < 0 {
+		return nil, errors.New("gltf: accessor has an unknown type")
+	}
+	rows, cols, colSize := a.layout()
+	elemSize := cols * colSize
+
+	var data []byte
+	stride := elemSize
+	if a.BufferView != nil {
+		var err error
+		data, stride, err = d.elements(*a.BufferView, a.ByteOffset, a.Count, elemSize)
+		if err != nil {
+			return nil, err
+		}
+	} else if a.Count > maxZeroCount {
+		return nil, errors.New("gltf: accessor without a buffer view is too large")
+	}
+	out := make([]float32, a.Count*n)
+
+	readElement := func(p []byte, dst []float32) {
+		for c := 0; c < cols; c++ {
+			for r := 0; r < rows; r++ {
+				dst[c*rows+r] = readComponent(p[c*colSize+r*size:], a.ComponentType, a.Normalized)
+			}
+		}
+	}
+
+	if a.BufferView != nil {
+		for i := 0; i < a.Count; i++ {
+			readElement(data[i*stride:], out[i*n:(i+1)*n])
+		}
+	}
+
+	if s := a.Sparse; s != nil {
+		indexSize := componentSize(s.Indices.ComponentType)
+		if indexSize == 0 || indexSize == 1 && s.Indices.ComponentType != UNSIGNED_BYTE {
+			return nil, errors.New("gltf: sparse indices have an unknown type")
+		}
+		indices, _, err := d.elements(s.Indices.BufferView, s.Indices.ByteOffset, s.Count, indexSize)
+		if err != nil {
+			return nil, err
+		}
+		values, _, err := d.elements(s.Values.BufferView, s.Values.ByteOffset, s.Count, elemSize)
+		if err != nil {
+			return nil, err
+		}
+		// Sparse data is always tightly packed.
+		for i := 0; i < s.Count; i++ {
+			idx := int(readIndex(indices[i*indexSize:], s.Indices.ComponentType))
+			if idx >= a.Count {
+				return nil, errors.New("gltf: sparse index is out of range")
+			}
+			readElement(values[i*elemSize:], out[idx*n:(idx+1)*n])
+		}
+	}
+	return out, nil
+}
+
+// Returns indices as 16 bit values, or false if any of them is 0xFFFF or
+// more. Index 0xFFFF is never used, since WebGL 2 treats it as a primitive
+// restart.
+func indices16(indices []uint32) ([]uint16, bool) {
+	out := make([]uint16, len(indices))
+	for i, v := range indices {
+		if v >= 0xFFFF {
+			return nil, false
+		}
+		out[i] = uint16(v)
+	}
+	return out, true
+}
+
+// ReadIndices returns the elements of a scalar accessor of unsigned
+// integers, such as the indices of a primitive.
+func (d *Document) ReadIndices(index int) ([]uint32, error) {
+	if index < 0 || index >= len(d.Accessors) {
+		return nil, errors.New("gltf: accessor index is out of range")
+	}
+	a := &d.Accessors[index]
+	switch a.ComponentType {
+	case UNSIGNED_BYTE, UNSIGNED_SHORT, UNSIGNED_INT:
+	default:
+		return nil, errors.New("gltf: indices must be unsigned integers")
+	}
+	if a.Type != "SCALAR" {
+		return nil, errors.New("gltf: indices must be a scalar accessor")
+	}
+	if a.Sparse != nil {
+		values, err := d.ReadAccessor(index)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]uint32, len(values))
+		for i, v := range values {
+			out[i] = uint32(v)
+		}
+		return out, nil
+	}
+	if a.BufferView == nil {
+		if a.Count < 0 || a.Count > maxZeroCount {
+			return nil, errors.New("gltf: accessor without a buffer view is too large")
+		}
+		return make([]uint32, a.Count), nil
+	}
+	size := componentSize(a.ComponentType)
+	data, stride, err := d.elements(*a.BufferView, a.ByteOffset, a.Count, size)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]uint32, a.Count)
+	for i := range out {
+		out[i] = readIndex(data[i*stride:], a.ComponentType)
+	}
+	return out, nil
+}
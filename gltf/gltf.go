@@ -0,0 +1,385 @@
+// Package gltf reads glTF 2.0 models from .gltf and .glb files.
+//
+// Parsing is pure Go, so models can be loaded and inspected without a
+// browser. Upload, which is only built for wasm, turns a parsed document
+// into WebGL buffers and textures.
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Component types of accessors, with the values of the WebGL enums.
+const (
+	BYTE           = 0x1400
+	UNSIGNED_BYTE  = 0x1401
+	SHORT          = 0x1402
+	UNSIGNED_SHORT = 0x1403
+	UNSIGNED_INT   = 0x1405
+	FLOAT          = 0x1406
+)
+
+// Primitive modes, with the values of the WebGL enums.
+const (
+	POINTS         = 0
+	LINES          = 1
+	LINE_LOOP      = 2
+	LINE_STRIP     = 3
+	TRIANGLES      = 4
+	TRIANGLE_STRIP = 5
+	TRIANGLE_FAN   = 6
+)
+
+// Values of the alpha mode of a material.
+const (
+	AlphaOpaque = "OPAQUE"
+	AlphaMask   = "MASK"
+	AlphaBlend  = "BLEND"
+)
+
+const (
+	glbMagic     = 0x46546C67 // "glTF"
+	glbChunkJSON = 0x4E4F534A // "JSON"
+	glbChunkBIN  = 0x004E4942 // "BIN\0"
+)
+
+// Document is a parsed glTF file. The slices hold the top level arrays of
+// the file, and objects refer to each other by their index in them.
+type Document struct {
+	Asset              Asset        `json:"asset"`
+	ExtensionsUsed     []string     `json:"extensionsUsed"`
+	ExtensionsRequired []string     `json:"extensionsRequired"`
+	Scene              *int         `json:"scene"`
+	Scenes             []Scene      `json:"scenes"`
+	Nodes              []Node       `json:"nodes"`
+	Meshes             []Mesh       `json:"meshes"`
+	Accessors          []Accessor   `json:"accessors"`
+	BufferViews        []BufferView `json:"bufferViews"`
+	Buffers            []Buffer     `json:"buffers"`
+	Materials          []Material   `json:"materials"`
+	Textures           []Texture    `json:"textures"`
+	Images             []Image      `json:"images"`
+	Samplers           []Sampler    `json:"samplers"`
+}
+
+// Asset holds metadata about the file.
+type Asset struct {
+	Version    string `json:"version"`
+	MinVersion string `json:"minVersion"`
+	Generator  string `json:"generator"`
+	Copyright  string `json:"copyright"`
+}
+
+// Scene is a set of root nodes.
+type Scene struct {
+	Name  string `json:"name"`
+	Nodes []int  `json:"nodes"`
+}
+
+// Mesh is a set of primitives that are drawn together.
+type Mesh struct {
+	Name       string      `json:"name"`
+	Primitives []Primitive `json:"primitives"`
+	Weights    []float32   `json:"weights"`
+}
+
+// Primitive is a single draw call: vertex attributes, optional indices and
+// a material.
+type Primitive struct {
+	// Attributes maps attribute names, such as "POSITION", "NORMAL" and
+	// "TEXCOORD_0", to accessors.
+	Attributes map[string]int   `json:"attributes"`
+	Indices    *int             `json:"indices"`
+	Material   *int             `json:"material"`
+	Mode       *int             `json:"mode"`
+	Targets    []map[string]int `json:"targets"`
+}
+
+// Returns the primitive mode, which defaults to TRIANGLES.
+func (p *Primitive) DrawMode() int {
+	if p.Mode == nil {
+		return TRIANGLES
+	}
+	return *p.Mode
+}
+
+// Buffer is a block of binary data.
+type Buffer struct {
+	Name       string `json:"name"`
+	ByteLength int    `json:"byteLength"`
+	URI        string `json:"uri"`
+
+	// Data holds the contents of the buffer, loaded by Parse.
+	Data []byte `json:"-"`
+}
+
+// BufferView is a range of a buffer.
+type BufferView struct {
+	Name       string `json:"name"`
+	Buffer     int    `json:"buffer"`
+	ByteOffset int    `json:"byteOffset"`
+	ByteLength int    `json:"byteLength"`
+	ByteStride int    `json:"byteStride"`
+	Target     int    `json:"target"`
+}
+
+// Image is an image used by textures, stored either in a buffer view or
+// behind a URI.
+type Image struct {
+	Name       string `json:"name"`
+	URI        string `json:"uri"`
+	MimeType   string `json:"mimeType"`
+	BufferView *int   `json:"bufferView"`
+
+	// Data holds the encoded image, such as a PNG or JPEG file, loaded by
+	// Parse.
+	Data []byte `json:"-"`
+}
+
+// Sampler holds the filters and wrap modes of a texture. Filters are 0 if
+// the file does not specify them.
+type Sampler struct {
+	Name      string `json:"name"`
+	MagFilter int    `json:"magFilter"`
+	MinFilter int    `json:"minFilter"`
+	WrapS     int    `json:"wrapS"`
+	WrapT     int    `json:"wrapT"`
+}
+
+// UnmarshalJSON sets the default wrap modes before decoding.
+func (s *Sampler) UnmarshalJSON(data []byte) error {
+	type plain Sampler
+	p := plain{WrapS: 0x2901, WrapT: 0x2901}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*s = Sampler(p)
+	return nil
+}
+
+// Texture pairs an image with a sampler.
+type Texture struct {
+	Name    string `json:"name"`
+	Sampler *int   `json:"sampler"`
+	Source  *int   `json:"source"`
+}
+
+// TextureInfo refers to a texture from a material.
+type TextureInfo struct {
+	Index    int `json:"index"`
+	TexCoord int `json:"texCoord"`
+
+	// Scale is the normal scale of a normal texture.
+	Scale float32 `json:"scale"`
+
+	// Strength is the occlusion strength of an occlusion texture.
+	Strength float32 `json:"strength"`
+}
+
+// UnmarshalJSON sets the default scale and strength before decoding.
+func (t *TextureInfo) UnmarshalJSON(data []byte) error {
+	type plain TextureInfo
+	p := plain{Scale: 1, Strength: 1}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*t = TextureInfo(p)
+	return nil
+}
+
+// PBRMetallicRoughness holds the metallic-roughness parameters of a
+// material.
+type PBRMetallicRoughness struct {
+	BaseColorFactor          [4]float32   `json:"baseColorFactor"`
+	BaseColorTexture         *TextureInfo `json:"baseColorTexture"`
+	MetallicFactor           float32      `json:"metallicFactor"`
+	RoughnessFactor          float32      `json:"roughnessFactor"`
+	MetallicRoughnessTexture *TextureInfo `json:"metallicRoughnessTexture"`
+}
+
+// Returns the parameters a material has when the file does not specify
+// them.
+func DefaultPBRMetallicRoughness() PBRMetallicRoughness {
+	return PBRMetallicRoughness{
+		BaseColorFactor: [4]float32{1, 1, 1, 1},
+		MetallicFactor:  1,
+		RoughnessFactor: 1,
+	}
+}
+
+// UnmarshalJSON sets the default factors before decoding.
+func (m *PBRMetallicRoughness) UnmarshalJSON(data []byte) error {
+	type plain PBRMetallicRoughness
+	p := plain(DefaultPBRMetallicRoughness())
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*m = PBRMetallicRoughness(p)
+	return nil
+}
+
+// Material describes how a primitive is shaded.
+type Material struct {
+	Name                 string               `json:"name"`
+	PBRMetallicRoughness PBRMetallicRoughness `json:"pbrMetallicRoughness"`
+	NormalTexture        *TextureInfo         `json:"normalTexture"`
+	OcclusionTexture     *TextureInfo         `json:"occlusionTexture"`
+	EmissiveTexture      *TextureInfo         `json:"emissiveTexture"`
+	EmissiveFactor       [3]float32           `json:"emissiveFactor"`
+	AlphaMode            string               `json:"alphaMode"`
+	AlphaCutoff          float32              `json:"alphaCutoff"`
+	DoubleSided          bool                 `json:"doubleSided"`
+}
+
+// UnmarshalJSON sets the defaults of the material before decoding.
+func (m *Material) UnmarshalJSON(data []byte) error {
+	type plain Material
+	p := plain{
+		PBRMetallicRoughness: DefaultPBRMetallicRoughness(),
+		AlphaMode:            AlphaOpaque,
+		AlphaCutoff:          0.5,
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*m = Material(p)
+	return nil
+}
+
+// Parse reads a .gltf or .glb file, telling them apart by their first
+// bytes. Buffers and images embedded as data URIs or stored in the .glb
+// binary chunk are loaded straight away. Other URIs are passed to
+// readFile, which may be nil if the file has no external resources.
+func Parse(data []byte, readFile func(uri string) ([]byte, error)) (*Document, error) {
+	var bin []byte
+	if len(data) >= 4 && binary.LittleEndian.Uint32(data) == glbMagic {
+		var err error
+		data, bin, err = readGLB(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d := &Document{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(d.Asset.Version, "2.") {
+		return nil, errors.New("gltf: unsupported version " + d.Asset.Version)
+	}
+	if err := d.load(bin, readFile); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// readGLB splits a .glb file into its JSON and binary chunks.
+func readGLB(data []byte) (jsonChunk, bin []byte, err error) {
+	le := binary.LittleEndian
+	if len(data) < 12 {
+		return nil, nil, errors.New("gltf: .glb header is truncated")
+	}
+	if le.Uint32(data[4:]) != 2 {
+		return nil, nil, errors.New("gltf: unsupported .glb version")
+	}
+	length := int(le.Uint32(data[8:]))
+	if length > len(data) {
+		return nil, nil, errors.New("gltf: .glb file is truncated")
+	}
+	for p := 12; p+8 <= length; {
+		size := int(le.Uint32(data[p:]))
+		typ := le.Uint32(data[p+4:])
+		p += 8
+		if size < 0 || p+size > length {
+			return nil, nil, errors.New("gltf: .glb chunk is truncated")
+		}
+		switch {
+		case typ == glbChunkJSON && jsonChunk == nil:
+			jsonChunk = data[p : p+size]
+		case typ == glbChunkBIN && bin == nil:
+			bin = data[p : p+size]
+		}
+		p += (size + 3) &^ 3
+	}
+	if jsonChunk == nil {
+		return nil, nil, errors.New("gltf: .glb file has no JSON chunk")
+	}
+	return jsonChunk, bin, nil
+}
+
+// load fills in the data of buffers and images.
+func (d *Document) load(bin []byte, readFile func(uri string) ([]byte, error)) error {
+	read := func(uri string) ([]byte, error) {
+		if strings.HasPrefix(uri, "data:") {
+			return decodeDataURI(uri)
+		}
+		if readFile == nil {
+			return nil, errors.New("gltf: no way to read external file " + uri)
+		}
+		return readFile(uri)
+	}
+	for i := range d.Buffers {
+		b := &d.Buffers[i]
+		if b.URI == "" {
+			if i != 0 || bin == nil {
+				return errors.New("gltf: buffer has no data")
+			}
+			b.Data = bin
+		} else {
+			data, err := read(b.URI)
+			if err != nil {
+				return err
+			}
+			b.Data = data
+		}
+		if len(b.Data) < b.ByteLength {
+			return errors.New("gltf: buffer is shorter than its byteLength")
+		}
+	}
+	for i := range d.BufferViews {
+		v := &d.BufferViews[i]
+		if v.Buffer < 0 || v.Buffer >= len(d.Buffers) || v.ByteOffset < 0 || v.ByteLength < 0 ||
+			v.ByteOffset+v.ByteLength > len(d.Buffers[v.Buffer].Data) {
+			return errors.New("gltf: buffer view is out of range")
+		}
+	}
+	for i := range d.Images {
+		img := &d.Images[i]
+		switch {
+		case img.BufferView != nil:
+			data, err := d.BufferViewData(*img.BufferView)
+			if err != nil {
+				return err
+			}
+			img.Data = data
+		case img.URI != "":
+			data, err := read(img.URI)
+			if err != nil {
+				return err
+			}
+			img.Data = data
+		}
+	}
+	return nil
+}
+
+// decodeDataURI returns the data of a base64 encoded data URI.
+func decodeDataURI(uri string) ([]byte, error) {
+	comma := strings.IndexByte(uri, ',')
+	if comma < 0 || !strings.HasSuffix(uri[:comma], ";base64") {
+		return nil, errors.New("gltf: only base64 data URIs are supported")
+	}
+	return base64.StdEncoding.DecodeString(uri[comma+1:])
+}
+
+// Returns the bytes covered by a buffer view.
+func (d *Document) BufferViewData(view int) ([]byte, error) {
+	if view < 0 || view >= len(d.BufferViews) {
+		return nil, errors.New("gltf: buffer view index is out of range")
+	}
+	v := &d.BufferViews[view]
+	return d.Buffers[v.Buffer].Data[v.ByteOffset : v.ByteOffset+v.ByteLength], nil
+}
@@ -0,0 +1,412 @@
+package gltf
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// floatBytes returns floats as little endian bytes.
+func floatBytes(values ...float32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+func dataURI(data []byte) string {
+	return "data:application/octet-stream;base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// glb builds a .glb file from a JSON chunk and an optional binary chunk.
+func glb(jsonChunk string, bin []byte) []byte {
+	pad := func(b []byte, with byte) []byte {
+		for len(b)%4 != 0 {
+			b = append(b, with)
+		}
+		return b
+	}
+	le := binary.LittleEndian
+	out := make([]byte, 12)
+	le.PutUint32(out, glbMagic)
+	le.PutUint32(out[4:], 2)
+	chunk := func(typ uint32, data []byte) {
+		var h [8]byte
+		le.PutUint32(h[:], uint32(len(data)))
+		le.PutUint32(h[4:], typ)
+		out = append(append(out, h[:]...), data...)
+	}
+	chunk(glbChunkJSON, pad([]byte(jsonChunk), ' '))
+	if bin != nil {
+		chunk(glbChunkBIN, pad(bin, 0))
+	}
+	le.PutUint32(out[8:], uint32(len(out)))
+	return out
+}
+
+// triangle is a document with one triangle, using buffer 0 for positions
+// and indices.
+const triangle = `{
+	"asset": {"version": "2.0"},
+	"scene": 0,
+	"scenes": [{"nodes": [0]}],
+	"nodes": [{"mesh": 0}],
+	"meshes": [{"primitives": [{"attributes": {"POSITION": 0}, "indices": 1}]}],
+	"accessors": [
+		{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+		{"bufferView": 1, "componentType": 5123, "count": 3, "type": "SCALAR"}
+	],
+	"bufferViews": [
+		{"buffer": 0, "byteOffset": 0, "byteLength": 36},
+		{"buffer": 0, "byteOffset": 36, "byteLength": 6}
+	],
+	"buffers": [{"byteLength": 42%s}]
+}`
+
+var (
+	trianglePositions = []float32{0, 0, 0, 1, 0, 0, 0, 1, 0}
+	triangleIndices   = []uint32{0, 1, 2}
+)
+
+func triangleData() []byte {
+	return append(floatBytes(trianglePositions...), 0, 0, 1, 0, 2, 0)
+}
+
+// checkTriangle checks that d holds the triangle document.
+func checkTriangle(t *testing.T, d *Document) {
+	t.Helper()
+	positions, err := d.ReadAccessor(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(positions, trianglePositions) {
+		t.Errorf("positions = %v; want %v", positions, trianglePositions)
+	}
+	indices, err := d.ReadIndices(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(indices, triangleIndices) {
+		t.Errorf("indices = %v; want %v", indices, triangleIndices)
+	}
+	if mode := d.Meshes[0].Primitives[0].DrawMode(); mode != TRIANGLES {
+		t.Errorf("mode = %d; want TRIANGLES", mode)
+	}
+}
+
+func TestParseGLTF(t *testing.T) {
+	doc := strings.Replace(triangle, "%s", `, "uri": "triangle.bin"`, 1)
+	var asked []string
+	d, err := Parse([]byte(doc), func(uri string) ([]byte, error) {
+		asked = append(asked, uri)
+		return triangleData(), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(asked, []string{"triangle.bin"}) {
+		t.Errorf("read %q; want triangle.bin", asked)
+	}
+	checkTriangle(t, d)
+}
+
+func TestParseDataURI(t *testing.T) {
+	doc := strings.Replace(triangle, "%s", `, "uri": "`+dataURI(triangleData())+`"`, 1)
+	d, err := Parse([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, d)
+}
+
+func TestParseGLB(t *testing.T) {
+	doc := strings.Replace(triangle, "%s", "", 1)
+	d, err := Parse(glb(doc, triangleData()), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkTriangle(t, d)
+}
+
+func TestParseImages(t *testing.T) {
+	png := []byte("\x89PNG fake image")
+	doc := `{
+		"asset": {"version": "2.0"},
+		"bufferViews": [{"buffer": 0, "byteOffset": 4, "byteLength": 15}],
+		"buffers": [{"byteLength": 20}],
+		"images": [
+			{"bufferView": 0, "mimeType": "image/png"},
+			{"uri": "` + dataURI(png) + `"},
+			{"uri": "image.png"}
+		]
+	}`
+	bin := append(append([]byte{1, 2, 3, 4}, png...), 0)
+	d, err := Parse(glb(doc, bin), func(uri string) ([]byte, error) {
+		if uri != "image.png" {
+			return nil, errors.New("unexpected file " + uri)
+		}
+		return png, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, img := range d.Images {
+		if string(img.Data) != string(png) {
+			t.Errorf("image %d = %q; want %q", i, img.Data, png)
+		}
+	}
+}
+
+func TestReadAccessor(t *testing.T) {
+	// Interleaved vertices of 16 bytes: a VEC3 of floats, then a VEC2 of
+	// normalized unsigned shorts.
+	var vertices []byte
+	for i := 0; i < 3; i++ {
+		vertices = append(vertices, floatBytes(float32(i), float32(i)+0.5, -float32(i))...)
+		vertices = append(vertices, byte(i), 0, 0xFF, 0xFF)
+	}
+	// A MAT2 of bytes, whose columns are padded to 4 bytes, and a VEC2 of
+	// normalized bytes.
+	mat2 := []byte{1, 2, 0, 0, 3, 4, 0, 0}
+	bytes2 := []byte{0x81, 0x7F, 0x80, 0x00}
+	buf := append(append(vertices, mat2...), bytes2...)
+	doc := `{
+		"asset": {"version": "2.0"},
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 3, "type": "VEC3"},
+			{"bufferView": 0, "byteOffset": 12, "componentType": 5123, "normalized": true, "count": 3, "type": "VEC2"},
+			{"bufferView": 1, "componentType": 5121, "count": 1, "type": "MAT2"},
+			{"bufferView": 1, "byteOffset": 8, "componentType": 5120, "normalized": true, "count": 2, "type": "VEC2"},
+			{"componentType": 5126, "count": 2, "type": "VEC2"}
+		],
+		"bufferViews": [
+			{"buffer": 0, "byteLength": 48, "byteStride": 16},
+			{"buffer": 0, "byteOffset": 48, "byteLength": 12}
+		],
+		"buffers": [{"byteLength": 60, "uri": "` + dataURI(buf) + `"}]
+	}`
+	d, err := Parse([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		accessor int
+		want     []float32
+	}{
+		{0, []float32{0, 0.5, 0, 1, 1.5, -1, 2, 2.5, -2}},
+		{1, []float32{0, 1, 1.0 / 65535, 1, 2.0 / 65535, 1}},
+		{2, []float32{1, 2, 3, 4}},
+		{3, []float32{-1, 1, -1, 0}},
+		{4, []float32{0, 0, 0, 0}},
+	}
+	for _, test := range tests {
+		got, err := d.ReadAccessor(test.accessor)
+		if err != nil {
+			t.Errorf("accessor %d: %v", test.accessor, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("accessor %d = %v; want %v", test.accessor, got, test.want)
+		}
+	}
+}
+
+func TestSparse(t *testing.T) {
+	base := floatBytes(1, 2, 3, 4)
+	indices := []byte{3, 0, 1, 0}
+	values := floatBytes(40, 20)
+	buf := append(append(append(base, indices...), values...), 7, 0, 0, 0)
+	doc := `{
+		"asset": {"version": "2.0"},
+		"accessors": [
+			{"bufferView": 0, "componentType": 5126, "count": 4, "type": "SCALAR",
+				"sparse": {"count": 2,
+					"indices": {"bufferView": 1, "componentType": 5123},
+					"values": {"bufferView": 2}}},
+			{"componentType": 5126, "count": 5, "type": "SCALAR",
+				"sparse": {"count": 2,
+					"indices": {"bufferView": 1, "componentType": 5123},
+					"values": {"bufferView": 2}}},
+			{"componentType": 5125, "count": 4, "type": "SCALAR",
+				"sparse": {"count": 1,
+					"indices": {"bufferView": 1, "componentType": 5121},
+					"values": {"bufferView": 3}}},
+			{"componentType": 5126, "count": 3, "type": "SCALAR",
+				"sparse": {"count": 2,
+					"indices": {"bufferView": 1, "componentType": 5123},
+					"values": {"bufferView": 2}}}
+		],
+		"bufferViews": [
+			{"buffer": 0, "byteLength": 16},
+			{"buffer": 0, "byteOffset": 16, "byteLength": 4},
+			{"buffer": 0, "byteOffset": 20, "byteLength": 8},
+			{"buffer": 0, "byteOffset": 28, "byteLength": 4}
+		],
+		"buffers": [{"byteLength": 32, "uri": "` + dataURI(buf) + `"}]
+	}`
+	d, err := Parse([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		accessor int
+		want     []float32
+	}{
+		{0, []float32{1, 20, 3, 40}},
+		{1, []float32{0, 20, 0, 40, 0}},
+	}
+	for _, test := range tests {
+		got, err := d.ReadAccessor(test.accessor)
+		if err != nil {
+			t.Errorf("accessor %d: %v", test.accessor, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("accessor %d = %v; want %v", test.accessor, got, test.want)
+		}
+	}
+	// Unsigned byte sparse indices, on indices without a buffer view.
+	got, err := d.ReadIndices(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []uint32{0, 0, 0, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sparse indices = %v; want %v", got, want)
+	}
+	// Sparse index 3 is out of range of a 3 element accessor.
+	if _, err := d.ReadAccessor(3); err == nil {
+		t.Error("out of range sparse index did not fail")
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	valid := strings.Replace(triangle, "%s", `, "uri": "`+dataURI(triangleData())+`"`, 1)
+	glbFile := glb(strings.Replace(triangle, "%s", "", 1), triangleData())
+	edit := func(old, new string) []byte {
+		if !strings.Contains(valid, old) {
+			panic("gltf test: " + old + " is not in the document")
+		}
+		return []byte(strings.Replace(valid, old, new, 1))
+	}
+	glbEdit := func(fn func(b []byte) []byte) []byte {
+		return fn(append([]byte(nil), glbFile...))
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not JSON", []byte("solid cube")},
+		{"truncated JSON", []byte(valid[:len(valid)/2])},
+		{"version 1", edit(`"2.0"`, `"1.0"`)},
+		{"glb header truncated", glbFile[:8]},
+		{"glb version 1", glbEdit(func(b []byte) []byte { b[4] = 1; return b })},
+		{"glb longer than file", glbEdit(func(b []byte) []byte { return b[:len(b)-4] })},
+		{"glb chunk past end", glbEdit(func(b []byte) []byte { b[12] = 0xFF; return b })},
+		{"glb without JSON", glbEdit(func(b []byte) []byte { b[16] = 'X'; return b })},
+		{"glb without binary chunk", glb(strings.Replace(triangle, "%s", "", 1), nil)},
+		{"plain data URI", edit(";base64,", ",")},
+		{"bad base64", edit(";base64,", ";base64,!")},
+		{"external file", edit(dataURI(triangleData()), "triangle.bin")},
+		{"buffer too short", edit(`"byteLength": 42`, `"byteLength": 43`)},
+		{"view past buffer", edit(`"byteOffset": 36, "byteLength": 6`, `"byteOffset": 38, "byteLength": 6`)},
+		{"view of missing buffer", edit(`{"buffer": 0, "byteOffset": 36`, `{"buffer": 1, "byteOffset": 36`)},
+		{"negative view offset", edit(`"byteOffset": 36`, `"byteOffset": -1`)},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.data, nil); err == nil {
+			t.Errorf("%s: Parse succeeded", test.name)
+		}
+	}
+}
+
+func TestReadAccessorMalformed(t *testing.T) {
+	doc := strings.Replace(triangle, "%s", `, "uri": "`+dataURI(triangleData())+`"`, 1)
+	tests := []struct {
+		name     string
+		accessor string
+		indices  bool
+	}{
+		{"past end of view", `{"bufferView": 0, "componentType": 5126, "count": 4, "type": "VEC3"}`, false},
+		{"offset past end of view", `{"bufferView": 0, "byteOffset": 40, "componentType": 5126, "count": 0, "type": "VEC3"}`, false},
+		{"negative offset", `{"bufferView": 0, "byteOffset": -4, "componentType": 5126, "count": 0, "type": "VEC3"}`, false},
+		{"missing view", `{"bufferView": 5, "componentType": 5126, "count": 1, "type": "VEC3"}`, false},
+		{"unknown type", `{"bufferView": 0, "componentType": 5126, "count": 1, "type": "VEC5"}`, false},
+		{"unknown component type", `{"bufferView": 0, "componentType": 1, "count": 1, "type": "VEC3"}`, false},
+		{"negative count", `{"bufferView": 0, "componentType": 5126, "count": -1, "type": "VEC3"}`, false},
+		{"huge count", `{"bufferView": 0, "componentType": 5126, "count": 4611686018427387904, "type": "VEC3"}`, false},
+		{"largest count", `{"bufferView": 0, "componentType": 5126, "count": 9223372036854775807, "type": "MAT4"}`, false},
+		{"huge count without view", `{"componentType": 5126, "count": 4611686018427387904, "type": "VEC3"}`, false},
+		{"huge sparse count", `{"componentType": 5126, "count": 2, "type": "SCALAR",
+			"sparse": {"count": 4611686018427387904, "indices": {"bufferView": 1, "componentType": 5123}, "values": {"bufferView": 0}}}`, false},
+		{"float indices", `{"bufferView": 0, "componentType": 5126, "count": 3, "type": "SCALAR"}`, true},
+		{"vector indices", `{"bufferView": 1, "componentType": 5123, "count": 1, "type": "VEC3"}`, true},
+		{"indices past end of view", `{"bufferView": 1, "componentType": 5123, "count": 4, "type": "SCALAR"}`, true},
+		{"huge index count", `{"bufferView": 1, "componentType": 5123, "count": 9223372036854775807, "type": "SCALAR"}`, true},
+		{"huge index count without view", `{"componentType": 5123, "count": 4611686018427387904, "type": "SCALAR"}`, true},
+		{"sparse byte indices", `{"componentType": 5126, "count": 2, "type": "SCALAR",
+			"sparse": {"count": 1, "indices": {"bufferView": 1, "componentType": 5120}, "values": {"bufferView": 0}}}`, false},
+		{"sparse values past end", `{"componentType": 5126, "count": 2, "type": "VEC3",
+			"sparse": {"count": 2, "indices": {"bufferView": 1, "componentType": 5123}, "values": {"bufferView": 1}}}`, false},
+	}
+	for _, test := range tests {
+		d, err := Parse([]byte(strings.Replace(doc, `"accessors": [`, `"accessors": [`+test.accessor+",", 1)), nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if test.indices {
+			_, err = d.ReadIndices(0)
+		} else {
+			_, err = d.ReadAccessor(0)
+		}
+		if err == nil {
+			t.Errorf("%s: reading the accessor succeeded", test.name)
+		}
+	}
+	d, err := Parse([]byte(doc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.ReadAccessor(2); err == nil {
+		t.Error("reading a missing accessor succeeded")
+	}
+	if _, err := d.ReadIndices(-1); err == nil {
+		t.Error("reading a negative accessor succeeded")
+	}
+}
+
+func TestIndices16(t *testing.T) {
+	tests := []struct {
+		indices []uint32
+		ok      bool
+	}{
+		{nil, true},
+		{[]uint32{0, 1, 2}, true},
+		{[]uint32{0, 0xFFFE, 1}, true},
+		{[]uint32{0, 0xFFFF, 1}, false},
+		{[]uint32{0x10000}, false},
+		{[]uint32{1, 2, 0xFFFFFFFF}, false},
+	}
+	for _, tt := range tests {
+		got, ok := indices16(tt.indices)
+		if ok != tt.ok {
+			t.Errorf("indices16(%v) ok = %v; want %v", tt.indices, ok, tt.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		for i, v := range got {
+			if uint32(v) != tt.indices[i] {
+				t.Errorf("indices16(%v) = %v", tt.indices, got)
+				break
+			}
+		}
+	}
+}
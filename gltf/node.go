@@ -0,0 +1,139 @@
+package gltf
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Node is an element of the scene hierarchy. Its transform is either
+// Matrix or the combination of Translation, Rotation and Scale.
+type Node struct {
+	Name     string `json:"name"`
+	Children []int  `json:"children"`
+	Mesh     *int   `json:"mesh"`
+	Camera   *int   `json:"camera"`
+	Skin     *int   `json:"skin"`
+
+	// Matrix is the column major transform of the node, or nil if the node
+	// is transformed by Translation, Rotation and Scale.
+	Matrix      *[16]float32 `json:"matrix"`
+	Translation [3]float32   `json:"translation"`
+	Rotation    [4]float32   `json:"rotation"`
+	Scale       [3]float32   `json:"scale"`
+	Weights     []float32    `json:"weights"`
+}
+
+// UnmarshalJSON sets the identity transform before decoding.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	type plain Node
+	p := plain{
+		Rotation: [4]float32{0, 0, 0, 1},
+		Scale:    [3]float32{1, 1, 1},
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*n = Node(p)
+	return nil
+}
+
+// LocalMatrix returns the column major transform of the node relative to
+// its parent.
+func (n *Node) LocalMatrix() [16]float32 {
+	if n.Matrix != nil {
+		return *n.Matrix
+	}
+	x, y, z, w := n.Rotation[0], n.Rotation[1], n.Rotation[2], n.Rotation[3]
+	sx, sy, sz := n.Scale[0], n.Scale[1], n.Scale[2]
+	return [16]float32{
+		(1 - 2*(y*y+z*z)) * sx, (2 * (x*y + z*w)) * sx, (2 * (x*z - y*w)) * sx, 0,
+		(2 * (x*y - z*w)) * sy, (1 - 2*(x*x+z*z)) * sy, (2 * (y*z + x*w)) * sy, 0,
+		(2 * (x*z + y*w)) * sz, (2 * (y*z - x*w)) * sz, (1 - 2*(x*x+y*y)) * sz, 0,
+		n.Translation[0], n.Translation[1], n.Translation[2], 1,
+	}
+}
+
+// Returns the product a*b of two column major matrices.
+func mulMatrix(a, b *[16]float32) [16]float32 {
+	var m [16]float32
+	for c := 0; c < 4; c++ {
+		for r := 0; r < 4; r++ {
+			var sum float32
+			for k := 0; k < 4; k++ {
+				sum += a[k*4+r] * b[c*4+k]
+			}
+			m[c*4+r] = sum
+		}
+	}
+	return m
+}
+
+// Returns the scene to show when none is picked: the file's default scene,
+// or the first one. It returns -1 if there are no scenes.
+func (d *Document) DefaultScene() int {
+	if d.Scene != nil {
+		return *d.Scene
+	}
+	if len(d.Scenes) > 0 {
+		return 0
+	}
+	return -1
+}
+
+// Parents returns the index of the parent of each node, or -1 for nodes
+// without a parent.
+func (d *Document) Parents() []int {
+	parents := make([]int, len(d.Nodes))
+	for i := range parents {
+		parents[i] = -1
+	}
+	for i, n := range d.Nodes {
+		for _, child := range n.Children {
+			if child >= 0 && child < len(parents) {
+				parents[child] = i
+			}
+		}
+	}
+	return parents
+}
+
+// Walk visits the nodes of a scene depth first, parents before their
+// children, passing each node's index and its transform relative to the
+// scene root. Visiting stops at the first error fn returns. Walk fails if
+// the hierarchy refers to nodes that do not exist, or is not a tree.
+func (d *Document) Walk(scene int, fn func(node int, world [16]float32) error) error {
+	if scene < 0 || scene >= len(d.Scenes) {
+		return errors.New("gltf: scene index is out of range")
+	}
+	visited := make([]bool, len(d.Nodes))
+	var walk func(node int, parent *[16]float32) error
+	walk = func(node int, parent *[16]float32) error {
+		if node < 0 || node >= len(d.Nodes) {
+			return errors.New("gltf: node index is out of range")
+		}
+		if visited[node] {
+			return errors.New("gltf: node hierarchy is not a tree")
+		}
+		visited[node] = true
+		n := &d.Nodes[node]
+		world := n.LocalMatrix()
+		if parent != nil {
+			world = mulMatrix(parent, &world)
+		}
+		if err := fn(node, world); err != nil {
+			return err
+		}
+		for _, child := range n.Children {
+			if err := walk(child, &world); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range d.Scenes[scene].Nodes {
+		if err := walk(root, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
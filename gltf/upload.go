@@ -0,0 +1,369 @@
+// +build wasm
+
+package gltf
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"syscall/js"
+
+	"github.com/justinclift/webgl"
+)
+
+// Attribute is a vertex attribute uploaded to a buffer as tightly packed
+// floats.
+type Attribute struct {
+	Buffer *js.Value
+
+	// Size is the number of components per vertex.
+	Size int
+}
+
+// DrawPrimitive is a Primitive uploaded to WebGL buffers.
+type DrawPrimitive struct {
+	// Attributes maps glTF attribute names, such as "POSITION", to their
+	// buffers.
+	Attributes map[string]Attribute
+
+	// Indices is the index buffer, or nil if the primitive is drawn with
+	// DrawArrays. IndexType is UNSIGNED_SHORT or UNSIGNED_INT.
+	Indices   *js.Value
+	IndexType int
+
+	// Count is the number of indices, or vertices if there are none.
+	Count int
+	Mode  int
+
+	// Material is the index of the material, or -1 for the default one.
+	Material int
+}
+
+// Model is a Document uploaded to WebGL.
+type Model struct {
+	Document *Document
+
+	// Meshes holds the uploaded primitives of each mesh.
+	Meshes [][]*DrawPrimitive
+
+	// Textures holds the uploaded texture of each glTF texture.
+	Textures []*webgl.Texture
+
+	gl      *webgl.Context
+	buffers []*js.Value
+	white   *webgl.Texture
+	normal  *webgl.Texture
+}
+
+// Upload creates buffers for the meshes and textures for the images of a
+// document. Attributes are converted to floats, and indices are uploaded
+// as 16 bit values unless they need 32 bits, which requires the
+// OES_element_index_uint extension.
+func Upload(c *webgl.Context, d *Document) (*Model, error) {
+	m := &Model{Document: d, gl: c}
+	if err := m.upload(); err != nil {
+		m.Delete()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Model) upload() error {
+	d := m.Document
+	attributes := make(map[int]Attribute)
+	for _, mesh := range d.Meshes {
+		var prims []*DrawPrimitive
+		for i := range mesh.Primitives {
+			p := &mesh.Primitives[i]
+			dp := &DrawPrimitive{
+				Attributes: make(map[string]Attribute, len(p.Attributes)),
+				Mode:       p.DrawMode(),
+				Material:   -1,
+			}
+			if p.Material != nil {
+				if *p.Material < 0 || *p.Material >= len(d.Materials) {
+					return errors.New("gltf: material index is out of range")
+				}
+				dp.Material = *p.Material
+			}
+			for name, accessor := range p.Attributes {
+				attr, ok := attributes[accessor]
+				if !ok {
+					data, err := d.ReadAccessor(accessor)
+					if err != nil {
+						return err
+					}
+					attr = Attribute{m.newBuffer(webgl.ARRAY_BUFFER, data), d.Accessors[accessor].Components()}
+					attributes[accessor] = attr
+				}
+				dp.Attributes[name] = attr
+			}
+			if pos, ok := p.Attributes["POSITION"]; ok {
+				dp.Count = d.Accessors[pos].Count
+			}
+			if p.Indices != nil {
+				if err := m.uploadIndices(dp, *p.Indices); err != nil {
+					return err
+				}
+			}
+			prims = append(prims, dp)
+		}
+		m.Meshes = append(m.Meshes, prims)
+	}
+
+	for _, t := range d.Textures {
+		tex, err := m.uploadTexture(&t)
+		if err != nil {
+			return err
+		}
+		m.Textures = append(m.Textures, tex)
+	}
+	return nil
+}
+
+// Creates a buffer bound to target holding data.
+func (m *Model) newBuffer(target int, data interface{}) *js.Value {
+	buf := m.gl.CreateBuffer()
+	m.gl.BindBuffer(target, buf)
+	m.gl.BufferData(target, webgl.SliceToTypedArray(data), webgl.STATIC_DRAW)
+	m.buffers = append(m.buffers, buf)
+	return buf
+}
+
+func (m *Model) uploadIndices(dp *DrawPrimitive, accessor int) error {
+	indices, err := m.Document.ReadIndices(accessor)
+	if err != nil {
+		return err
+	}
+	dp.Count = len(indices)
+	if short, ok := indices16(indices); ok {
+		dp.Indices = m.newBuffer(webgl.ELEMENT_ARRAY_BUFFER, short)
+		dp.IndexType = webgl.UNSIGNED_SHORT
+		return nil
+	}
+	if !m.gl.HasExtension("OES_element_index_uint") {
+		return &webgl.ExtensionError{Name: "OES_element_index_uint"}
+	}
+	dp.Indices = m.newBuffer(webgl.ELEMENT_ARRAY_BUFFER, indices)
+	dp.IndexType = webgl.UNSIGNED_INT
+	return nil
+}
+
+func (m *Model) uploadTexture(t *Texture) (*webgl.Texture, error) {
+	d := m.Document
+	if t.Source == nil || *t.Source < 0 || *t.Source >= len(d.Images) {
+		return nil, errors.New("gltf: texture has no image")
+	}
+	opts := webgl.DefaultTextureOptions()
+	// glTF texture coordinates start at the top of the image.
+	opts.FlipY = false
+	if t.Sampler != nil {
+		if *t.Sampler < 0 || *t.Sampler >= len(d.Samplers) {
+			return nil, errors.New("gltf: sampler index is out of range")
+		}
+		s := &d.Samplers[*t.Sampler]
+		if s.MinFilter != 0 {
+			opts.MinFilter = s.MinFilter
+			opts.Mipmaps = s.MinFilter >= webgl.NEAREST_MIPMAP_NEAREST && s.MinFilter <= webgl.LINEAR_MIPMAP_LINEAR
+		}
+		if s.MagFilter != 0 {
+			opts.MagFilter = s.MagFilter
+		}
+		opts.WrapS, opts.WrapT = s.WrapS, s.WrapT
+	}
+	return m.gl.NewTextureFromBytes(d.Images[*t.Source].Data, opts)
+}
+
+// Draw draws a primitive. locations maps glTF attribute names to the
+// attribute locations of the current program; attributes without a
+// location are skipped. The attribute arrays it enables are disabled
+// again after drawing, so they can not leak into later draws.
+func (p *DrawPrimitive) Draw(c *webgl.Context, locations map[string]int) {
+	enabled := make([]int, 0, len(p.Attributes))
+	for name, attr := range p.Attributes {
+		loc, ok := locations[name]
+		if !ok || loc < 0 {
+			continue
+		}
+		c.BindBuffer(webgl.ARRAY_BUFFER, attr.Buffer)
+		c.EnableVertexAttribArray(loc)
+		c.VertexAttribPointer(loc, attr.Size, webgl.FLOAT, false, 0, 0)
+		enabled = append(enabled, loc)
+	}
+	if p.Indices != nil {
+		c.BindBuffer(webgl.ELEMENT_ARRAY_BUFFER, p.Indices)
+		c.DrawElements(p.Mode, p.Count, p.IndexType, 0)
+	} else {
+		c.DrawArrays(p.Mode, 0, p.Count)
+	}
+	for _, loc := range enabled {
+		c.DisableVertexAttribArray(loc)
+	}
+}
+
+// MaterialUniforms holds the uniform locations ApplyMaterial sets. Texture
+// samplers are assigned texture units 0 to 4 in field order.
+type MaterialUniforms struct {
+	BaseColorFactor          *js.Value // vec4
+	BaseColorTexture         *js.Value // sampler2D
+	MetallicRoughness        *js.Value // vec2: metallic, roughness
+	MetallicRoughnessTexture *js.Value // sampler2D
+	NormalTexture            *js.Value // sampler2D
+	NormalScale              *js.Value // float
+	OcclusionTexture         *js.Value // sampler2D
+	OcclusionStrength        *js.Value // float
+	EmissiveFactor           *js.Value // vec3
+	EmissiveTexture          *js.Value // sampler2D
+	AlphaCutoff              *js.Value // float, 0 unless the alpha mode is MASK
+}
+
+// Looks up the material uniforms of a program by the names u_baseColorFactor,
+// u_baseColorTexture, u_metallicRoughness, u_metallicRoughnessTexture,
+// u_normalTexture, u_normalScale, u_occlusionTexture, u_occlusionStrength,
+// u_emissiveFactor, u_emissiveTexture and u_alphaCutoff.
+func GetMaterialUniforms(c *webgl.Context, program *js.Value) *MaterialUniforms {
+	loc := func(name string) *js.Value {
+		return c.GetUniformLocation(program, name)
+	}
+	return &MaterialUniforms{
+		BaseColorFactor:          loc("u_baseColorFactor"),
+		BaseColorTexture:         loc("u_baseColorTexture"),
+		MetallicRoughness:        loc("u_metallicRoughness"),
+		MetallicRoughnessTexture: loc("u_metallicRoughnessTexture"),
+		NormalTexture:            loc("u_normalTexture"),
+		NormalScale:              loc("u_normalScale"),
+		OcclusionTexture:         loc("u_occlusionTexture"),
+		OcclusionStrength:        loc("u_occlusionStrength"),
+		EmissiveFactor:           loc("u_emissiveFactor"),
+		EmissiveTexture:          loc("u_emissiveTexture"),
+		AlphaCutoff:              loc("u_alphaCutoff"),
+	}
+}
+
+// ApplyMaterial sets the uniforms, textures, blending and face culling of
+// a material, or of the default material if index is -1. Textures the
+// material does not have are replaced by ones that leave the result
+// unchanged.
+func (m *Model) ApplyMaterial(index int, u *MaterialUniforms) {
+	c := m.gl
+	mat := Material{
+		PBRMetallicRoughness: DefaultPBRMetallicRoughness(),
+		AlphaMode:            AlphaOpaque,
+	}
+	if index >= 0 {
+		mat = m.Document.Materials[index]
+	}
+	pbr := &mat.PBRMetallicRoughness
+
+	f := pbr.BaseColorFactor
+	c.Uniform4f(u.BaseColorFactor, f[0], f[1], f[2], f[3])
+	c.Uniform2f(u.MetallicRoughness, pbr.MetallicFactor, pbr.RoughnessFactor)
+	c.Uniform3f(u.EmissiveFactor, mat.EmissiveFactor[0], mat.EmissiveFactor[1], mat.EmissiveFactor[2])
+
+	m.bindTexture(0, u.BaseColorTexture, pbr.BaseColorTexture, m.whiteTexture())
+	m.bindTexture(1, u.MetallicRoughnessTexture, pbr.MetallicRoughnessTexture, m.whiteTexture())
+	m.bindTexture(2, u.NormalTexture, mat.NormalTexture, m.normalTexture())
+	m.bindTexture(3, u.OcclusionTexture, mat.OcclusionTexture, m.whiteTexture())
+	m.bindTexture(4, u.EmissiveTexture, mat.EmissiveTexture, m.whiteTexture())
+	normalScale, occlusionStrength := float32(1), float32(1)
+	if mat.NormalTexture != nil {
+		normalScale = mat.NormalTexture.Scale
+	}
+	if mat.OcclusionTexture != nil {
+		occlusionStrength = mat.OcclusionTexture.Strength
+	}
+	c.Uniform1f(u.NormalScale, normalScale)
+	c.Uniform1f(u.OcclusionStrength, occlusionStrength)
+
+	cutoff := float32(0)
+	if mat.AlphaMode == AlphaMask {
+		cutoff = mat.AlphaCutoff
+	}
+	c.Uniform1f(u.AlphaCutoff, cutoff)
+	if mat.AlphaMode == AlphaBlend {
+		c.Enable(webgl.BLEND)
+		c.BlendFunc(webgl.SRC_ALPHA, webgl.ONE_MINUS_SRC_ALPHA)
+	} else {
+		c.Disable(webgl.BLEND)
+	}
+	if mat.DoubleSided {
+		c.Disable(webgl.CULL_FACE)
+	} else {
+		c.Enable(webgl.CULL_FACE)
+	}
+}
+
+// Binds the texture of info, or fallback if there is none, to a texture
+// unit and points the sampler uniform at it.
+func (m *Model) bindTexture(unit int, sampler *js.Value, info *TextureInfo, fallback *webgl.Texture) {
+	tex := fallback
+	if info != nil && info.Index >= 0 && info.Index < len(m.Textures) {
+		tex = m.Textures[info.Index]
+	}
+	m.gl.ActiveTexture(webgl.TEXTURE0 + unit)
+	m.gl.BindTexture(webgl.TEXTURE_2D, tex.Object)
+	m.gl.Uniform1i(sampler, unit)
+}
+
+// Returns a 1x1 texture of the given color, for missing textures.
+func (m *Model) solidTexture(r, g, b uint8) *webgl.Texture {
+	opts := webgl.DefaultTextureOptions()
+	opts.Mipmaps = false
+	tex, _ := m.gl.NewTextureFromImage(solidImage(r, g, b), opts)
+	return tex
+}
+
+func solidImage(r, g, b uint8) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{r, g, b, 255})
+	return img
+}
+
+func (m *Model) whiteTexture() *webgl.Texture {
+	if m.white == nil {
+		m.white = m.solidTexture(255, 255, 255)
+	}
+	return m.white
+}
+
+func (m *Model) normalTexture() *webgl.Texture {
+	if m.normal == nil {
+		m.normal = m.solidTexture(128, 128, 255)
+	}
+	return m.normal
+}
+
+// DrawScene draws every mesh of a scene. The world transform of each node
+// is set on the mat4 uniform at modelMatrix, and materials are applied
+// with ApplyMaterial.
+func (m *Model) DrawScene(scene int, modelMatrix *js.Value, u *MaterialUniforms, locations map[string]int) error {
+	return m.Document.Walk(scene, func(node int, world [16]float32) error {
+		mesh := m.Document.Nodes[node].Mesh
+		if mesh == nil {
+			return nil
+		}
+		if *mesh < 0 || *mesh >= len(m.Meshes) {
+			return errors.New("gltf: mesh index is out of range")
+		}
+		m.gl.UniformMatrix4fv(modelMatrix, false, world[:])
+		for _, p := range m.Meshes[*mesh] {
+			m.ApplyMaterial(p.Material, u)
+			p.Draw(m.gl, locations)
+		}
+		return nil
+	})
+}
+
+// Delete deletes the buffers and textures of the model.
+func (m *Model) Delete() {
+	for _, buf := range m.buffers {
+		m.gl.DeleteBuffer(buf)
+	}
+	m.buffers = nil
+	for _, tex := range append(m.Textures, m.white, m.normal) {
+		if tex != nil {
+			tex.Delete()
+		}
+	}
+	m.Textures, m.white, m.normal = nil, nil, nil
+}
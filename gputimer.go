@@ -0,0 +1,274 @@
+// +build wasm
+
+package webgl
+
+import (
+	"sort"
+	"syscall/js"
+	"time"
+)
+
+// EXT_disjoint_timer_query
+const (
+	QUERY_COUNTER_BITS_EXT     = 0x8864
+	CURRENT_QUERY_EXT          = 0x8865
+	QUERY_RESULT_EXT           = 0x8866
+	QUERY_RESULT_AVAILABLE_EXT = 0x8867
+	TIME_ELAPSED_EXT           = 0x88BF
+	TIMESTAMP_EXT              = 0x8E28
+	GPU_DISJOINT_EXT           = 0x8FBB
+)
+
+const (
+	// The number of samples GPU timings are averaged over by default.
+	defaultGPUTimingWindow = 60
+
+	// Queries still waiting for their result once there are this many are
+	// given up on, so a stalled GPU can not make them pile up.
+	maxPendingGPUQueries = 256
+)
+
+// GPUTiming is the measured GPU time of a named scope.
+type GPUTiming struct {
+	Name string
+
+	// Last is the most recent measurement, and Average the mean of the
+	// last Samples measurements.
+	Last    time.Duration
+	Average time.Duration
+	Samples int
+}
+
+// gpuScope holds the recent measurements of a scope in a ring buffer.
+type gpuScope struct {
+	samples []time.Duration
+	next    int
+	count   int
+	last    time.Duration
+}
+
+func (s *gpuScope) add(d time.Duration) {
+	s.samples[s.next] = d
+	s.next = (s.next + 1) % len(s.samples)
+	if s.count < len(s.samples) {
+		s.count++
+	}
+	s.last = d
+}
+
+func (s *gpuScope) average() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	var sum time.Duration
+	for _, d := range s.samples[:s.count] {
+		sum += d
+	}
+	return sum / time.Duration(s.count)
+}
+
+type gpuQuery struct {
+	name  string
+	query js.Value
+}
+
+// GPUProfiler measures how long the GPU spends on named scopes of drawing
+// commands, using EXT_disjoint_timer_query, or its WebGL 2 equivalent.
+//
+// Results arrive a few frames after the commands ran, so call Poll once a
+// frame to collect them. Whenever the GPU reports a disjoint event, such
+// as a change of clock speed, the measurements in flight are thrown away.
+type GPUProfiler struct {
+	// Window is how many of the latest measurements each average is taken
+	// over. Changing it only affects scopes that have not been seen yet.
+	Window int
+
+	ext     js.Value
+	webgl2  bool
+	gl      *Context
+	free    []js.Value
+	pending []gpuQuery
+	active  *gpuQuery
+	scopes  map[string]*gpuScope
+}
+
+// Creates a GPUProfiler, or returns an *ExtensionError if timer queries
+// are not supported.
+func (c *Context) NewGPUProfiler() (*GPUProfiler, error) {
+	p := &GPUProfiler{
+		Window: defaultGPUTimingWindow,
+		gl:     c,
+		scopes: make(map[string]*gpuScope),
+	}
+	ext, err := c.extension("EXT_disjoint_timer_query")
+	if err != nil {
+		// WebGL 2 contexts only have the variant that uses the context's
+		// own query methods.
+		if c.Object.Get("createQuery").Type() != js.TypeFunction {
+			return nil, err
+		}
+		if ext, err = c.extension("EXT_disjoint_timer_query_webgl2"); err != nil {
+			return nil, err
+		}
+		p.webgl2 = true
+	}
+	p.ext = ext
+	return p, nil
+}
+
+func (p *GPUProfiler) createQuery() js.Value {
+	if n := len(p.free); n > 0 {
+		q := p.free[n-1]
+		p.free = p.free[:n-1]
+		return q
+	}
+	if p.webgl2 {
+		return p.gl.callResult("createQuery")
+	}
+	return p.ext.Call("createQueryEXT")
+}
+
+func (p *GPUProfiler) beginQuery(q js.Value) {
+	p.gl.FlushBatch()
+	if p.webgl2 {
+		p.gl.Object.Call("beginQuery", TIME_ELAPSED_EXT, q)
+	} else {
+		p.ext.Call("beginQueryEXT", TIME_ELAPSED_EXT, q)
+	}
+}
+
+func (p *GPUProfiler) endQuery() {
+	p.gl.FlushBatch()
+	if p.webgl2 {
+		p.gl.Object.Call("endQuery", TIME_ELAPSED_EXT)
+	} else {
+		p.ext.Call("endQueryEXT", TIME_ELAPSED_EXT)
+	}
+}
+
+func (p *GPUProfiler) queryResult(q js.Value, pname int) js.Value {
+	if p.webgl2 {
+		return p.gl.callResult("getQueryParameter", q, pname)
+	}
+	return p.ext.Call("getQueryObjectEXT", q, pname)
+}
+
+func (p *GPUProfiler) deleteQuery(q js.Value) {
+	if p.webgl2 {
+		p.gl.callResult("deleteQuery", q)
+	} else {
+		p.ext.Call("deleteQueryEXT", q)
+	}
+}
+
+// Begin starts timing the commands that follow under name. Timer queries
+// can not be nested, so any scope still open is ended first.
+func (p *GPUProfiler) Begin(name string) {
+	if p.active != nil {
+		p.End()
+	}
+	q := gpuQuery{name: name, query: p.createQuery()}
+	p.beginQuery(q.query)
+	p.active = &q
+}
+
+// End stops timing the open scope. It does nothing if no scope is open.
+func (p *GPUProfiler) End() {
+	if p.active == nil {
+		return
+	}
+	p.endQuery()
+	p.pending = append(p.pending, *p.active)
+	p.active = nil
+	if len(p.pending) > maxPendingGPUQueries {
+		p.free = append(p.free, p.pending[0].query)
+		p.pending = p.pending[1:]
+	}
+}
+
+// Scope times the commands issued by fn under name.
+func (p *GPUProfiler) Scope(name string, fn func()) {
+	p.Begin(name)
+	fn()
+	p.End()
+}
+
+// Poll collects the results of the queries that have finished, and
+// returns how many there were. Call it once a frame, outside of any scope.
+func (p *GPUProfiler) Poll() int {
+	if p.gl.callResult("getParameter", GPU_DISJOINT_EXT).Bool() {
+		// Every result in flight is unreliable.
+		for _, q := range p.pending {
+			p.free = append(p.free, q.query)
+		}
+		p.pending = p.pending[:0]
+		return 0
+	}
+	// Queries finish in the order they were issued.
+	n := 0
+	for ; n < len(p.pending); n++ {
+		q := p.pending[n]
+		if !p.queryResult(q.query, QUERY_RESULT_AVAILABLE_EXT).Bool() {
+			break
+		}
+		ns := p.queryResult(q.query, QUERY_RESULT_EXT).Float()
+		p.scope(q.name).add(time.Duration(ns))
+		p.free = append(p.free, q.query)
+	}
+	p.pending = append(p.pending[:0], p.pending[n:]...)
+	return n
+}
+
+func (p *GPUProfiler) scope(name string) *gpuScope {
+	s := p.scopes[name]
+	if s == nil {
+		window := p.Window
+		if window < 1 {
+			window = 1
+		}
+		s = &gpuScope{samples: make([]time.Duration, window)}
+		p.scopes[name] = s
+	}
+	return s
+}
+
+// Returns the timing of the named scope, and whether it has been measured.
+func (p *GPUProfiler) Timing(name string) (GPUTiming, bool) {
+	s := p.scopes[name]
+	if s == nil || s.count == 0 {
+		return GPUTiming{}, false
+	}
+	return GPUTiming{Name: name, Last: s.last, Average: s.average(), Samples: s.count}, true
+}
+
+// Returns the timings of every scope measured so far, sorted by name.
+func (p *GPUProfiler) Timings() []GPUTiming {
+	timings := make([]GPUTiming, 0, len(p.scopes))
+	for name := range p.scopes {
+		if t, ok := p.Timing(name); ok {
+			timings = append(timings, t)
+		}
+	}
+	sort.Slice(timings, func(i, j int) bool { return timings[i].Name < timings[j].Name })
+	return timings
+}
+
+// Reset forgets every measurement, keeping the queries in flight.
+func (p *GPUProfiler) Reset() {
+	p.scopes = make(map[string]*gpuScope)
+}
+
+// Delete deletes the profiler's queries. It can not be used afterwards.
+func (p *GPUProfiler) Delete() {
+	if p.gl == nil {
+		return
+	}
+	p.End()
+	for _, q := range p.pending {
+		p.deleteQuery(q.query)
+	}
+	for _, q := range p.free {
+		p.deleteQuery(q)
+	}
+	p.pending, p.free, p.gl = nil, nil, nil
+}
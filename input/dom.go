@@ -0,0 +1,280 @@
+// +build wasm
+
+package input
+
+import (
+	"errors"
+	"syscall/js"
+
+	"github.com/justinclift/webgl"
+)
+
+// Pixels per line, for wheel events that scroll by lines.
+const wheelLinePixels = 16
+
+// domBinding holds the event listeners of an Input.
+type domBinding struct {
+	canvas    js.Value
+	listeners []listener
+}
+
+type listener struct {
+	target js.Value
+	name   string
+	fn     js.Func
+}
+
+// New starts listening for input on the canvas of a context. Pointer,
+// wheel and touch events are taken from the canvas, keyboard events from
+// the whole window. Call Close to stop listening.
+func New(c *webgl.Context) (*Input, error) {
+	return NewForCanvas(c.Canvas)
+}
+
+// NewForCanvas starts listening for input on a canvas element. An
+// OffscreenCanvas gets no input events, so it is rejected; forward the
+// events of the page's canvas to the worker instead.
+func NewForCanvas(canvas js.Value) (*Input, error) {
+	if canvas.IsUndefined() || canvas.IsNull() {
+		return nil, errors.New("input: canvas is not an element on the page")
+	}
+	offscreen := js.Global().Get("OffscreenCanvas")
+	if offscreen.Type() == js.TypeFunction && canvas.InstanceOf(offscreen) {
+		return nil, errors.New("input: an OffscreenCanvas has no input events")
+	}
+	if canvas.Get("addEventListener").Type() != js.TypeFunction || canvas.Get("style").Type() != js.TypeObject {
+		return nil, errors.New("input: canvas is not an element on the page")
+	}
+	in := newInput()
+	in.dom.canvas = canvas
+	window := js.Global()
+	document := window.Get("document")
+
+	// Stop the browser from scrolling or zooming on touch and from
+	// showing the context menu, so drags reach the application.
+	canvas.Get("style").Set("touchAction", "none")
+	in.listen(canvas, "contextmenu", true, func(e js.Value) {})
+
+	pointerEvents := !window.Get("PointerEvent").IsUndefined()
+	if pointerEvents {
+		in.listen(canvas, "pointerdown", false, func(e js.Value) {
+			canvas.Call("setPointerCapture", e.Get("pointerId"))
+			in.handle(in.pointerEvent(PointerDown, e))
+		})
+		in.listen(canvas, "pointerup", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerUp, e))
+		})
+		in.listen(canvas, "pointermove", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerMove, e))
+		})
+		in.listen(canvas, "pointercancel", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerCancel, e))
+		})
+	} else {
+		in.listen(canvas, "mousedown", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerDown, e))
+		})
+		in.listen(window, "mouseup", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerUp, e))
+		})
+		in.listen(window, "mousemove", false, func(e js.Value) {
+			in.handle(in.pointerEvent(PointerMove, e))
+		})
+	}
+	in.listen(canvas, "wheel", true, func(e js.Value) {
+		in.handle(in.wheelEvent(e))
+	})
+	in.listen(canvas, "touchstart", true, func(e js.Value) {
+		in.handle(in.touchEvent(TouchStart, e))
+	})
+	in.listen(canvas, "touchmove", true, func(e js.Value) {
+		in.handle(in.touchEvent(TouchMove, e))
+	})
+	in.listen(canvas, "touchend", true, func(e js.Value) {
+		in.handle(in.touchEvent(TouchEnd, e))
+	})
+	in.listen(canvas, "touchcancel", true, func(e js.Value) {
+		in.handle(in.touchEvent(TouchCancel, e))
+	})
+	in.listen(window, "keydown", false, func(e js.Value) {
+		in.handle(keyEvent(KeyDown, e))
+	})
+	in.listen(window, "keyup", false, func(e js.Value) {
+		in.handle(keyEvent(KeyUp, e))
+	})
+	in.listen(window, "blur", false, func(e js.Value) {
+		in.releaseAll()
+	})
+	if !document.IsUndefined() {
+		in.listen(document, "pointerlockchange", false, func(e js.Value) {
+			in.locked = document.Get("pointerLockElement").Equal(canvas)
+		})
+	}
+	return in, nil
+}
+
+// listen adds an event listener that is removed by Close. If
+// preventDefault is true, the browser's default action is cancelled.
+func (in *Input) listen(target js.Value, name string, preventDefault bool, fn func(e js.Value)) {
+	f := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if preventDefault {
+			args[0].Call("preventDefault")
+		}
+		fn(args[0])
+		return nil
+	})
+	// Listeners that cancel the default action can not be passive.
+	opts := map[string]interface{}{"passive": !preventDefault}
+	target.Call("addEventListener", name, f, opts)
+	in.dom.listeners = append(in.dom.listeners, listener{target, name, f})
+}
+
+// Close stops listening for input and exits pointer lock.
+func (in *Input) Close() {
+	in.ExitPointerLock()
+	for _, l := range in.dom.listeners {
+		l.target.Call("removeEventListener", l.name, l.fn)
+		l.fn.Release()
+	}
+	in.dom.listeners = nil
+}
+
+// RequestPointerLock hides the pointer and locks it to the canvas, so
+// pointer events keep reporting movement in DX and DY however far the
+// pointer moves. Browsers only allow this while handling a user gesture,
+// such as a click.
+func (in *Input) RequestPointerLock() {
+	if !in.dom.canvas.Get("requestPointerLock").IsUndefined() {
+		in.dom.canvas.Call("requestPointerLock")
+	}
+}
+
+// ExitPointerLock releases a pointer locked with RequestPointerLock.
+func (in *Input) ExitPointerLock() {
+	document := js.Global().Get("document")
+	if in.locked && !document.IsUndefined() {
+		document.Call("exitPointerLock")
+	}
+}
+
+// scale returns the factors that turn CSS pixels into canvas pixels, and
+// the position of the canvas on the page.
+func (in *Input) scale() (sx, sy, left, top float64) {
+	canvas := in.dom.canvas
+	rect := canvas.Call("getBoundingClientRect")
+	width, height := rect.Get("width").Float(), rect.Get("height").Float()
+	sx, sy = 1, 1
+	if width > 0 && height > 0 {
+		sx = canvas.Get("width").Float() / width
+		sy = canvas.Get("height").Float() / height
+	}
+	return sx, sy, rect.Get("left").Float(), rect.Get("top").Float()
+}
+
+func modifiers(e js.Value) Modifiers {
+	var m Modifiers
+	if e.Get("shiftKey").Truthy() {
+		m |= ModShift
+	}
+	if e.Get("ctrlKey").Truthy() {
+		m |= ModCtrl
+	}
+	if e.Get("altKey").Truthy() {
+		m |= ModAlt
+	}
+	if e.Get("metaKey").Truthy() {
+		m |= ModMeta
+	}
+	return m
+}
+
+// Returns the number property of a JS object, or 0 if it is missing.
+func number(v js.Value, name string) float64 {
+	p := v.Get(name)
+	if p.Type() != js.TypeNumber {
+		return 0
+	}
+	return p.Float()
+}
+
+// pointerEvent converts a PointerEvent, or a MouseEvent where pointer
+// events are not supported.
+func (in *Input) pointerEvent(typ EventType, e js.Value) Event {
+	sx, sy, left, top := in.scale()
+	ev := Event{
+		Type:        typ,
+		Time:        number(e, "timeStamp"),
+		X:           (number(e, "clientX") - left) * sx,
+		Y:           (number(e, "clientY") - top) * sy,
+		DX:          number(e, "movementX") * sx,
+		DY:          number(e, "movementY") * sy,
+		Button:      int(number(e, "button")),
+		Buttons:     int(number(e, "buttons")),
+		PointerID:   int(number(e, "pointerId")),
+		PointerType: "mouse",
+		Mods:        modifiers(e),
+	}
+	if t := e.Get("pointerType"); t.Type() == js.TypeString && t.String() != "" {
+		ev.PointerType = t.String()
+	}
+	// DOM buttons number the right button 2 but give it bit 1 in
+	// buttons; swap them so bit n is button n.
+	b := ev.Buttons
+	ev.Buttons = b&^6 | (b&2)<<1 | (b&4)>>1
+	return ev
+}
+
+func (in *Input) wheelEvent(e js.Value) Event {
+	sx, sy, left, top := in.scale()
+	dx, dy := number(e, "deltaX"), number(e, "deltaY")
+	switch int(number(e, "deltaMode")) {
+	case 1:
+		dx, dy = dx*wheelLinePixels, dy*wheelLinePixels
+	case 2:
+		rect := in.dom.canvas.Call("getBoundingClientRect")
+		dx, dy = dx*rect.Get("width").Float(), dy*rect.Get("height").Float()
+	}
+	return Event{
+		Type:   Wheel,
+		Time:   number(e, "timeStamp"),
+		X:      (number(e, "clientX") - left) * sx,
+		Y:      (number(e, "clientY") - top) * sy,
+		WheelX: dx * sx,
+		WheelY: dy * sy,
+		Mods:   modifiers(e),
+	}
+}
+
+func (in *Input) touchEvent(typ EventType, e js.Value) Event {
+	sx, sy, left, top := in.scale()
+	changed := e.Get("changedTouches")
+	ev := Event{
+		Type:    typ,
+		Time:    number(e, "timeStamp"),
+		Mods:    modifiers(e),
+		Touches: make([]Touch, changed.Length()),
+	}
+	for i := range ev.Touches {
+		t := changed.Index(i)
+		ev.Touches[i] = Touch{
+			ID: int(number(t, "identifier")),
+			X:  (number(t, "clientX") - left) * sx,
+			Y:  (number(t, "clientY") - top) * sy,
+		}
+	}
+	if len(ev.Touches) > 0 {
+		ev.X, ev.Y = ev.Touches[0].X, ev.Touches[0].Y
+	}
+	return ev
+}
+
+func keyEvent(typ EventType, e js.Value) Event {
+	return Event{
+		Type:   typ,
+		Time:   number(e, "timeStamp"),
+		Key:    e.Get("key").String(),
+		Code:   e.Get("code").String(),
+		Repeat: e.Get("repeat").Truthy(),
+		Mods:   modifiers(e),
+	}
+}
@@ -0,0 +1,221 @@
+// Package input turns the pointer, mouse, wheel, keyboard and touch events
+// of a WebGL canvas into Go events.
+//
+// Positions are in canvas pixels: the pixels of the drawing buffer, with
+// the origin at the top left, so they match the viewport whatever the
+// device pixel ratio or CSS size of the canvas. Input can be read as it
+// is now, with IsKeyDown and friends, or as the queue of events that led
+// there, with Events.
+package input
+
+// EventType is the kind of an Event.
+type EventType int
+
+const (
+	PointerDown EventType = iota
+	PointerUp
+	PointerMove
+	PointerCancel
+	Wheel
+	KeyDown
+	KeyUp
+	TouchStart
+	TouchMove
+	TouchEnd
+	TouchCancel
+)
+
+// Mouse buttons, as in Event.Button.
+const (
+	ButtonLeft   = 0
+	ButtonMiddle = 1
+	ButtonRight  = 2
+)
+
+// Modifiers is a set of modifier keys.
+type Modifiers int
+
+const (
+	ModShift Modifiers = 1 << iota
+	ModCtrl
+	ModAlt
+	ModMeta
+)
+
+// Touch is a single touch point.
+type Touch struct {
+	ID int
+	X  float64
+	Y  float64
+}
+
+// Event is an input event.
+type Event struct {
+	Type EventType
+
+	// Time is the time stamp of the event in milliseconds.
+	Time float64
+
+	// X and Y are the pointer position in canvas pixels.
+	X float64
+	Y float64
+
+	// DX and DY are the movement since the previous pointer event in
+	// canvas pixels. Unlike X and Y they keep changing while the pointer
+	// is locked.
+	DX float64
+	DY float64
+
+	// Button is the button that changed for PointerDown and PointerUp, and
+	// Buttons the bit set of buttons held, with bit n for button n.
+	Button  int
+	Buttons int
+
+	// PointerID and PointerType identify the pointer, such as "mouse",
+	// "pen" or "touch".
+	PointerID   int
+	PointerType string
+
+	// WheelX and WheelY are the scroll amounts of a Wheel event in canvas
+	// pixels.
+	WheelX float64
+	WheelY float64
+
+	// Key is the value of the key, such as "a" or "Enter", and Code the
+	// layout independent name of the physical key, such as "KeyA".
+	Key    string
+	Code   string
+	Repeat bool
+
+	Mods Modifiers
+
+	// Touches holds the touch points that changed, for touch events.
+	Touches []Touch
+}
+
+// DefaultQueueSize is the number of events an Input keeps before it starts
+// dropping the oldest ones.
+const DefaultQueueSize = 256
+
+// Input holds the input state of a canvas and the events not read yet.
+type Input struct {
+	// QueueSize is the number of events kept for Events. Once it is
+	// reached, the oldest events are dropped. Zero means no queue.
+	QueueSize int
+
+	keys    map[string]bool
+	buttons int
+	x, y    float64
+	dx, dy  float64
+	wheelX  float64
+	wheelY  float64
+	touches map[int]Touch
+	events  []Event
+	locked  bool
+
+	dom domBinding
+}
+
+func newInput() *Input {
+	return &Input{
+		QueueSize: DefaultQueueSize,
+		keys:      make(map[string]bool),
+		touches:   make(map[int]Touch),
+	}
+}
+
+// handle updates the state for an event and queues it.
+func (in *Input) handle(e Event) {
+	switch e.Type {
+	case PointerDown, PointerUp, PointerMove:
+		in.x, in.y = e.X, e.Y
+		in.dx += e.DX
+		in.dy += e.DY
+		in.buttons = e.Buttons
+	case PointerCancel:
+		in.buttons = 0
+	case Wheel:
+		in.wheelX += e.WheelX
+		in.wheelY += e.WheelY
+	case KeyDown:
+		in.keys[e.Code] = true
+	case KeyUp:
+		delete(in.keys, e.Code)
+	case TouchStart, TouchMove:
+		for _, t := range e.Touches {
+			in.touches[t.ID] = t
+		}
+	case TouchEnd, TouchCancel:
+		for _, t := range e.Touches {
+			delete(in.touches, t.ID)
+		}
+	}
+	if in.QueueSize <= 0 {
+		return
+	}
+	if len(in.events) >= in.QueueSize {
+		n := copy(in.events, in.events[len(in.events)-in.QueueSize+1:])
+		in.events = in.events[:n]
+	}
+	in.events = append(in.events, e)
+}
+
+// releaseAll lets go of every key and button, such as when the page loses
+// focus and the matching up events would never arrive.
+func (in *Input) releaseAll() {
+	for code := range in.keys {
+		in.handle(Event{Type: KeyUp, Code: code})
+	}
+	in.buttons = 0
+}
+
+// Returns whether the key with the given code, such as "KeyW" or
+// "ArrowUp", is held down.
+func (in *Input) IsKeyDown(code string) bool {
+	return in.keys[code]
+}
+
+// Returns whether a mouse button is held down.
+func (in *Input) IsButtonDown(button int) bool {
+	return in.buttons&(1<<uint(button)) != 0
+}
+
+// Returns the last known pointer position in canvas pixels.
+func (in *Input) Pointer() (x, y float64) {
+	return in.x, in.y
+}
+
+// Returns the pointer movement since the last call, in canvas pixels.
+func (in *Input) TakeMovement() (dx, dy float64) {
+	dx, dy = in.dx, in.dy
+	in.dx, in.dy = 0, 0
+	return dx, dy
+}
+
+// Returns the wheel scrolling since the last call, in canvas pixels.
+func (in *Input) TakeWheel() (x, y float64) {
+	x, y = in.wheelX, in.wheelY
+	in.wheelX, in.wheelY = 0, 0
+	return x, y
+}
+
+// Returns the touch points that are down, in no particular order.
+func (in *Input) Touches() []Touch {
+	touches := make([]Touch, 0, len(in.touches))
+	for _, t := range in.touches {
+		touches = append(touches, t)
+	}
+	return touches
+}
+
+// Events returns the queued events, oldest first, and empties the queue.
+func (in *Input) Events() []Event {
+	events := in.events
+	in.events = nil
+	return events
+}
+
+// Returns whether the pointer is locked to the canvas.
+func (in *Input) PointerLocked() bool {
+	return in.locked
+}
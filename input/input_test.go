@@ -0,0 +1,162 @@
+package input
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	in := newInput()
+	in.handle(Event{Type: KeyDown, Key: "w", Code: "KeyW"})
+	in.handle(Event{Type: KeyDown, Key: "Shift", Code: "ShiftLeft", Mods: ModShift})
+	in.handle(Event{Type: KeyDown, Key: "W", Code: "KeyW", Repeat: true, Mods: ModShift})
+	if !in.IsKeyDown("KeyW") || !in.IsKeyDown("ShiftLeft") {
+		t.Errorf("KeyW, ShiftLeft down = %v, %v; want true, true", in.IsKeyDown("KeyW"), in.IsKeyDown("ShiftLeft"))
+	}
+	if in.IsKeyDown("w") {
+		t.Error("keys are looked up by key value; want by code")
+	}
+	in.handle(Event{Type: KeyUp, Key: "W", Code: "KeyW"})
+	if in.IsKeyDown("KeyW") {
+		t.Error("KeyW is still down after KeyUp")
+	}
+	if !in.IsKeyDown("ShiftLeft") {
+		t.Error("ShiftLeft was released by the KeyUp of KeyW")
+	}
+	// A KeyUp without a KeyDown, such as for a key held while the page
+	// got focus, is harmless.
+	in.handle(Event{Type: KeyUp, Code: "KeyQ"})
+	if in.IsKeyDown("KeyQ") {
+		t.Error("KeyQ is down after a lone KeyUp")
+	}
+}
+
+func TestReleaseAll(t *testing.T) {
+	in := newInput()
+	in.handle(Event{Type: KeyDown, Code: "KeyA"})
+	in.handle(Event{Type: KeyDown, Code: "KeyB"})
+	in.handle(Event{Type: PointerDown, Button: ButtonLeft, Buttons: 1})
+	in.Events()
+
+	in.releaseAll()
+	if in.IsKeyDown("KeyA") || in.IsKeyDown("KeyB") || in.IsButtonDown(ButtonLeft) {
+		t.Error("keys or buttons are still down after releaseAll")
+	}
+	var codes []string
+	for _, e := range in.Events() {
+		if e.Type != KeyUp {
+			t.Errorf("releaseAll queued event type %d; want KeyUp", e.Type)
+		}
+		codes = append(codes, e.Code)
+	}
+	sort.Strings(codes)
+	if !reflect.DeepEqual(codes, []string{"KeyA", "KeyB"}) {
+		t.Errorf("releaseAll queued KeyUp for %v; want KeyA and KeyB", codes)
+	}
+}
+
+func TestPointer(t *testing.T) {
+	in := newInput()
+	in.handle(Event{Type: PointerMove, X: 10, Y: 20, DX: 1, DY: 2})
+	in.handle(Event{Type: PointerDown, X: 11, Y: 22, DX: 1, DY: 2, Button: ButtonRight, Buttons: 1 << ButtonRight})
+	if x, y := in.Pointer(); x != 11 || y != 22 {
+		t.Errorf("Pointer() = %g, %g; want 11, 22", x, y)
+	}
+	if !in.IsButtonDown(ButtonRight) || in.IsButtonDown(ButtonLeft) {
+		t.Errorf("right, left down = %v, %v; want true, false", in.IsButtonDown(ButtonRight), in.IsButtonDown(ButtonLeft))
+	}
+	if dx, dy := in.TakeMovement(); dx != 2 || dy != 4 {
+		t.Errorf("TakeMovement() = %g, %g; want 2, 4", dx, dy)
+	}
+	if dx, dy := in.TakeMovement(); dx != 0 || dy != 0 {
+		t.Errorf("second TakeMovement() = %g, %g; want 0, 0", dx, dy)
+	}
+	in.handle(Event{Type: PointerCancel})
+	if in.IsButtonDown(ButtonRight) {
+		t.Error("right button is still down after PointerCancel")
+	}
+}
+
+func TestWheel(t *testing.T) {
+	in := newInput()
+	in.handle(Event{Type: Wheel, WheelY: 100})
+	in.handle(Event{Type: Wheel, WheelX: -5, WheelY: 50})
+	in.handle(Event{Type: Wheel, WheelY: -30})
+	if x, y := in.TakeWheel(); x != -5 || y != 120 {
+		t.Errorf("TakeWheel() = %g, %g; want -5, 120", x, y)
+	}
+	if x, y := in.TakeWheel(); x != 0 || y != 0 {
+		t.Errorf("second TakeWheel() = %g, %g; want 0, 0", x, y)
+	}
+}
+
+func TestTouches(t *testing.T) {
+	in := newInput()
+	in.handle(Event{Type: TouchStart, Touches: []Touch{{ID: 1, X: 1, Y: 1}, {ID: 2, X: 5, Y: 5}}})
+	in.handle(Event{Type: TouchMove, Touches: []Touch{{ID: 2, X: 6, Y: 7}}})
+	in.handle(Event{Type: TouchStart, Touches: []Touch{{ID: 3, X: 9, Y: 9}}})
+	in.handle(Event{Type: TouchEnd, Touches: []Touch{{ID: 1}}})
+	in.handle(Event{Type: TouchCancel, Touches: []Touch{{ID: 3}}})
+
+	if got, want := in.Touches(), []Touch{{ID: 2, X: 6, Y: 7}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Touches() = %v; want %v", got, want)
+	}
+	var types []EventType
+	for _, e := range in.Events() {
+		types = append(types, e.Type)
+	}
+	if want := []EventType{TouchStart, TouchMove, TouchStart, TouchEnd, TouchCancel}; !reflect.DeepEqual(types, want) {
+		t.Errorf("queued %v; want %v", types, want)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	in := newInput()
+	events := []Event{
+		{Type: PointerDown, X: 1, Button: ButtonLeft, Buttons: 1},
+		{Type: PointerMove, X: 2, Buttons: 1},
+		{Type: KeyDown, Code: "Space"},
+		{Type: Wheel, WheelY: 3},
+		{Type: PointerUp, X: 3, Button: ButtonLeft},
+		{Type: KeyUp, Code: "Space"},
+	}
+	for _, e := range events {
+		in.handle(e)
+	}
+	if got := in.Events(); !reflect.DeepEqual(got, events) {
+		t.Errorf("Events() = %v; want %v", got, events)
+	}
+	if got := in.Events(); len(got) != 0 {
+		t.Errorf("Events() after draining = %v; want none", got)
+	}
+
+	in.handle(Event{Type: KeyDown, Code: "KeyA"})
+	if got := in.Events(); len(got) != 1 || got[0].Code != "KeyA" {
+		t.Errorf("Events() after refilling = %v; want the KeyA event", got)
+	}
+}
+
+func TestQueueSize(t *testing.T) {
+	in := newInput()
+	in.QueueSize = 3
+	for i := 0; i < 5; i++ {
+		in.handle(Event{Type: PointerMove, X: float64(i)})
+	}
+	var xs []float64
+	for _, e := range in.Events() {
+		xs = append(xs, e.X)
+	}
+	if want := []float64{2, 3, 4}; !reflect.DeepEqual(xs, want) {
+		t.Errorf("queue holds the moves to %v; want the newest, %v", xs, want)
+	}
+
+	in.QueueSize = 0
+	in.handle(Event{Type: KeyDown, Code: "KeyA"})
+	if got := in.Events(); len(got) != 0 {
+		t.Errorf("Events() with no queue = %v; want none", got)
+	}
+	if !in.IsKeyDown("KeyA") {
+		t.Error("state is not updated when there is no queue")
+	}
+}
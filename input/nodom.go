@@ -0,0 +1,6 @@
+// +build !wasm
+
+package input
+
+// Outside of the browser there are no events to listen to.
+type domBinding struct{}
@@ -0,0 +1,188 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// ANGLE_instanced_arrays
+const VERTEX_ATTRIB_ARRAY_DIVISOR_ANGLE = 0x88FE
+
+// InstancedArrays is the ANGLE_instanced_arrays extension, which draws
+// many instances of the same geometry in one call. Its methods run any
+// batched commands first, since they are not part of the batch.
+type InstancedArrays struct {
+	ext js.Value
+	gl  *Context
+}
+
+// Returns the ANGLE_instanced_arrays extension, or an *ExtensionError if
+// it is not supported.
+func (c *Context) InstancedArrays() (*InstancedArrays, error) {
+	ext, err := c.extension("ANGLE_instanced_arrays")
+	if err != nil {
+		return nil, err
+	}
+	return &InstancedArrays{ext: ext, gl: c}, nil
+}
+
+// Draws instances copies of the vertices from first to first+count.
+func (a *InstancedArrays) DrawArraysInstanced(mode, first, count, instances int) {
+	a.gl.FlushBatch()
+	a.ext.Call("drawArraysInstancedANGLE", mode, first, count, instances)
+}
+
+// Draws instances copies of the indexed vertices in the bound element
+// array buffer, starting offset bytes in.
+func (a *InstancedArrays) DrawElementsInstanced(mode, count, typ, offset, instances int) {
+	a.gl.FlushBatch()
+	a.ext.Call("drawElementsInstancedANGLE", mode, count, typ, offset, instances)
+}
+
+// Sets how many instances are drawn with each value of a vertex attribute.
+// Zero, the default, advances the attribute per vertex instead.
+func (a *InstancedArrays) VertexAttribDivisor(index, divisor int) {
+	if a.gl.vao != nil {
+		a.gl.vao.divisor(index, divisor)
+	}
+	a.gl.FlushBatch()
+	a.ext.Call("vertexAttribDivisorANGLE", index, divisor)
+}
+
+// InstanceAttribute is a per-instance vertex attribute of an
+// InstancedMesh.
+type InstanceAttribute struct {
+	// Location is the attribute location in the shader program. Attributes
+	// with more than 4 components, such as a mat4, take up consecutive
+	// locations of 4 components each.
+	Location int
+
+	// Size is the number of float components.
+	Size int
+
+	// Divisor is how many instances share each value. Zero is taken as 1.
+	Divisor int
+}
+
+// InstancedMesh holds per-instance attributes, such as positions and
+// colors, in one interleaved buffer, and draws the bound geometry once for
+// each instance.
+type InstancedMesh struct {
+	Buffer     *js.Value
+	Attributes []InstanceAttribute
+
+	// Stride is the number of floats per instance.
+	Stride int
+
+	// Count is the number of instances.
+	Count int
+
+	capacity int
+	ext      *InstancedArrays
+	gl       *Context
+}
+
+// NewInstancedMesh creates an empty instanced mesh with the given
+// attributes, which are stored interleaved in the order given. It returns
+// an *ExtensionError if ANGLE_instanced_arrays is not supported.
+func NewInstancedMesh(c *Context, attrs []InstanceAttribute) (*InstancedMesh, error) {
+	ext, err := c.InstancedArrays()
+	if err != nil {
+		return nil, err
+	}
+	m := &InstancedMesh{
+		Attributes: attrs,
+		ext:        ext,
+		gl:         c,
+	}
+	for _, a := range attrs {
+		if a.Size <= 0 {
+			return nil, errors.New("instance attribute size must be positive")
+		}
+		m.Stride += a.Size
+	}
+	m.Buffer = c.CreateBuffer()
+	return m, nil
+}
+
+// SetInstances uploads the attributes of every instance, interleaved with
+// Stride floats per instance. The buffer only grows, so updating the
+// instances every frame does not reallocate it.
+func (m *InstancedMesh) SetInstances(data []float32) error {
+	if m.Stride == 0 || len(data)%m.Stride != 0 {
+		return errors.New("instance data is not a whole number of instances")
+	}
+	c := m.gl
+	c.BindBuffer(ARRAY_BUFFER, m.Buffer)
+	if len(data) > m.capacity {
+		c.BufferData(ARRAY_BUFFER, SliceToTypedArray(data), DYNAMIC_DRAW)
+		m.capacity = len(data)
+	} else if len(data) > 0 {
+		c.BufferSubData(ARRAY_BUFFER, 0, SliceToTypedArray(data))
+	}
+	m.Count = len(data) / m.Stride
+	return nil
+}
+
+// Bind points the instance attributes at the instance buffer. Per-vertex
+// attributes and any index buffer are left to the caller.
+func (m *InstancedMesh) Bind() {
+	c := m.gl
+	c.BindBuffer(ARRAY_BUFFER, m.Buffer)
+	offset := 0
+	for _, a := range m.Attributes {
+		divisor := a.Divisor
+		if divisor == 0 {
+			divisor = 1
+		}
+		for i := 0; i < a.Size; i += 4 {
+			size := a.Size - i
+			if size > 4 {
+				size = 4
+			}
+			loc := a.Location + i/4
+			c.EnableVertexAttribArray(loc)
+			c.VertexAttribPointer(loc, size, FLOAT, false, m.Stride*4, (offset+i)*4)
+			m.ext.VertexAttribDivisor(loc, divisor)
+		}
+		offset += a.Size
+	}
+}
+
+// Unbind resets the divisors of the instance attributes and disables
+// them, so later draws that do not use instancing are unaffected.
+func (m *InstancedMesh) Unbind() {
+	for _, a := range m.Attributes {
+		for i := 0; i < a.Size; i += 4 {
+			loc := a.Location + i/4
+			m.ext.VertexAttribDivisor(loc, 0)
+			m.gl.DisableVertexAttribArray(loc)
+		}
+	}
+}
+
+// DrawArrays draws count vertices of the bound geometry for every
+// instance. The mesh must be bound.
+func (m *InstancedMesh) DrawArrays(mode, first, count int) {
+	if m.Count > 0 {
+		m.ext.DrawArraysInstanced(mode, first, count, m.Count)
+	}
+}
+
+// DrawElements draws count indices of the bound geometry for every
+// instance. The mesh must be bound.
+func (m *InstancedMesh) DrawElements(mode, count, typ, offset int) {
+	if m.Count > 0 {
+		m.ext.DrawElementsInstanced(mode, count, typ, offset, m.Count)
+	}
+}
+
+// Delete deletes the instance buffer.
+func (m *InstancedMesh) Delete() {
+	if m.Buffer != nil {
+		m.gl.DeleteBuffer(m.Buffer)
+		m.Buffer = nil
+	}
+}
@@ -0,0 +1,170 @@
+// +build wasm
+
+package webgl
+
+import (
+	"context"
+	"errors"
+	"syscall/js"
+	"time"
+)
+
+// When catching up in RunFixedLoop, at most this many steps are run per
+// frame. Time beyond that is dropped rather than making every following
+// frame slower.
+const maxFixedSteps = 8
+
+// FrameTime is the timing of a frame of RunLoopTimed.
+type FrameTime struct {
+	// Delta is the time since the previous frame. It is zero for the first
+	// frame, and for the first frame after the page was hidden.
+	Delta time.Duration
+
+	// Elapsed is the sum of the deltas so far, which leaves out the time
+	// the page spent hidden.
+	Elapsed time.Duration
+
+	// Frame counts the frames, starting at 0.
+	Frame int
+}
+
+// RunLoop calls frame once per animation frame with the time since the
+// previous frame, until frame returns false or ctx is done. It blocks
+// until then, and returns ctx.Err() if ctx ended the loop. See
+// RunLoopTimed.
+func RunLoop(ctx context.Context, frame func(dt time.Duration) bool) error {
+	return RunLoopTimed(ctx, func(t FrameTime) bool {
+		return frame(t.Delta)
+	})
+}
+
+// RunLoopTimed calls frame once per animation frame until frame returns
+// false or ctx is done. It blocks until then, and returns ctx.Err() if ctx
+// ended the loop.
+//
+// Frames are requested with requestAnimationFrame, or with setTimeout
+// where that is missing. While the page is hidden no frames are run, and
+// the time it was hidden is not counted. frame runs inside the browser's
+// callback, so it must not block, such as by waiting on a promise.
+func RunLoopTimed(ctx context.Context, frame func(t FrameTime) bool) error {
+	global := js.Global()
+	request, cancel := "requestAnimationFrame", "cancelAnimationFrame"
+	if isNullish(global.Get(request)) {
+		request, cancel = "setTimeout", "clearTimeout"
+	}
+	performance := global.Get("performance")
+	document := global.Get("document")
+
+	done := make(chan error, 1)
+	var (
+		t       FrameTime
+		last    = -1.0
+		pending js.Value
+		stopped bool
+		tick    js.Func
+	)
+	finish := func(err error) {
+		if !stopped {
+			stopped = true
+			done <- err
+		}
+	}
+	schedule := func() {
+		if !stopped && pending.IsUndefined() {
+			if request == "setTimeout" {
+				pending = global.Call(request, tick, 16)
+			} else {
+				pending = global.Call(request, tick)
+			}
+		}
+	}
+	hidden := func() bool {
+		return !isNullish(document) && document.Get("hidden").Truthy()
+	}
+
+	tick = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		pending = js.Undefined()
+		if stopped || hidden() {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			finish(err)
+			return nil
+		}
+		var now float64
+		if len(args) > 0 && args[0].Type() == js.TypeNumber {
+			now = args[0].Float()
+		} else {
+			now = performance.Call("now").Float()
+		}
+		t.Delta = 0
+		if last >= 0 {
+			t.Delta = time.Duration((now - last) * float64(time.Millisecond))
+		}
+		last = now
+		t.Elapsed += t.Delta
+		if !frame(t) {
+			finish(nil)
+			return nil
+		}
+		t.Frame++
+		schedule()
+		return nil
+	})
+	defer tick.Release()
+
+	if !isNullish(document) {
+		visibility := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			if !hidden() {
+				last = -1
+				schedule()
+			}
+			return nil
+		})
+		document.Call("addEventListener", "visibilitychange", visibility)
+		defer func() {
+			document.Call("removeEventListener", "visibilitychange", visibility)
+			visibility.Release()
+		}()
+	}
+
+	schedule()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		stopped = true
+		err = ctx.Err()
+	}
+	if !pending.IsUndefined() {
+		global.Call(cancel, pending)
+		pending = js.Undefined()
+	}
+	return err
+}
+
+// RunFixedLoop runs update with a fixed time step, as many times per
+// animation frame as it takes to keep up with real time, and then render
+// once. alpha is how far between the last update and the next one the
+// frame is, from 0 to 1, for interpolating what is drawn. The loop ends
+// when update returns false or ctx is done, as with RunLoopTimed.
+func RunFixedLoop(ctx context.Context, step time.Duration, update func(step time.Duration) bool, render func(alpha float64)) error {
+	if step <= 0 {
+		return errors.New("time step must be positive")
+	}
+	var acc time.Duration
+	return RunLoopTimed(ctx, func(t FrameTime) bool {
+		acc += t.Delta
+		if acc > maxFixedSteps*step {
+			acc = maxFixedSteps * step
+		}
+		for acc >= step {
+			if !update(step) {
+				return false
+			}
+			acc -= step
+		}
+		render(float64(acc) / float64(step))
+		return true
+	})
+}
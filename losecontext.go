@@ -0,0 +1,219 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+	"sort"
+	"syscall/js"
+	"time"
+)
+
+// How long LossTest waits for the context to be lost or restored.
+const lossTestTimeout = 5 * time.Second
+
+// LoseContext is the WEBGL_lose_context extension, which simulates losing
+// and restoring the context, for testing how that is handled.
+type LoseContext struct {
+	ext js.Value
+	gl  *Context
+}
+
+// Returns the WEBGL_lose_context extension, or an *ExtensionError if it is
+// not supported.
+func (c *Context) LoseContext() (*LoseContext, error) {
+	ext, err := c.extension("WEBGL_lose_context")
+	if err != nil {
+		return nil, err
+	}
+	return &LoseContext{ext: ext, gl: c}, nil
+}
+
+// LoseContext loses the context, as if the GPU had been reset. The
+// webglcontextlost event follows shortly.
+func (l *LoseContext) LoseContext() {
+	l.gl.FlushBatch()
+	l.ext.Call("loseContext")
+}
+
+// RestoreContext restores a context lost by LoseContext. It only works if
+// the lost event was handled, which OnContextLost does.
+func (l *LoseContext) RestoreContext() {
+	l.ext.Call("restoreContext")
+}
+
+// contextLoss holds the context loss event listeners of a Context.
+type contextLoss struct {
+	lost       js.Func
+	restored   js.Func
+	onLost     map[int]func()
+	onRestored map[int]func()
+	nextID     int
+}
+
+// Listens for context loss events on the canvas, if that is not being done
+// already. The lost event is always cancelled, so that the context can be
+// restored, and everything cached about the context is forgotten when it
+// is.
+func (c *Context) watchContextLoss() *contextLoss {
+	if c.loss != nil {
+		return c.loss
+	}
+	l := &contextLoss{onLost: make(map[int]func()), onRestored: make(map[int]func())}
+	l.lost = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		args[0].Call("preventDefault")
+		for _, id := range sortedHandlerIDs(l.onLost) {
+			l.onLost[id]()
+		}
+		return nil
+	})
+	l.restored = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		// Every object, extension and piece of state is gone.
+		if c.state != nil {
+			c.state.reset()
+		}
+		c.exts = nil
+		c.vao = nil
+		for _, id := range sortedHandlerIDs(l.onRestored) {
+			l.onRestored[id]()
+		}
+		return nil
+	})
+	c.Canvas.Call("addEventListener", "webglcontextlost", l.lost)
+	c.Canvas.Call("addEventListener", "webglcontextrestored", l.restored)
+	c.loss = l
+	return l
+}
+
+// Returns the keys of handlers in the order they were added.
+func sortedHandlerIDs(handlers map[int]func()) []int {
+	ids := make([]int, 0, len(handlers))
+	for id := range handlers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// OnContextLost calls fn whenever the context is lost, and makes sure it
+// can be restored. fn runs inside the event handler, so it must not block.
+// Calling the returned function stops calling fn.
+func (c *Context) OnContextLost(fn func()) (remove func()) {
+	l := c.watchContextLoss()
+	id := l.nextID
+	l.nextID++
+	l.onLost[id] = fn
+	return func() { delete(l.onLost, id) }
+}
+
+// OnContextRestored calls fn whenever the context has been restored, by
+// which time every object has to be created again and every extension
+// enabled again. fn runs inside the event handler, so it must not block.
+// Calling the returned function stops calling fn.
+func (c *Context) OnContextRestored(fn func()) (remove func()) {
+	l := c.watchContextLoss()
+	id := l.nextID
+	l.nextID++
+	l.onRestored[id] = fn
+	return func() { delete(l.onRestored, id) }
+}
+
+// LossTest drives a context through a loss and restore, then checks that
+// the objects it tracks were all created again.
+type LossTest struct {
+	gl      *Context
+	names   []string
+	objects map[string]func() *js.Value
+}
+
+// Creates a LossTest, or returns an *ExtensionError if WEBGL_lose_context
+// is not supported.
+func NewLossTest(c *Context) (*LossTest, error) {
+	if _, err := c.LoseContext(); err != nil {
+		return nil, err
+	}
+	return &LossTest{gl: c, objects: make(map[string]func() *js.Value)}, nil
+}
+
+// Track adds an object to check under name. object returns the current
+// WebGL object, such as a texture or buffer, and is called before the
+// context is lost and after it is restored.
+func (t *LossTest) Track(name string, object func() *js.Value) {
+	if _, ok := t.objects[name]; !ok {
+		t.names = append(t.names, name)
+	}
+	t.objects[name] = object
+}
+
+// Run loses the context, restores it, and calls recreate, which may be nil
+// if OnContextRestored handlers do the work. It then returns the names of
+// the tracked objects that are missing or are still the object from before
+// the loss. Run blocks until the context has been restored, so it must not
+// be called from an event handler.
+func (t *LossTest) Run(recreate func() error) ([]string, error) {
+	c := t.gl
+	lc, err := c.LoseContext()
+	if err != nil {
+		return nil, err
+	}
+	before := make(map[string]js.Value, len(t.names))
+	for _, name := range t.names {
+		if obj := t.objects[name](); obj != nil {
+			before[name] = *obj
+		}
+	}
+
+	lost := make(chan struct{}, 1)
+	restored := make(chan struct{}, 1)
+	removeLost := c.OnContextLost(func() {
+		select {
+		case lost <- struct{}{}:
+		default:
+		}
+	})
+	defer removeLost()
+	removeRestored := c.OnContextRestored(func() {
+		select {
+		case restored <- struct{}{}:
+		default:
+		}
+	})
+	defer removeRestored()
+
+	lc.LoseContext()
+	if err := waitFor(lost); err != nil {
+		return nil, errors.New("context was not lost")
+	}
+	lc.RestoreContext()
+	if err := waitFor(restored); err != nil {
+		return nil, errors.New("context was not restored")
+	}
+	if recreate != nil {
+		if err := recreate(); err != nil {
+			return nil, err
+		}
+	}
+
+	var missing []string
+	for _, name := range t.names {
+		obj := t.objects[name]()
+		if obj == nil || isNullish(*obj) {
+			missing = append(missing, name)
+			continue
+		}
+		if old, ok := before[name]; ok && old.Equal(*obj) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// Waits for a signal on ch, giving up after lossTestTimeout.
+func waitFor(ch chan struct{}) error {
+	select {
+	case <-ch:
+		return nil
+	case <-time.After(lossTestTimeout):
+		return errors.New("timed out")
+	}
+}
@@ -0,0 +1,126 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+)
+
+// MultiDraw draws many ranges of vertices in one call with the
+// WEBGL_multi_draw extension. Where the extension is not supported, it
+// makes one draw call per range instead, which batched mode still turns
+// into a single call into JS, so callers only need one code path.
+//
+// Each method draws as many ranges as its shortest slice has elements.
+type MultiDraw struct {
+	ext js.Value
+	gl  *Context
+}
+
+// Returns a MultiDraw for the context, using WEBGL_multi_draw if it is
+// supported.
+func (c *Context) MultiDraw() *MultiDraw {
+	ext, _ := c.extension("WEBGL_multi_draw")
+	return &MultiDraw{ext: ext, gl: c}
+}
+
+// Returns whether WEBGL_multi_draw is used, rather than a loop of draw
+// calls.
+func (m *MultiDraw) Native() bool {
+	return !isNullish(m.ext)
+}
+
+// Returns the length of the shortest slice.
+func drawCount(lists ...[]int32) int {
+	n := len(lists[0])
+	for _, l := range lists[1:] {
+		if len(l) < n {
+			n = len(l)
+		}
+	}
+	return n
+}
+
+// Draws counts[i] vertices from firsts[i] for each i.
+func (m *MultiDraw) MultiDrawArrays(mode int, firsts, counts []int32) {
+	n := drawCount(firsts, counts)
+	if n == 0 {
+		return
+	}
+	if m.Native() {
+		m.gl.FlushBatch()
+		m.ext.Call("multiDrawArraysWEBGL", mode,
+			SliceToTypedArray(firsts), 0, SliceToTypedArray(counts), 0, n)
+		return
+	}
+	for i := 0; i < n; i++ {
+		m.gl.DrawArrays(mode, int(firsts[i]), int(counts[i]))
+	}
+}
+
+// Draws counts[i] indexed vertices from the bound element array buffer,
+// starting offsets[i] bytes in, for each i.
+func (m *MultiDraw) MultiDrawElements(mode int, counts []int32, typ int, offsets []int32) {
+	n := drawCount(counts, offsets)
+	if n == 0 {
+		return
+	}
+	if m.Native() {
+		m.gl.FlushBatch()
+		m.ext.Call("multiDrawElementsWEBGL", mode,
+			SliceToTypedArray(counts), 0, typ, SliceToTypedArray(offsets), 0, n)
+		return
+	}
+	for i := 0; i < n; i++ {
+		m.gl.DrawElements(mode, int(counts[i]), typ, int(offsets[i]))
+	}
+}
+
+// Draws instances[i] copies of counts[i] vertices from firsts[i] for each
+// i. Without WEBGL_multi_draw this needs ANGLE_instanced_arrays, and
+// returns an *ExtensionError if that is not supported either.
+func (m *MultiDraw) MultiDrawArraysInstanced(mode int, firsts, counts, instances []int32) error {
+	n := drawCount(firsts, counts, instances)
+	if m.Native() {
+		if n > 0 {
+			m.gl.FlushBatch()
+			m.ext.Call("multiDrawArraysInstancedWEBGL", mode,
+				SliceToTypedArray(firsts), 0, SliceToTypedArray(counts), 0,
+				SliceToTypedArray(instances), 0, n)
+		}
+		return nil
+	}
+	a, err := m.gl.InstancedArrays()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		a.DrawArraysInstanced(mode, int(firsts[i]), int(counts[i]), int(instances[i]))
+	}
+	return nil
+}
+
+// Draws instances[i] copies of counts[i] indexed vertices from the bound
+// element array buffer, starting offsets[i] bytes in, for each i. Without
+// WEBGL_multi_draw this needs ANGLE_instanced_arrays, and returns an
+// *ExtensionError if that is not supported either.
+func (m *MultiDraw) MultiDrawElementsInstanced(mode int, counts []int32, typ int, offsets, instances []int32) error {
+	n := drawCount(counts, offsets, instances)
+	if m.Native() {
+		if n > 0 {
+			m.gl.FlushBatch()
+			m.ext.Call("multiDrawElementsInstancedWEBGL", mode,
+				SliceToTypedArray(counts), 0, typ, SliceToTypedArray(offsets), 0,
+				SliceToTypedArray(instances), 0, n)
+		}
+		return nil
+	}
+	a, err := m.gl.InstancedArrays()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		a.DrawElementsInstanced(mode, int(counts[i]), typ, int(offsets[i]), int(instances[i]))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Material is a material from an MTL file. Texture maps are file names
+// relative to the MTL file, or empty.
+type Material struct {
+	Name      string
+	Ambient   [3]float32 // Ka
+	Diffuse   [3]float32 // Kd
+	Specular  [3]float32 // Ks
+	Emissive  [3]float32 // Ke
+	Shininess float32    // Ns
+	Opacity   float32    // d, or 1 - Tr
+
+	AmbientMap  string // map_Ka
+	DiffuseMap  string // map_Kd
+	SpecularMap string // map_Ks
+	EmissiveMap string // map_Ke
+	OpacityMap  string // map_d
+	BumpMap     string // map_Bump, bump or norm
+}
+
+// ParseMTL reads the materials of an MTL file, by name.
+func ParseMTL(data []byte) (map[string]*Material, error) {
+	mats := make(map[string]*Material)
+	var cur *Material
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		fail := func(msg string) error {
+			return errors.New("obj: mtl line " + strconv.Itoa(line) + ": " + msg)
+		}
+		key, args := fields[0], fields[1:]
+		if key == "newmtl" {
+			cur = &Material{
+				Name:    strings.Join(args, " "),
+				Diffuse: [3]float32{1, 1, 1},
+				Opacity: 1,
+			}
+			mats[cur.Name] = cur
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "ka":
+			err = parseColor(args, &cur.Ambient)
+		case "kd":
+			err = parseColor(args, &cur.Diffuse)
+		case "ks":
+			err = parseColor(args, &cur.Specular)
+		case "ke":
+			err = parseColor(args, &cur.Emissive)
+		case "ns":
+			cur.Shininess, err = parseFloat(args)
+		case "d":
+			cur.Opacity, err = parseFloat(args)
+		case "tr":
+			var tr float32
+			tr, err = parseFloat(args)
+			cur.Opacity = 1 - tr
+		case "map_ka":
+			cur.AmbientMap = mapName(args)
+		case "map_kd":
+			cur.DiffuseMap = mapName(args)
+		case "map_ks":
+			cur.SpecularMap = mapName(args)
+		case "map_ke":
+			cur.EmissiveMap = mapName(args)
+		case "map_d":
+			cur.OpacityMap = mapName(args)
+		case "map_bump", "bump", "norm":
+			cur.BumpMap = mapName(args)
+		}
+		if err != nil {
+			return nil, fail(err.Error())
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return mats, nil
+}
+
+// Parses an RGB color. A single value is used for all three channels.
+func parseColor(args []string, c *[3]float32) error {
+	v, err := parseFloats(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(v) < 3 {
+		v = []float32{v[0], v[0], v[0]}
+	}
+	copy(c[:], v)
+	return nil
+}
+
+func parseFloat(args []string) (float32, error) {
+	v, err := parseFloats(args, 1)
+	if err != nil {
+		return 0, err
+	}
+	return v[0], nil
+}
+
+// Returns the file name of a texture map statement, which comes after any
+// options such as "-bm 0.5".
+func mapName(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[len(args)-1]
+}
@@ -0,0 +1,271 @@
+// Package obj reads Wavefront OBJ models and their MTL material libraries.
+//
+// Parsing is pure Go. Faces are triangulated as fans, and the position,
+// texture coordinate and normal indices of each corner are merged into a
+// single indexed stream of interleaved vertices, ready to be uploaded with
+// Upload, which is only built for wasm.
+package obj
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Model is a parsed OBJ file.
+type Model struct {
+	// Vertices holds the interleaved vertices: a position, followed by a
+	// texture coordinate if HasTexCoords is true and a normal if
+	// HasNormals is true.
+	Vertices []float32
+
+	// Indices holds three vertex indices for each triangle.
+	Indices []uint32
+
+	HasTexCoords bool
+	HasNormals   bool
+
+	// Groups splits the triangles by object, group and material, in the
+	// order they appear in the file.
+	Groups []Group
+
+	// Materials holds the materials of the MTL files the model refers to,
+	// by name.
+	Materials map[string]*Material
+}
+
+// Group is a run of triangles that share a material.
+type Group struct {
+	// Name is the name of the object or group the triangles are part of.
+	Name     string
+	Material string
+
+	// First and Count are the range of indices the group covers.
+	First int
+	Count int
+}
+
+// Returns the number of floats each vertex takes up in Vertices.
+func (m *Model) Stride() int {
+	n := 3
+	if m.HasTexCoords {
+		n += 2
+	}
+	if m.HasNormals {
+		n += 3
+	}
+	return n
+}
+
+// Returns the number of vertices.
+func (m *Model) VertexCount() int {
+	return len(m.Vertices) / m.Stride()
+}
+
+// Returns the indices as 16 bit values, or false if there are 65536 or
+// more vertices. Index 0xFFFF is never used, since WebGL 2 treats it as a
+// primitive restart.
+func (m *Model) Indices16() ([]uint16, bool) {
+	if m.VertexCount() > 0xFFFF {
+		return nil, false
+	}
+	out := make([]uint16, len(m.Indices))
+	for i, v := range m.Indices {
+		out[i] = uint16(v)
+	}
+	return out, true
+}
+
+// corner is a face corner's position, texture coordinate and normal
+// index, each -1 if missing.
+type corner [3]int
+
+// Parse reads an OBJ file. Material libraries named by mtllib statements
+// are read with readFile, which may be nil to skip them.
+func Parse(data []byte, readFile func(name string) ([]byte, error)) (*Model, error) {
+	var positions, texCoords, normals []float32
+	var corners []corner
+	m := &Model{Materials: make(map[string]*Material)}
+	group := Group{}
+
+	endGroup := func() {
+		group.Count = len(corners) - group.First
+		if group.Count > 0 {
+			m.Groups = append(m.Groups, group)
+		}
+		group.First = len(corners)
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, 1<<20)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		fail := func(msg string) error {
+			return errors.New("obj: line " + strconv.Itoa(line) + ": " + msg)
+		}
+		args := fields[1:]
+		switch fields[0] {
+		case "v":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return nil, fail(err.Error())
+			}
+			positions = append(positions, v...)
+		case "vt":
+			v, err := parseFloats(args, 1)
+			if err != nil {
+				return nil, fail(err.Error())
+			}
+			if len(v) == 1 {
+				v = append(v, 0)
+			}
+			texCoords = append(texCoords, v[0], v[1])
+		case "vn":
+			v, err := parseFloats(args, 3)
+			if err != nil {
+				return nil, fail(err.Error())
+			}
+			normals = append(normals, v...)
+		case "f":
+			if len(args) < 3 {
+				return nil, fail("face has fewer than 3 corners")
+			}
+			face := make([]corner, len(args))
+			for i, arg := range args {
+				c, err := parseCorner(arg, len(positions)/3, len(texCoords)/2, len(normals)/3)
+				if err != nil {
+					return nil, fail(err.Error())
+				}
+				face[i] = c
+			}
+			for i := 1; i+1 < len(face); i++ {
+				corners = append(corners, face[0], face[i], face[i+1])
+			}
+		case "o", "g":
+			endGroup()
+			group.Name = strings.Join(args, " ")
+		case "usemtl":
+			endGroup()
+			group.Material = strings.Join(args, " ")
+		case "mtllib":
+			if readFile == nil {
+				continue
+			}
+			for _, name := range args {
+				lib, err := readFile(name)
+				if err != nil {
+					return nil, err
+				}
+				mats, err := ParseMTL(lib)
+				if err != nil {
+					return nil, err
+				}
+				for name, mat := range mats {
+					m.Materials[name] = mat
+				}
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	endGroup()
+
+	m.build(corners, positions, texCoords, normals)
+	return m, nil
+}
+
+// build merges identical corners into vertices.
+func (m *Model) build(corners []corner, positions, texCoords, normals []float32) {
+	for _, c := range corners {
+		if c[1] >= 0 {
+			m.HasTexCoords = true
+		}
+		if c[2] >= 0 {
+			m.HasNormals = true
+		}
+	}
+	stride := m.Stride()
+	seen := make(map[corner]uint32)
+	m.Indices = make([]uint32, len(corners))
+	for i, c := range corners {
+		idx, ok := seen[c]
+		if !ok {
+			idx = uint32(len(m.Vertices) / stride)
+			seen[c] = idx
+			m.Vertices = append(m.Vertices, positions[c[0]*3:c[0]*3+3]...)
+			if m.HasTexCoords {
+				if c[1] >= 0 {
+					m.Vertices = append(m.Vertices, texCoords[c[1]*2:c[1]*2+2]...)
+				} else {
+					m.Vertices = append(m.Vertices, 0, 0)
+				}
+			}
+			if m.HasNormals {
+				if c[2] >= 0 {
+					m.Vertices = append(m.Vertices, normals[c[2]*3:c[2]*3+3]...)
+				} else {
+					m.Vertices = append(m.Vertices, 0, 0, 0)
+				}
+			}
+		}
+		m.Indices[i] = idx
+	}
+}
+
+// Parses at least min floats, ignoring any beyond the third.
+func parseFloats(args []string, min int) ([]float32, error) {
+	if len(args) < min {
+		return nil, errors.New("too few values")
+	}
+	if len(args) > 3 {
+		args = args[:3]
+	}
+	out := make([]float32, len(args))
+	for i, arg := range args {
+		f, err := strconv.ParseFloat(arg, 32)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = float32(f)
+	}
+	return out, nil
+}
+
+// Parses a face corner such as "1", "1/2", "1//3" or "1/2/3", resolving
+// negative indices, which count back from the last element read so far.
+func parseCorner(s string, numPositions, numTexCoords, numNormals int) (corner, error) {
+	c := corner{-1, -1, -1}
+	parts := strings.Split(s, "/")
+	if len(parts) > 3 {
+		return c, errors.New("malformed face corner " + s)
+	}
+	counts := [3]int{numPositions, numTexCoords, numNormals}
+	for i, part := range parts {
+		if part == "" {
+			if i == 0 {
+				return c, errors.New("face corner has no position")
+			}
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return c, err
+		}
+		if n < 0 {
+			n += counts[i]
+		} else {
+			n--
+		}
+		if n < 0 || n >= counts[i] {
+			return c, errors.New("face index is out of range in " + s)
+		}
+		c[i] = n
+	}
+	return c, nil
+}
@@ -0,0 +1,377 @@
+package obj
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name         string
+		src          string
+		vertices     []float32
+		indices      []uint32
+		hasTexCoords bool
+		hasNormals   bool
+	}{
+		{
+			name:     "triangle",
+			src:      "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 3\n",
+			vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0},
+			indices:  []uint32{0, 1, 2},
+		},
+		{
+			name:     "quad is a fan",
+			src:      "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nf 1 2 3 4\n",
+			vertices: []float32{0, 0, 0, 1, 0, 0, 1, 1, 0, 0, 1, 0},
+			indices:  []uint32{0, 1, 2, 0, 2, 3},
+		},
+		{
+			name: "pentagon is a fan",
+			src:  "v 0 0 0\nv 1 0 0\nv 2 1 0\nv 1 2 0\nv 0 1 0\nf 1 2 3 4 5\n",
+			vertices: []float32{
+				0, 0, 0, 1, 0, 0, 2, 1, 0, 1, 2, 0, 0, 1, 0,
+			},
+			indices: []uint32{0, 1, 2, 0, 2, 3, 0, 3, 4},
+		},
+		{
+			name: "shared corners are merged",
+			src: "v 0 0 0\nv 1 0 0\nv 1 1 0\nv 0 1 0\nvt 0 0\nvt 1 1\nvn 0 0 1\n" +
+				"f 1/1/1 2/1/1 3/2/1\nf 1/1/1 3/2/1 4/1/1\n",
+			vertices: []float32{
+				0, 0, 0, 0, 0, 0, 0, 1,
+				1, 0, 0, 0, 0, 0, 0, 1,
+				1, 1, 0, 1, 1, 0, 0, 1,
+				0, 1, 0, 0, 0, 0, 0, 1,
+			},
+			indices:      []uint32{0, 1, 2, 0, 2, 3},
+			hasTexCoords: true,
+			hasNormals:   true,
+		},
+		{
+			name: "same position with different texcoords",
+			src:  "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nvt 1 0\nf 1/1 2/1 3/1\nf 1/2 3/1 2/1\n",
+			vertices: []float32{
+				0, 0, 0, 0, 0,
+				1, 0, 0, 0, 0,
+				0, 1, 0, 0, 0,
+				0, 0, 0, 1, 0,
+			},
+			indices:      []uint32{0, 1, 2, 3, 2, 1},
+			hasTexCoords: true,
+		},
+		{
+			name: "same position with different normals",
+			src:  "v 0 0 0\nv 1 0 0\nv 0 1 0\nvn 0 0 1\nvn 0 0 -1\nf 1//1 2//1 3//1\nf 1//2 3//2 2//2\n",
+			vertices: []float32{
+				0, 0, 0, 0, 0, 1,
+				1, 0, 0, 0, 0, 1,
+				0, 1, 0, 0, 0, 1,
+				0, 0, 0, 0, 0, -1,
+				0, 1, 0, 0, 0, -1,
+				1, 0, 0, 0, 0, -1,
+			},
+			indices:    []uint32{0, 1, 2, 3, 4, 5},
+			hasNormals: true,
+		},
+		{
+			name: "negative indices count back",
+			src: "v 9 9 9\nv 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0.5\nvn 1 0 0\n" +
+				"f -3/-1/-1 -2/-1/-1 -1/-1/-1\n",
+			vertices: []float32{
+				0, 0, 0, 0.5, 0, 1, 0, 0,
+				1, 0, 0, 0.5, 0, 1, 0, 0,
+				0, 1, 0, 0.5, 0, 1, 0, 0,
+			},
+			indices:      []uint32{0, 1, 2},
+			hasTexCoords: true,
+			hasNormals:   true,
+		},
+		{
+			name:     "negative indices are relative to where they appear",
+			src:      "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -3 -2 -1\nv 5 5 5\nf -4 -1 -2\n",
+			vertices: []float32{0, 0, 0, 1, 0, 0, 0, 1, 0, 5, 5, 5},
+			indices:  []uint32{0, 1, 2, 0, 3, 2},
+		},
+		{
+			name: "faces missing texcoords",
+			src:  "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 1 1\nf 1/1 2/1 3/1\nf 1 2 3\n",
+			vertices: []float32{
+				0, 0, 0, 1, 1,
+				1, 0, 0, 1, 1,
+				0, 1, 0, 1, 1,
+				0, 0, 0, 0, 0,
+				1, 0, 0, 0, 0,
+				0, 1, 0, 0, 0,
+			},
+			indices:      []uint32{0, 1, 2, 3, 4, 5},
+			hasTexCoords: true,
+		},
+		{
+			name: "faces missing normals",
+			src:  "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 1 1\nvn 0 1 0\nf 1/1 2/1 3/1/1\n",
+			vertices: []float32{
+				0, 0, 0, 1, 1, 0, 0, 0,
+				1, 0, 0, 1, 1, 0, 0, 0,
+				0, 1, 0, 1, 1, 0, 1, 0,
+			},
+			indices:      []uint32{0, 1, 2},
+			hasTexCoords: true,
+			hasNormals:   true,
+		},
+		{
+			name: "comments, blank lines and extra values",
+			src:  "# a comment\n\nv 0 0 0 1\nv 1 0 0\n  v 0 1 0\nvt 0 0 0\ns off\nf 1/1 2/1 3/1\n",
+			vertices: []float32{
+				0, 0, 0, 0, 0,
+				1, 0, 0, 0, 0,
+				0, 1, 0, 0, 0,
+			},
+			indices:      []uint32{0, 1, 2},
+			hasTexCoords: true,
+		},
+	}
+	for _, test := range tests {
+		m, err := Parse([]byte(test.src), nil)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(m.Vertices, test.vertices) {
+			t.Errorf("%s: vertices = %v; want %v", test.name, m.Vertices, test.vertices)
+		}
+		if !reflect.DeepEqual(m.Indices, test.indices) {
+			t.Errorf("%s: indices = %v; want %v", test.name, m.Indices, test.indices)
+		}
+		if m.HasTexCoords != test.hasTexCoords || m.HasNormals != test.hasNormals {
+			t.Errorf("%s: HasTexCoords, HasNormals = %v, %v; want %v, %v", test.name,
+				m.HasTexCoords, m.HasNormals, test.hasTexCoords, test.hasNormals)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"too few position values", "v 0 0\n"},
+		{"bad number", "v 0 x 0\n"},
+		{"too few normal values", "vn 0 1\n"},
+		{"no texcoord values", "vt\n"},
+		{"face with two corners", "v 0 0 0\nv 1 0 0\nf 1 2\n"},
+		{"index past the end", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 2 4\n"},
+		{"index zero", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 0 1 2\n"},
+		{"negative index before the start", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf -1 -2 -4\n"},
+		{"texcoord index past the end", "v 0 0 0\nv 1 0 0\nv 0 1 0\nvt 0 0\nf 1/1 2/2 3/1\n"},
+		{"normal index before any normals", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1//1 2//1 3//1\n"},
+		{"corner without position", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf /1 2 3\n"},
+		{"corner with four parts", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1/1/1/1 2 3\n"},
+		{"corner that is not a number", "v 0 0 0\nv 1 0 0\nv 0 1 0\nf 1 b 3\n"},
+	}
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.src), nil); err == nil {
+			t.Errorf("%s: Parse succeeded", test.name)
+		} else if !strings.HasPrefix(err.Error(), "obj: line ") {
+			t.Errorf("%s: error %q does not give the line", test.name, err)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	src := `mtllib a.mtl
+v 0 0 0
+v 1 0 0
+v 0 1 0
+v 1 1 0
+f 1 2 3
+o box
+usemtl red
+f 1 2 3
+f 2 4 3
+g lid
+f 1 2 4 3
+usemtl blue
+usemtl green
+f 1 2 3
+`
+	mtl := "newmtl red\nKd 1 0 0\nnewmtl green\nKd 0 1 0\n"
+	var asked []string
+	m, err := Parse([]byte(src), func(name string) ([]byte, error) {
+		asked = append(asked, name)
+		return []byte(mtl), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Group{
+		{Name: "", Material: "", First: 0, Count: 3},
+		{Name: "box", Material: "red", First: 3, Count: 6},
+		{Name: "lid", Material: "red", First: 9, Count: 6},
+		{Name: "lid", Material: "green", First: 15, Count: 3},
+	}
+	if !reflect.DeepEqual(m.Groups, want) {
+		t.Errorf("groups = %+v; want %+v", m.Groups, want)
+	}
+	if !reflect.DeepEqual(asked, []string{"a.mtl"}) {
+		t.Errorf("read %q; want a.mtl", asked)
+	}
+	if len(m.Materials) != 2 || m.Materials["red"] == nil || m.Materials["green"] == nil {
+		t.Errorf("materials = %v; want red and green", m.Materials)
+	}
+
+	// Without readFile material libraries are skipped.
+	m, err = Parse([]byte(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Materials) != 0 {
+		t.Errorf("materials = %v; want none", m.Materials)
+	}
+
+	// Errors reading or parsing a library are returned.
+	readErr := errors.New("no such file")
+	if _, err := Parse([]byte(src), func(string) ([]byte, error) { return nil, readErr }); err != readErr {
+		t.Errorf("error = %v; want %v", err, readErr)
+	}
+	if _, err := Parse([]byte(src), func(string) ([]byte, error) { return []byte("newmtl x\nKd a\n"), nil }); err == nil {
+		t.Error("bad material library did not fail")
+	}
+}
+
+func TestParseMTL(t *testing.T) {
+	src := `# materials
+newmtl plain
+
+newmtl shiny metal
+Ka 0.1 0.2 0.3
+Kd 0.5
+Ks 1 1 1
+Ke 0 0 0.25
+Ns 96
+d 0.75
+map_Kd -bm 0.5 textures/metal.png
+map_Ka ambient.png
+map_Ks spec.png
+map_Ke glow.png
+map_d mask.png
+bump -bm 2 normal.png
+
+newmtl glass
+Tr 0.9
+norm glass_normal.png
+`
+	mats, err := ParseMTL([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]*Material{
+		"plain": {
+			Name:    "plain",
+			Diffuse: [3]float32{1, 1, 1},
+			Opacity: 1,
+		},
+		"shiny metal": {
+			Name:        "shiny metal",
+			Ambient:     [3]float32{0.1, 0.2, 0.3},
+			Diffuse:     [3]float32{0.5, 0.5, 0.5},
+			Specular:    [3]float32{1, 1, 1},
+			Emissive:    [3]float32{0, 0, 0.25},
+			Shininess:   96,
+			Opacity:     0.75,
+			AmbientMap:  "ambient.png",
+			DiffuseMap:  "textures/metal.png",
+			SpecularMap: "spec.png",
+			EmissiveMap: "glow.png",
+			OpacityMap:  "mask.png",
+			BumpMap:     "normal.png",
+		},
+		"glass": {
+			Name:    "glass",
+			Diffuse: [3]float32{1, 1, 1},
+			Opacity: 1 - float32(0.9),
+			BumpMap: "glass_normal.png",
+		},
+	}
+	if !reflect.DeepEqual(mats, want) {
+		for name, m := range mats {
+			t.Logf("%s: %+v", name, *m)
+		}
+		t.Errorf("materials differ")
+	}
+
+	for _, bad := range []string{"newmtl a\nKd\n", "newmtl a\nNs x\n", "newmtl a\nd\n", "newmtl a\nTr 0.5 x\nKa 1 2 y\n"} {
+		if _, err := ParseMTL([]byte(bad)); err == nil {
+			t.Errorf("ParseMTL(%q) succeeded", bad)
+		} else if !strings.HasPrefix(err.Error(), "obj: mtl line ") {
+			t.Errorf("ParseMTL(%q) error %q does not give the line", bad, err)
+		}
+	}
+	// Statements before the first newmtl are ignored.
+	if mats, err := ParseMTL([]byte("Kd 1 0 0\n")); err != nil || len(mats) != 0 {
+		t.Errorf("ParseMTL without newmtl = %v, %v; want no materials", mats, err)
+	}
+}
+
+// grid returns an OBJ file with n vertices, where each face uses the next
+// three, wrapping around at the end.
+func grid(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString("v " + strconv.Itoa(i) + " 0 0\n")
+	}
+	for i := 0; i < n; i += 3 {
+		b.WriteString("f")
+		for j := i; j < i+3; j++ {
+			b.WriteString(" " + strconv.Itoa(j%n+1))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func TestIndices16(t *testing.T) {
+	tests := []struct {
+		vertices int
+		ok       bool
+	}{
+		{3, true},
+		{65534, true},
+		{65535, true},
+		{65536, false},
+		{65537, false},
+	}
+	for _, test := range tests {
+		n := test.vertices
+		m, err := Parse([]byte(grid(n)), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.VertexCount() != n {
+			t.Fatalf("%d vertices; want %d", m.VertexCount(), n)
+		}
+		short, ok := m.Indices16()
+		if ok != test.ok {
+			t.Errorf("%d vertices: Indices16 ok = %v; want %v", n, ok, test.ok)
+			continue
+		}
+		if !ok {
+			if short != nil {
+				t.Errorf("%d vertices: Indices16 returned indices with ok false", n)
+			}
+			continue
+		}
+		if len(short) != len(m.Indices) {
+			t.Fatalf("%d vertices: %d 16 bit indices; want %d", n, len(short), len(m.Indices))
+		}
+		for i, v := range short {
+			if uint32(v) != m.Indices[i] || v == 0xFFFF {
+				t.Errorf("%d vertices: index %d = %d; want %d", n, i, v, m.Indices[i])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// +build wasm
+
+package obj
+
+import (
+	"syscall/js"
+
+	"github.com/justinclift/webgl"
+)
+
+// Mesh is a Model uploaded to WebGL buffers.
+type Mesh struct {
+	VertexBuffer *js.Value
+	IndexBuffer  *js.Value
+
+	// IndexType is UNSIGNED_SHORT or UNSIGNED_INT.
+	IndexType int
+
+	// Stride is the size of a vertex in bytes.
+	Stride       int
+	HasTexCoords bool
+	HasNormals   bool
+	Groups       []Group
+	Count        int
+
+	gl *webgl.Context
+}
+
+// Upload copies the vertices and indices of a model into new buffers.
+// Indices are 16 bit unless there are too many vertices, in which case
+// the OES_element_index_uint extension is needed.
+func Upload(c *webgl.Context, m *Model) (*Mesh, error) {
+	mesh := &Mesh{
+		Stride:       m.Stride() * 4,
+		HasTexCoords: m.HasTexCoords,
+		HasNormals:   m.HasNormals,
+		Groups:       m.Groups,
+		Count:        len(m.Indices),
+		gl:           c,
+	}
+	var indices interface{}
+	if short, ok := m.Indices16(); ok {
+		indices = short
+		mesh.IndexType = webgl.UNSIGNED_SHORT
+	} else {
+		if !c.HasExtension("OES_element_index_uint") {
+			return nil, &webgl.ExtensionError{Name: "OES_element_index_uint"}
+		}
+		indices = m.Indices
+		mesh.IndexType = webgl.UNSIGNED_INT
+	}
+
+	mesh.VertexBuffer = c.CreateBuffer()
+	c.BindBuffer(webgl.ARRAY_BUFFER, mesh.VertexBuffer)
+	c.BufferData(webgl.ARRAY_BUFFER, webgl.SliceToTypedArray(m.Vertices), webgl.STATIC_DRAW)
+	mesh.IndexBuffer = c.CreateBuffer()
+	c.BindBuffer(webgl.ELEMENT_ARRAY_BUFFER, mesh.IndexBuffer)
+	c.BufferData(webgl.ELEMENT_ARRAY_BUFFER, webgl.SliceToTypedArray(indices), webgl.STATIC_DRAW)
+	return mesh, nil
+}
+
+// Bind binds the buffers of the mesh and points the given attribute
+// locations at the position, texture coordinate and normal of each
+// vertex. Locations of -1, and attributes the mesh does not have, are
+// skipped.
+func (m *Mesh) Bind(position, texCoord, normal int) {
+	c := m.gl
+	c.BindBuffer(webgl.ARRAY_BUFFER, m.VertexBuffer)
+	c.BindBuffer(webgl.ELEMENT_ARRAY_BUFFER, m.IndexBuffer)
+	attrib := func(loc, size, offset int) {
+		if loc >= 0 {
+			c.EnableVertexAttribArray(loc)
+			c.VertexAttribPointer(loc, size, webgl.FLOAT, false, m.Stride, offset)
+		}
+	}
+	attrib(position, 3, 0)
+	offset := 12
+	if m.HasTexCoords {
+		attrib(texCoord, 2, offset)
+		offset += 8
+	}
+	if m.HasNormals {
+		attrib(normal, 3, offset)
+	}
+}
+
+// Draw draws the whole mesh. The mesh must be bound.
+func (m *Mesh) Draw() {
+	m.gl.DrawElements(webgl.TRIANGLES, m.Count, m.IndexType, 0)
+}
+
+// DrawGroup draws the triangles of one group. The mesh must be bound.
+func (m *Mesh) DrawGroup(g Group) {
+	size := 2
+	if m.IndexType == webgl.UNSIGNED_INT {
+		size = 4
+	}
+	m.gl.DrawElements(webgl.TRIANGLES, g.Count, m.IndexType, g.First*size)
+}
+
+// Delete deletes the buffers of the mesh.
+func (m *Mesh) Delete() {
+	if m.VertexBuffer != nil {
+		m.gl.DeleteBuffer(m.VertexBuffer)
+		m.VertexBuffer = nil
+	}
+	if m.IndexBuffer != nil {
+		m.gl.DeleteBuffer(m.IndexBuffer)
+		m.IndexBuffer = nil
+	}
+}
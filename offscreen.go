@@ -0,0 +1,183 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+	"syscall/js"
+)
+
+// Message types used between the main thread and a worker.
+const (
+	canvasMessage = "webgl-offscreen-canvas"
+	readyMessage  = "webgl-offscreen-ready"
+	queryMessage  = "webgl-offscreen-query"
+	bitmapMessage = "webgl-image-bitmap"
+)
+
+// Returns whether v is an OffscreenCanvas.
+func isOffscreenCanvas(v js.Value) bool {
+	offscreen := js.Global().Get("OffscreenCanvas")
+	return !isNullish(offscreen) && v.InstanceOf(offscreen)
+}
+
+// IsWorker returns whether the program is running inside a Web Worker.
+func IsWorker() bool {
+	scope := js.Global().Get("WorkerGlobalScope")
+	return !isNullish(scope) && js.Global().InstanceOf(scope)
+}
+
+// NewOffscreenCanvas creates an OffscreenCanvas of the given size, which
+// works both on the main thread and in a worker.
+func NewOffscreenCanvas(width, height int) (js.Value, error) {
+	offscreen := js.Global().Get("OffscreenCanvas")
+	if isNullish(offscreen) {
+		return js.Value{}, errors.New("OffscreenCanvas is not supported")
+	}
+	return offscreen.New(width, height), nil
+}
+
+// NewOffscreenContext creates a context from an OffscreenCanvas, such as
+// one made with NewOffscreenCanvas or received with ReceiveCanvas.
+func NewOffscreenContext(canvas js.Value, ca *ContextAttributes) (*Context, error) {
+	if !isOffscreenCanvas(canvas) {
+		return nil, errors.New("canvas is not an OffscreenCanvas")
+	}
+	return NewContext(&canvas, ca)
+}
+
+// Returns whether the context draws into an OffscreenCanvas.
+func (c *Context) IsOffscreen() bool {
+	return isOffscreenCanvas(c.Canvas)
+}
+
+// Returns the current contents of an OffscreenCanvas as an ImageBitmap,
+// which can be posted to another thread with PostImageBitmap.
+func (c *Context) TransferToImageBitmap() (js.Value, error) {
+	if !c.IsOffscreen() {
+		return js.Value{}, errors.New("context is not drawing into an OffscreenCanvas")
+	}
+	c.FlushBatch()
+	return c.Canvas.Call("transferToImageBitmap"), nil
+}
+
+// Commit pushes the rendered frame of an OffscreenCanvas to the canvas
+// element it was transferred from. Browsers without a commit method
+// present the frame on their own once control returns to the event loop,
+// so there it only runs any batched commands.
+func (c *Context) Commit() {
+	c.FlushBatch()
+	if commit := c.Object.Get("commit"); !isNullish(commit) {
+		c.Object.Call("commit")
+	}
+}
+
+// TransferCanvasToWorker hands control of an HTML canvas element to a
+// worker, which picks it up with ReceiveCanvas. Frames rendered in the
+// worker then show up in the canvas element.
+//
+// A worker drops messages that arrive before its wasm module has started
+// listening, so the canvas is not posted until ReceiveCanvas reports that
+// the worker is ready. TransferCanvasToWorker returns without waiting for
+// that.
+func TransferCanvasToWorker(canvas, worker js.Value) error {
+	if isNullish(canvas.Get("transferControlToOffscreen")) {
+		return errors.New("canvas can not be transferred to a worker")
+	}
+	offscreen := canvas.Call("transferControlToOffscreen")
+	msg := map[string]interface{}{
+		"type":   canvasMessage,
+		"canvas": offscreen,
+	}
+	var handler js.Func
+	handler = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		if messageType(args[0].Get("data")) != readyMessage {
+			return nil
+		}
+		worker.Call("removeEventListener", "message", handler)
+		handler.Release()
+		worker.Call("postMessage", msg, []interface{}{offscreen})
+		return nil
+	})
+	worker.Call("addEventListener", "message", handler)
+	// The worker may have reported that it is ready before the handler was
+	// added, so ask it again.
+	worker.Call("postMessage", map[string]interface{}{"type": queryMessage})
+	return nil
+}
+
+// ReceiveCanvas waits inside a worker until a canvas is sent to it with
+// TransferCanvasToWorker, and returns the OffscreenCanvas. It tells the
+// main thread that the worker is ready once it is listening. Other
+// messages are left to the worker's own handlers.
+func ReceiveCanvas() js.Value {
+	self := js.Global()
+	ready := map[string]interface{}{"type": readyMessage}
+	ch := make(chan js.Value, 1)
+	handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := args[0].Get("data")
+		switch messageType(data) {
+		case canvasMessage:
+			select {
+			case ch <- data.Get("canvas"):
+			default:
+			}
+		case queryMessage:
+			self.Call("postMessage", ready)
+		}
+		return nil
+	})
+	self.Call("addEventListener", "message", handler)
+	self.Call("postMessage", ready)
+	canvas := <-ch
+	self.Call("removeEventListener", "message", handler)
+	handler.Release()
+	return canvas
+}
+
+// Returns the type field of a message sent by this package, or "" if data
+// is not such a message. Workers can post strings and numbers too, which
+// have no fields to read.
+func messageType(data js.Value) string {
+	if data.Type() != js.TypeObject {
+		return ""
+	}
+	t := data.Get("type")
+	if t.Type() != js.TypeString {
+		return ""
+	}
+	return t.String()
+}
+
+// PostImageBitmap sends an ImageBitmap, such as one returned by
+// TransferToImageBitmap, to target without copying it. Inside a worker,
+// pass js.Global() as the target to send it to the main thread.
+func PostImageBitmap(target, bitmap js.Value) {
+	msg := map[string]interface{}{
+		"type":   bitmapMessage,
+		"bitmap": bitmap,
+	}
+	target.Call("postMessage", msg, []interface{}{bitmap})
+}
+
+// PresentImageBitmaps draws every ImageBitmap that the worker sends with
+// PostImageBitmap into canvas. The returned function stops listening.
+func PresentImageBitmaps(worker, canvas js.Value) (func(), error) {
+	renderer := canvas.Call("getContext", "bitmaprenderer")
+	if isNullish(renderer) {
+		return nil, errors.New("creating a bitmaprenderer context has failed")
+	}
+	handler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := args[0].Get("data")
+		if messageType(data) == bitmapMessage {
+			renderer.Call("transferFromImageBitmap", data.Get("bitmap"))
+		}
+		return nil
+	})
+	worker.Call("addEventListener", "message", handler)
+	stop := func() {
+		worker.Call("removeEventListener", "message", handler)
+		handler.Release()
+	}
+	return stop, nil
+}
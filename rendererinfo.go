@@ -0,0 +1,218 @@
+// +build wasm
+
+package webgl
+
+import (
+	"strings"
+)
+
+// WEBGL_debug_renderer_info
+const (
+	UNMASKED_VENDOR_WEBGL   = 0x9245
+	UNMASKED_RENDERER_WEBGL = 0x9246
+)
+
+// GPUVendor is the company behind a GPU or renderer.
+type GPUVendor int
+
+const (
+	GPUVendorUnknown GPUVendor = iota
+	GPUVendorNVIDIA
+	GPUVendorAMD
+	GPUVendorIntel
+	GPUVendorApple
+	GPUVendorARM
+	GPUVendorQualcomm
+	GPUVendorImagination
+	GPUVendorMicrosoft
+	GPUVendorGoogle
+	GPUVendorMesa
+)
+
+var gpuVendorNames = [...]string{
+	GPUVendorUnknown:     "Unknown",
+	GPUVendorNVIDIA:      "NVIDIA",
+	GPUVendorAMD:         "AMD",
+	GPUVendorIntel:       "Intel",
+	GPUVendorApple:       "Apple",
+	GPUVendorARM:         "ARM",
+	GPUVendorQualcomm:    "Qualcomm",
+	GPUVendorImagination: "Imagination",
+	GPUVendorMicrosoft:   "Microsoft",
+	GPUVendorGoogle:      "Google",
+	GPUVendorMesa:        "Mesa",
+}
+
+func (v GPUVendor) String() string {
+	if v < 0 || int(v) >= len(gpuVendorNames) {
+		return gpuVendorNames[GPUVendorUnknown]
+	}
+	return gpuVendorNames[v]
+}
+
+// RendererInfo describes the GPU, or software renderer, behind a context.
+type RendererInfo struct {
+	// Vendor and Renderer are the strings reported by the context.
+	Vendor   string
+	Renderer string
+
+	// Unmasked is true if the strings came from WEBGL_debug_renderer_info,
+	// rather than the often generic VENDOR and RENDERER parameters.
+	Unmasked bool
+
+	// GPUVendor and Family classify the device, such as GPUVendorNVIDIA
+	// and "GeForce". Family is empty if it is not recognised.
+	GPUVendor GPUVendor
+	Family    string
+
+	// Device is the name of the device, without any ANGLE wrapping.
+	Device string
+
+	// ANGLE is true if the browser translates WebGL through ANGLE, and
+	// Backend is the API it translates to, such as "Direct3D11", "Metal"
+	// or "OpenGL", if known.
+	ANGLE   bool
+	Backend string
+
+	// Software is true if rendering is done on the CPU. SwiftShader and
+	// LLVMpipe say which software renderer it is, where known.
+	Software    bool
+	SwiftShader bool
+	LLVMpipe    bool
+}
+
+// Returns information about the GPU behind the context. The unmasked
+// strings of WEBGL_debug_renderer_info are used where the browser exposes
+// them, otherwise VENDOR and RENDERER.
+func (c *Context) RendererInfo() RendererInfo {
+	vendor, renderer := "", ""
+	unmasked := false
+	if _, err := c.extension("WEBGL_debug_renderer_info"); err == nil {
+		v := c.callResult("getParameter", UNMASKED_VENDOR_WEBGL)
+		r := c.callResult("getParameter", UNMASKED_RENDERER_WEBGL)
+		if !isNullish(v) && !isNullish(r) {
+			vendor, renderer, unmasked = v.String(), r.String(), true
+		}
+	}
+	if !unmasked {
+		if v := c.callResult("getParameter", VENDOR); !isNullish(v) {
+			vendor = v.String()
+		}
+		if r := c.callResult("getParameter", RENDERER); !isNullish(r) {
+			renderer = r.String()
+		}
+	}
+	info := ParseRendererInfo(vendor, renderer)
+	info.Unmasked = unmasked
+	return info
+}
+
+// gpuFamilies maps lower case substrings of device names to what they
+// identify, most specific first.
+var gpuFamilies = []struct {
+	match  string
+	vendor GPUVendor
+	family string
+}{
+	{"swiftshader", GPUVendorGoogle, "SwiftShader"},
+	{"llvmpipe", GPUVendorMesa, "llvmpipe"},
+	{"softpipe", GPUVendorMesa, "softpipe"},
+	{"microsoft basic render", GPUVendorMicrosoft, "Basic Render Driver"},
+	{"geforce", GPUVendorNVIDIA, "GeForce"},
+	{"quadro", GPUVendorNVIDIA, "Quadro"},
+	{"tesla", GPUVendorNVIDIA, "Tesla"},
+	{"tegra", GPUVendorNVIDIA, "Tegra"},
+	{"radeon", GPUVendorAMD, "Radeon"},
+	{"firepro", GPUVendorAMD, "FirePro"},
+	{"iris", GPUVendorIntel, "Iris"},
+	{"uhd graphics", GPUVendorIntel, "UHD Graphics"},
+	{"hd graphics", GPUVendorIntel, "HD Graphics"},
+	{"intel(r) arc", GPUVendorIntel, "Arc"},
+	{"intel arc", GPUVendorIntel, "Arc"},
+	{"adreno", GPUVendorQualcomm, "Adreno"},
+	{"mali", GPUVendorARM, "Mali"},
+	{"powervr", GPUVendorImagination, "PowerVR"},
+	{"apple", GPUVendorApple, "Apple"},
+}
+
+// gpuVendors maps lower case substrings of vendor names to vendors.
+var gpuVendors = []struct {
+	match  string
+	vendor GPUVendor
+}{
+	{"nvidia", GPUVendorNVIDIA},
+	{"amd", GPUVendorAMD},
+	{"ati technologies", GPUVendorAMD},
+	{"intel", GPUVendorIntel},
+	{"apple", GPUVendorApple},
+	{"arm", GPUVendorARM},
+	{"qualcomm", GPUVendorQualcomm},
+	{"imagination", GPUVendorImagination},
+	{"microsoft", GPUVendorMicrosoft},
+	{"google", GPUVendorGoogle},
+	{"mesa", GPUVendorMesa},
+}
+
+// gpuBackends maps lower case substrings of ANGLE renderer strings to the
+// API ANGLE runs on.
+var gpuBackends = []struct {
+	match   string
+	backend string
+}{
+	{"direct3d11", "Direct3D11"},
+	{"d3d11", "Direct3D11"},
+	{"direct3d9", "Direct3D9"},
+	{"d3d9", "Direct3D9"},
+	{"metal", "Metal"},
+	{"vulkan", "Vulkan"},
+	{"opengl", "OpenGL"},
+}
+
+// ParseRendererInfo classifies vendor and renderer strings, as reported by
+// a WebGL context, into a RendererInfo.
+func ParseRendererInfo(vendor, renderer string) RendererInfo {
+	info := RendererInfo{Vendor: vendor, Renderer: renderer, Device: renderer}
+
+	// ANGLE wraps the native strings, as "ANGLE (device)" in older
+	// browsers and "ANGLE (vendor, device, backend)" in newer ones.
+	if strings.HasPrefix(renderer, "ANGLE (") && strings.HasSuffix(renderer, ")") {
+		info.ANGLE = true
+		inner := renderer[len("ANGLE (") : len(renderer)-1]
+		parts := strings.Split(inner, ", ")
+		info.Device = inner
+		if len(parts) >= 3 {
+			vendor = parts[0]
+			info.Device = strings.Join(parts[1:len(parts)-1], ", ")
+		}
+		lower := strings.ToLower(inner)
+		for _, b := range gpuBackends {
+			if strings.Contains(lower, b.match) {
+				info.Backend = b.backend
+				break
+			}
+		}
+	}
+
+	device := strings.ToLower(info.Device)
+	for _, f := range gpuFamilies {
+		if strings.Contains(device, f.match) {
+			info.GPUVendor, info.Family = f.vendor, f.family
+			break
+		}
+	}
+	if info.GPUVendor == GPUVendorUnknown {
+		lower := strings.ToLower(vendor + " " + info.Device)
+		for _, v := range gpuVendors {
+			if strings.Contains(lower, v.match) {
+				info.GPUVendor = v.vendor
+				break
+			}
+		}
+	}
+
+	info.SwiftShader = info.Family == "SwiftShader"
+	info.LLVMpipe = info.Family == "llvmpipe"
+	info.Software = info.SwiftShader || info.LLVMpipe || info.Family == "softpipe" ||
+		info.Family == "Basic Render Driver" || strings.Contains(device, "software")
+	return info
+}
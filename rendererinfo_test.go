@@ -0,0 +1,193 @@
+// +build wasm
+
+package webgl
+
+import "testing"
+
+func TestParseRendererInfo(t *testing.T) {
+	tests := []struct {
+		name, vendor, renderer string
+
+		gpuVendor GPUVendor
+		family    string
+		device    string
+		angle     bool
+		backend   string
+		software  bool
+	}{
+		{
+			name:      "ANGLE D3D11 NVIDIA",
+			vendor:    "Google Inc. (NVIDIA)",
+			renderer:  "ANGLE (NVIDIA, NVIDIA GeForce RTX 3070 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			gpuVendor: GPUVendorNVIDIA,
+			family:    "GeForce",
+			device:    "NVIDIA GeForce RTX 3070 Direct3D11 vs_5_0 ps_5_0",
+			angle:     true,
+			backend:   "Direct3D11",
+		},
+		{
+			name:      "ANGLE D3D11 AMD",
+			vendor:    "Google Inc. (AMD)",
+			renderer:  "ANGLE (AMD, AMD Radeon RX 6800 XT Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			gpuVendor: GPUVendorAMD,
+			family:    "Radeon",
+			device:    "AMD Radeon RX 6800 XT Direct3D11 vs_5_0 ps_5_0",
+			angle:     true,
+			backend:   "Direct3D11",
+		},
+		{
+			name:      "ANGLE D3D11 Intel",
+			vendor:    "Google Inc. (Intel)",
+			renderer:  "ANGLE (Intel, Intel(R) UHD Graphics 620 Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			gpuVendor: GPUVendorIntel,
+			family:    "UHD Graphics",
+			device:    "Intel(R) UHD Graphics 620 Direct3D11 vs_5_0 ps_5_0",
+			angle:     true,
+			backend:   "Direct3D11",
+		},
+		{
+			name:      "older ANGLE D3D11 Intel",
+			vendor:    "Google Inc.",
+			renderer:  "ANGLE (Intel(R) HD Graphics 630 Direct3D11 vs_5_0 ps_5_0)",
+			gpuVendor: GPUVendorIntel,
+			family:    "HD Graphics",
+			device:    "Intel(R) HD Graphics 630 Direct3D11 vs_5_0 ps_5_0",
+			angle:     true,
+			backend:   "Direct3D11",
+		},
+		{
+			name:      "ANGLE Metal Apple M1",
+			vendor:    "Google Inc. (Apple)",
+			renderer:  "ANGLE (Apple, ANGLE Metal Renderer: Apple M1, Unspecified Version)",
+			gpuVendor: GPUVendorApple,
+			family:    "Apple",
+			device:    "ANGLE Metal Renderer: Apple M1",
+			angle:     true,
+			backend:   "Metal",
+		},
+		{
+			name:      "ANGLE OpenGL llvmpipe",
+			vendor:    "Google Inc. (Mesa/X.org)",
+			renderer:  "ANGLE (Mesa/X.org, llvmpipe (LLVM 12.0.0, 256 bits), OpenGL 4.5 (Core Profile) Mesa 21.2.6)",
+			gpuVendor: GPUVendorMesa,
+			family:    "llvmpipe",
+			device:    "llvmpipe (LLVM 12.0.0, 256 bits)",
+			angle:     true,
+			backend:   "OpenGL",
+			software:  true,
+		},
+		{
+			name:      "Mesa llvmpipe",
+			vendor:    "Mesa/X.org",
+			renderer:  "llvmpipe (LLVM 15.0.7, 256 bits)",
+			gpuVendor: GPUVendorMesa,
+			family:    "llvmpipe",
+			device:    "llvmpipe (LLVM 15.0.7, 256 bits)",
+			software:  true,
+		},
+		{
+			name:      "SwiftShader Vulkan",
+			vendor:    "Google Inc. (Google)",
+			renderer:  "ANGLE (Google, Vulkan 1.3.0 (SwiftShader Device (Subzero) (0x0000C0DE)), SwiftShader driver)",
+			gpuVendor: GPUVendorGoogle,
+			family:    "SwiftShader",
+			device:    "Vulkan 1.3.0 (SwiftShader Device (Subzero) (0x0000C0DE))",
+			angle:     true,
+			backend:   "Vulkan",
+			software:  true,
+		},
+		{
+			name:      "Microsoft Basic Render Driver",
+			vendor:    "Google Inc. (Microsoft)",
+			renderer:  "ANGLE (Microsoft, Microsoft Basic Render Driver Direct3D11 vs_5_0 ps_5_0, D3D11)",
+			gpuVendor: GPUVendorMicrosoft,
+			family:    "Basic Render Driver",
+			device:    "Microsoft Basic Render Driver Direct3D11 vs_5_0 ps_5_0",
+			angle:     true,
+			backend:   "Direct3D11",
+			software:  true,
+		},
+		{
+			name:      "Mali",
+			vendor:    "ARM",
+			renderer:  "Mali-G78",
+			gpuVendor: GPUVendorARM,
+			family:    "Mali",
+			device:    "Mali-G78",
+		},
+		{
+			name:      "Adreno",
+			vendor:    "Qualcomm",
+			renderer:  "Adreno (TM) 650",
+			gpuVendor: GPUVendorQualcomm,
+			family:    "Adreno",
+			device:    "Adreno (TM) 650",
+		},
+		{
+			name:      "ANGLE OpenGL ES Adreno",
+			vendor:    "Google Inc. (Qualcomm)",
+			renderer:  "ANGLE (Qualcomm, Adreno (TM) 740, OpenGL ES 3.2)",
+			gpuVendor: GPUVendorQualcomm,
+			family:    "Adreno",
+			device:    "Adreno (TM) 740",
+			angle:     true,
+			backend:   "OpenGL",
+		},
+		{
+			name:      "vendor only",
+			vendor:    "NVIDIA Corporation",
+			renderer:  "NV167",
+			gpuVendor: GPUVendorNVIDIA,
+			device:    "NV167",
+		},
+		{
+			name:      "masked",
+			vendor:    "WebKit",
+			renderer:  "WebKit WebGL",
+			gpuVendor: GPUVendorUnknown,
+			device:    "WebKit WebGL",
+		},
+	}
+	for _, tt := range tests {
+		info := ParseRendererInfo(tt.vendor, tt.renderer)
+		if info.Vendor != tt.vendor || info.Renderer != tt.renderer {
+			t.Errorf("%s: strings = %q, %q; want %q, %q", tt.name, info.Vendor, info.Renderer, tt.vendor, tt.renderer)
+		}
+		if info.GPUVendor != tt.gpuVendor {
+			t.Errorf("%s: GPUVendor = %v; want %v", tt.name, info.GPUVendor, tt.gpuVendor)
+		}
+		if info.Family != tt.family {
+			t.Errorf("%s: Family = %q; want %q", tt.name, info.Family, tt.family)
+		}
+		if info.Device != tt.device {
+			t.Errorf("%s: Device = %q; want %q", tt.name, info.Device, tt.device)
+		}
+		if info.ANGLE != tt.angle || info.Backend != tt.backend {
+			t.Errorf("%s: ANGLE, Backend = %v, %q; want %v, %q", tt.name, info.ANGLE, info.Backend, tt.angle, tt.backend)
+		}
+		if info.Software != tt.software {
+			t.Errorf("%s: Software = %v; want %v", tt.name, info.Software, tt.software)
+		}
+		if info.SwiftShader != (tt.family == "SwiftShader") || info.LLVMpipe != (tt.family == "llvmpipe") {
+			t.Errorf("%s: SwiftShader, LLVMpipe = %v, %v", tt.name, info.SwiftShader, info.LLVMpipe)
+		}
+	}
+}
+
+func TestGPUVendorString(t *testing.T) {
+	tests := []struct {
+		v    GPUVendor
+		want string
+	}{
+		{GPUVendorUnknown, "Unknown"},
+		{GPUVendorNVIDIA, "NVIDIA"},
+		{GPUVendorMesa, "Mesa"},
+		{GPUVendor(-1), "Unknown"},
+		{GPUVendorMesa + 1, "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := tt.v.String(); got != tt.want {
+			t.Errorf("GPUVendor(%d).String() = %q; want %q", int(tt.v), got, tt.want)
+		}
+	}
+}
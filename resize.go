@@ -0,0 +1,215 @@
+// +build wasm
+
+package webgl
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"syscall/js"
+)
+
+// CanvasSize is the size of a canvas on the page and of its drawing
+// buffer.
+type CanvasSize struct {
+	// Width and Height are the size of the drawing buffer in pixels.
+	Width  int
+	Height int
+
+	// CSSWidth and CSSHeight are the size the canvas is shown at, in CSS
+	// pixels.
+	CSSWidth  float64
+	CSSHeight float64
+
+	// PixelRatio is the number of drawing buffer pixels per CSS pixel. It
+	// is below devicePixelRatio when the pixel budget is exceeded.
+	PixelRatio float64
+}
+
+// CanvasResizer keeps the drawing buffer of a canvas element the size the
+// canvas is shown at, in device pixels, as the page layout and the
+// device pixel ratio change.
+type CanvasResizer struct {
+	// MaxPixels limits the number of pixels in the drawing buffer. If the
+	// canvas would need more, the buffer is scaled down to fit, keeping
+	// its aspect ratio. Zero means no limit.
+	MaxPixels int
+
+	canvas   js.Value
+	onResize func(CanvasSize)
+	size     CanvasSize
+
+	observer   js.Value
+	observed   js.Func
+	dprQuery   js.Value
+	dprChanged js.Func
+	cssWidth   float64
+	cssHeight  float64
+	devWidth   int
+	devHeight  int
+}
+
+// NewCanvasResizer starts keeping the drawing buffer of canvas in sync
+// with its size on the page. onResize, which may be nil, is called with
+// the new size whenever the drawing buffer is resized, so the viewport and
+// projection matrices can be updated. The canvas is resized once straight
+// away. It uses ResizeObserver where available, and window resize events
+// otherwise.
+func NewCanvasResizer(canvas js.Value, maxPixels int, onResize func(CanvasSize)) (*CanvasResizer, error) {
+	if isOffscreenCanvas(canvas) || isNullish(canvas.Get("getBoundingClientRect")) {
+		return nil, errors.New("canvas is not an element on the page")
+	}
+	r := &CanvasResizer{
+		MaxPixels: maxPixels,
+		canvas:    canvas,
+		onResize:  onResize,
+	}
+	r.observed = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		r.measure(args)
+		return nil
+	})
+	if observer := js.Global().Get("ResizeObserver"); !isNullish(observer) {
+		r.observer = observer.New(r.observed)
+		r.observer.Call("observe", canvas)
+	} else {
+		js.Global().Call("addEventListener", "resize", r.observed)
+	}
+	r.dprChanged = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		r.watchPixelRatio()
+		r.Resize()
+		return nil
+	})
+	r.watchPixelRatio()
+	r.Resize()
+	return r, nil
+}
+
+// AutoResize keeps the drawing buffer of the context's canvas in sync with
+// its size on the page, setting the viewport to cover it after every
+// resize before calling onResize. See NewCanvasResizer.
+func (c *Context) AutoResize(maxPixels int, onResize func(CanvasSize)) (*CanvasResizer, error) {
+	return NewCanvasResizer(c.Canvas, maxPixels, func(size CanvasSize) {
+		c.Viewport(0, 0, size.Width, size.Height)
+		if onResize != nil {
+			onResize(size)
+		}
+	})
+}
+
+// Returns the device pixel ratio of the page.
+func devicePixelRatio() float64 {
+	dpr := js.Global().Get("devicePixelRatio")
+	if dpr.Type() != js.TypeNumber || dpr.Float() <= 0 {
+		return 1
+	}
+	return dpr.Float()
+}
+
+// watchPixelRatio listens for the device pixel ratio changing from its
+// current value, such as when the window moves to another screen or the
+// page is zoomed. ResizeObserver does not report these by itself.
+func (r *CanvasResizer) watchPixelRatio() {
+	if !r.dprQuery.IsUndefined() {
+		r.dprQuery.Call("removeEventListener", "change", r.dprChanged)
+		r.dprQuery = js.Undefined()
+	}
+	if isNullish(js.Global().Get("matchMedia")) {
+		return
+	}
+	dpr := strconv.FormatFloat(devicePixelRatio(), 'f', -1, 64)
+	r.dprQuery = js.Global().Call("matchMedia", "(resolution: "+dpr+"dppx)")
+	r.dprQuery.Call("addEventListener", "change", r.dprChanged)
+}
+
+// measure reads the size of the canvas from a ResizeObserver entry if
+// there is one, and from the page layout otherwise, then resizes.
+func (r *CanvasResizer) measure(args []js.Value) {
+	isArray := js.Global().Get("Array").Get("isArray")
+	if len(args) > 0 && isArray.Invoke(args[0]).Bool() && args[0].Length() > 0 {
+		entry := args[0].Index(args[0].Length() - 1)
+		rect := entry.Get("contentRect")
+		r.cssWidth, r.cssHeight = rect.Get("width").Float(), rect.Get("height").Float()
+		r.devWidth, r.devHeight = 0, 0
+		// Exact device pixel sizes, where the browser reports them.
+		if dev := entry.Get("devicePixelContentBoxSize"); !isNullish(dev) && isArray.Invoke(dev).Bool() && dev.Length() > 0 {
+			r.devWidth = dev.Index(0).Get("inlineSize").Int()
+			r.devHeight = dev.Index(0).Get("blockSize").Int()
+		}
+		r.apply(devicePixelRatio())
+		return
+	}
+	r.Resize()
+}
+
+// Resize measures the canvas and resizes its drawing buffer if needed.
+// It only needs to be called when something the resizer can not see has
+// changed, such as MaxPixels.
+func (r *CanvasResizer) Resize() {
+	rect := r.canvas.Call("getBoundingClientRect")
+	r.cssWidth, r.cssHeight = rect.Get("width").Float(), rect.Get("height").Float()
+	r.devWidth, r.devHeight = 0, 0
+	r.apply(devicePixelRatio())
+}
+
+// apply works out the drawing buffer size from the measured size, and
+// resizes the drawing buffer if it has changed.
+func (r *CanvasResizer) apply(dpr float64) {
+	width, height := r.devWidth, r.devHeight
+	if width == 0 || height == 0 {
+		width = int(math.Round(r.cssWidth * dpr))
+		height = int(math.Round(r.cssHeight * dpr))
+	}
+	if r.MaxPixels > 0 && width*height > r.MaxPixels {
+		scale := math.Sqrt(float64(r.MaxPixels) / float64(width*height))
+		width = int(float64(width) * scale)
+		height = int(float64(height) * scale)
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	size := CanvasSize{
+		Width:      width,
+		Height:     height,
+		CSSWidth:   r.cssWidth,
+		CSSHeight:  r.cssHeight,
+		PixelRatio: dpr,
+	}
+	if r.cssWidth > 0 {
+		size.PixelRatio = float64(width) / r.cssWidth
+	}
+	if size.Width == r.size.Width && size.Height == r.size.Height {
+		r.size = size
+		return
+	}
+	r.size = size
+	// Setting the size clears the drawing buffer, so only do it on change.
+	r.canvas.Set("width", width)
+	r.canvas.Set("height", height)
+	if r.onResize != nil {
+		r.onResize(size)
+	}
+}
+
+// Returns the current size of the canvas.
+func (r *CanvasResizer) Size() CanvasSize {
+	return r.size
+}
+
+// Stop stops resizing the canvas.
+func (r *CanvasResizer) Stop() {
+	if !r.observer.IsUndefined() {
+		r.observer.Call("disconnect")
+		r.observer = js.Undefined()
+	} else {
+		js.Global().Call("removeEventListener", "resize", r.observed)
+	}
+	if !r.dprQuery.IsUndefined() {
+		r.dprQuery.Call("removeEventListener", "change", r.dprChanged)
+		r.dprQuery = js.Undefined()
+	}
+	r.observed.Release()
+	r.dprChanged.Release()
+}
@@ -0,0 +1,136 @@
+// +build wasm
+
+package webgl
+
+// Free render targets that have not been handed out for this many frames
+// are deleted by EndFrame, unless the pool says otherwise.
+const defaultMaxIdleFrames = 60
+
+type renderTargetKey struct {
+	width  int
+	height int
+	format RenderTargetFormat
+}
+
+type idleRenderTarget struct {
+	rt       *RenderTarget
+	lastUsed int
+}
+
+// RenderTargetPool hands out temporary render targets by size and format,
+// reusing ones that were returned earlier instead of creating new ones.
+// Render targets handed out by Get go back to the pool at the end of the
+// frame, and free ones that are not asked for again are deleted after
+// MaxIdleFrames frames. Render targets from the pool must not be resized
+// or deleted by the caller.
+type RenderTargetPool struct {
+	// MaxIdleFrames is how many frames a free render target is kept for.
+	MaxIdleFrames int
+
+	gl    *Context
+	frame int
+	free  map[renderTargetKey][]idleRenderTarget
+	inUse []*RenderTarget
+}
+
+// NewRenderTargetPool creates an empty pool.
+func NewRenderTargetPool(c *Context) *RenderTargetPool {
+	return &RenderTargetPool{
+		MaxIdleFrames: defaultMaxIdleFrames,
+		gl:            c,
+		free:          make(map[renderTargetKey][]idleRenderTarget),
+	}
+}
+
+// Get returns a render target of the given size and format for use during
+// the current frame, creating one if there is no free one to reuse.
+func (p *RenderTargetPool) Get(width, height int, format RenderTargetFormat) (*RenderTarget, error) {
+	key := renderTargetKey{width, height, format}
+	var rt *RenderTarget
+	if free := p.free[key]; len(free) > 0 {
+		rt = free[len(free)-1].rt
+		free[len(free)-1] = idleRenderTarget{}
+		p.free[key] = free[:len(free)-1]
+	} else {
+		var err error
+		rt, err = NewRenderTarget(p.gl, width, height, format)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.inUse = append(p.inUse, rt)
+	return rt, nil
+}
+
+// Release returns a render target to the pool before the end of the
+// frame, so a later Get in the same frame can reuse it.
+func (p *RenderTargetPool) Release(rt *RenderTarget) {
+	for i, used := range p.inUse {
+		if used == rt {
+			last := len(p.inUse) - 1
+			p.inUse[i] = p.inUse[last]
+			p.inUse[last] = nil
+			p.inUse = p.inUse[:last]
+			p.put(rt)
+			return
+		}
+	}
+}
+
+func (p *RenderTargetPool) put(rt *RenderTarget) {
+	key := renderTargetKey{rt.Width, rt.Height, rt.Format}
+	p.free[key] = append(p.free[key], idleRenderTarget{rt, p.frame})
+}
+
+// EndFrame returns every render target handed out during the frame to the
+// pool, and deletes the free ones that have been idle for too long.
+func (p *RenderTargetPool) EndFrame() {
+	for i, rt := range p.inUse {
+		p.put(rt)
+		p.inUse[i] = nil
+	}
+	p.inUse = p.inUse[:0]
+	p.frame++
+
+	for key, free := range p.free {
+		kept := free[:0]
+		for _, idle := range free {
+			if p.frame-idle.lastUsed > p.MaxIdleFrames {
+				idle.rt.Delete()
+				continue
+			}
+			kept = append(kept, idle)
+		}
+		for i := len(kept); i < len(free); i++ {
+			free[i] = idleRenderTarget{}
+		}
+		if len(kept) == 0 {
+			delete(p.free, key)
+		} else {
+			p.free[key] = kept
+		}
+	}
+}
+
+// Returns how many render targets are handed out and how many are free.
+func (p *RenderTargetPool) Stats() (inUse, free int) {
+	for _, f := range p.free {
+		free += len(f)
+	}
+	return len(p.inUse), free
+}
+
+// Delete deletes every render target in the pool, including the ones that
+// are handed out.
+func (p *RenderTargetPool) Delete() {
+	for _, rt := range p.inUse {
+		rt.Delete()
+	}
+	p.inUse = nil
+	for _, free := range p.free {
+		for _, idle := range free {
+			idle.rt.Delete()
+		}
+	}
+	p.free = make(map[renderTargetKey][]idleRenderTarget)
+}
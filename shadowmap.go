@@ -0,0 +1,139 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+// shadowBias maps clip space coordinates to texture coordinates and depth
+// values in [0, 1].
+var shadowBias = glmath.Mat4{
+	0.5, 0, 0, 0,
+	0, 0.5, 0, 0,
+	0, 0, 0.5, 0,
+	0.5, 0.5, 0.5, 1,
+}
+
+// ShadowMap is a depth texture rendered from the point of view of a light.
+// Render the shadow casters between Begin and End with LightMatrix as
+// their view projection matrix, then Bind the map for the main pass and
+// compare each fragment's depth, transformed by ShadowMatrix, with the
+// depth sampled from the map.
+type ShadowMap struct {
+	// Target is the render target the depth pass draws into. Its
+	// DepthTexture is the shadow map.
+	Target *RenderTarget
+
+	// View and Projection are the view and projection matrices of the
+	// light.
+	View       glmath.Mat4
+	Projection glmath.Mat4
+
+	gl *Context
+}
+
+// NewShadowMap creates a square shadow map with sides of size pixels. It
+// needs WEBGL_depth_texture, and returns an *ExtensionError without it.
+func NewShadowMap(c *Context, size int) (*ShadowMap, error) {
+	format := RenderTargetFormat{DepthTexture: true}
+	rt, err := NewRenderTarget(c, size, size, format)
+	if _, ok := err.(*FramebufferError); ok {
+		// Some implementations need a color attachment as well.
+		format.ColorFormat, format.ColorType = RGBA, UNSIGNED_BYTE
+		rt, err = NewRenderTarget(c, size, size, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ShadowMap{
+		Target:     rt,
+		View:       glmath.Ident4(),
+		Projection: glmath.Ident4(),
+		gl:         c,
+	}, nil
+}
+
+// SetDirectionalLight points the shadow map along direction, covering a
+// sphere of radius around center with an orthographic projection.
+func (s *ShadowMap) SetDirectionalLight(direction, center glmath.Vec3, radius float32) {
+	dir := direction.Normalize()
+	eye := center.Sub(dir.Mul(radius))
+	s.View = glmath.LookAt(eye, center, shadowUp(dir))
+	s.Projection = glmath.Ortho(-radius, radius, -radius, radius, 0, 2*radius)
+}
+
+// SetSpotLight places the shadow map at position looking at target, with a
+// perspective projection of fovy radians from near to far.
+func (s *ShadowMap) SetSpotLight(position, target glmath.Vec3, fovy, near, far float32) {
+	s.View = glmath.LookAt(position, target, shadowUp(target.Sub(position).Normalize()))
+	s.Projection = glmath.Perspective(fovy, 1, near, far)
+}
+
+// Returns an up vector that is not parallel to the direction dir.
+func shadowUp(dir glmath.Vec3) glmath.Vec3 {
+	if d := dir[1]; d > 0.99 || d < -0.99 {
+		return glmath.Vec3{0, 0, 1}
+	}
+	return glmath.Vec3{0, 1, 0}
+}
+
+// Returns the light's view projection matrix, for the depth pass.
+func (s *ShadowMap) LightMatrix() glmath.Mat4 {
+	return s.Projection.Mul(&s.View)
+}
+
+// Returns the matrix that takes world space positions to shadow map
+// texture coordinates in xy and depth in z, for the main pass.
+func (s *ShadowMap) ShadowMatrix() glmath.Mat4 {
+	light := s.LightMatrix()
+	return shadowBias.Mul(&light)
+}
+
+// Returns the depth texture holding the shadow map.
+func (s *ShadowMap) Texture() *js.Value {
+	return s.Target.DepthTexture
+}
+
+// Begin binds the shadow map for the depth pass, sets the viewport to cover
+// it, enables depth testing and clears it.
+func (s *ShadowMap) Begin() {
+	c := s.gl
+	s.Target.Bind()
+	c.Enable(DEPTH_TEST)
+	c.DepthMask(true)
+	flags := DEPTH_BUFFER_BIT
+	if s.Target.Texture != nil {
+		flags |= COLOR_BUFFER_BIT
+	}
+	c.Clear(flags)
+}
+
+// End makes the default framebuffer the destination of drawing operations
+// again. The viewport is left for the caller to restore.
+func (s *ShadowMap) End() {
+	s.Target.Unbind()
+}
+
+// Bind binds the shadow map to texture unit unit for the main pass, and
+// sets the sampler uniform to it and the matrix uniform to ShadowMatrix.
+// Either uniform location may be nil.
+func (s *ShadowMap) Bind(unit int, matrix, sampler *js.Value) {
+	c := s.gl
+	c.ActiveTexture(TEXTURE0 + unit)
+	c.BindTexture(TEXTURE_2D, s.Target.DepthTexture)
+	if sampler != nil {
+		c.Uniform1i(sampler, unit)
+	}
+	if matrix != nil {
+		m := s.ShadowMatrix()
+		c.UniformMat4(matrix, &m)
+	}
+}
+
+// Delete deletes the shadow map.
+func (s *ShadowMap) Delete() {
+	s.Target.Delete()
+}
@@ -0,0 +1,143 @@
+// +build wasm
+
+package webgl
+
+import (
+	"math"
+	"testing"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+var shadowDirections = []glmath.Vec3{
+	{1, 0, 0},
+	{0, 0, -1},
+	{1, -1, 1},
+	{0, 1, 0},
+	{0, -1, 0},
+	{0.01, -1, 0},
+	{0, 0.999, 0.04},
+	{0.2, -1, 0.1},
+}
+
+// Returns the world space corners of the light's frustum, in the order of
+// the clip space corners (x, y, z) with each of them -1 or 1.
+func frustumCorners(t *testing.T, s *ShadowMap) []glmath.Vec3 {
+	light := s.LightMatrix()
+	inv, ok := light.Inverse()
+	if !ok {
+		t.Fatalf("light matrix %v is not invertible", light)
+	}
+	var corners []glmath.Vec3
+	for i := 0; i < 8; i++ {
+		clip := glmath.Vec3{-1, -1, -1}
+		for j := range clip {
+			if i>>uint(j)&1 != 0 {
+				clip[j] = 1
+			}
+		}
+		corners = append(corners, inv.MulPoint(clip))
+	}
+	return corners
+}
+
+func checkShadowCorners(t *testing.T, name string, s *ShadowMap, corners []glmath.Vec3) {
+	const eps = 1e-3
+	m := s.ShadowMatrix()
+	for i, c := range corners {
+		got := m.MulPoint(c)
+		for j := range got {
+			want := float32(i >> uint(j) & 1)
+			if math.Abs(float64(got[j]-want)) > eps {
+				t.Errorf("%s: corner %d maps to %v; want component %d = %g", name, i, got, j, want)
+				break
+			}
+		}
+	}
+}
+
+func inUnitCube(v glmath.Vec3) bool {
+	const eps = 1e-4
+	for _, f := range v {
+		if f < -eps || f > 1+eps {
+			return false
+		}
+	}
+	return true
+}
+
+func TestShadowMatrixDirectional(t *testing.T) {
+	center := glmath.Vec3{3, -2, 5}
+	const radius float32 = 4
+	for _, dir := range shadowDirections {
+		var s ShadowMap
+		s.SetDirectionalLight(dir, center, radius)
+		corners := frustumCorners(t, &s)
+		checkShadowCorners(t, "directional", &s, corners)
+
+		// The frustum is a box around the sphere, 2*radius along each side.
+		for _, e := range [][2]int{{0, 1}, {0, 2}, {0, 4}} {
+			if d := corners[e[1]].Sub(corners[e[0]]).Len(); math.Abs(float64(d-2*radius)) > 1e-3 {
+				t.Errorf("direction %v: frustum edge is %g long; want %g", dir, d, 2*radius)
+			}
+		}
+		m := s.ShadowMatrix()
+		for _, o := range []glmath.Vec3{{0, 0, 0}, {radius, 0, 0}, {0, -radius, 0}, {0, 0, radius}, dir.Normalize().Mul(radius)} {
+			if p := m.MulPoint(center.Add(o)); !inUnitCube(p) {
+				t.Errorf("direction %v: point %v of the sphere maps to %v; want it in [0, 1]", dir, center.Add(o), p)
+			}
+		}
+		if p := m.MulPoint(center.Sub(dir.Normalize().Mul(radius))); math.Abs(float64(p[2])) > 1e-4 {
+			t.Errorf("direction %v: nearest point of the sphere has depth %g; want 0", dir, p[2])
+		}
+	}
+}
+
+func TestShadowMatrixSpot(t *testing.T) {
+	position := glmath.Vec3{1, 2, 3}
+	const near, far = 0.5, 20
+	for _, dir := range shadowDirections {
+		var s ShadowMap
+		target := position.Add(dir.Mul(5))
+		s.SetSpotLight(position, target, math.Pi/3, near, far)
+		corners := frustumCorners(t, &s)
+		checkShadowCorners(t, "spot", &s, corners)
+
+		m := s.ShadowMatrix()
+		axis := dir.Normalize()
+		for _, tt := range []struct {
+			dist, depth float32
+		}{{near, 0}, {far, 1}} {
+			p := m.MulPoint(position.Add(axis.Mul(tt.dist)))
+			if math.Abs(float64(p[0]-0.5)) > 1e-4 || math.Abs(float64(p[1]-0.5)) > 1e-4 || math.Abs(float64(p[2]-tt.depth)) > 1e-3 {
+				t.Errorf("direction %v: point at distance %g maps to %v; want (0.5, 0.5, %g)", dir, tt.dist, p, tt.depth)
+			}
+		}
+		for _, d := range []float32{1, 5, 19} {
+			if p := m.MulPoint(position.Add(axis.Mul(d))); !inUnitCube(p) {
+				t.Errorf("direction %v: point at distance %g maps to %v; want it in [0, 1]", dir, d, p)
+			}
+		}
+	}
+}
+
+func TestShadowUp(t *testing.T) {
+	dirs := append([]glmath.Vec3(nil), shadowDirections...)
+	for lat := -90; lat <= 90; lat++ {
+		for lon := 0; lon < 360; lon += 15 {
+			phi, theta := float64(lat)*math.Pi/180, float64(lon)*math.Pi/180
+			dirs = append(dirs, glmath.Vec3{
+				float32(math.Cos(phi) * math.Cos(theta)),
+				float32(math.Sin(phi)),
+				float32(math.Cos(phi) * math.Sin(theta)),
+			})
+		}
+	}
+	for _, dir := range dirs {
+		d := dir.Normalize()
+		up := shadowUp(d)
+		if l := d.Cross(up).Len(); l < 0.1 {
+			t.Errorf("shadowUp(%v) = %v is almost parallel to it (|cross| = %g)", d, up, l)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+)
+
+// unknown marks an integer piece of cached state that has not been set
+// through the Context yet. GL enums and sizes are never negative.
+const unknown = -1
+
+type textureBinding struct {
+	unit   int
+	target int
+}
+
+// stateCache shadows the parts of the GL state that are changed most often,
+// so that calls which would not change anything can be skipped before they
+// cross into JS. Object bindings which are not known yet are held as the
+// zero js.Value (undefined), which never equals a real object or null.
+type stateCache struct {
+	saved int
+
+	arrayBuffer        js.Value
+	elementArrayBuffer js.Value
+	framebuffer        js.Value
+	renderbuffer       js.Value
+	program            js.Value
+	activeTexture      int
+	textures           map[textureBinding]js.Value
+
+	caps map[int]bool
+
+	blendSrcRGB, blendDstRGB     int
+	blendSrcAlpha, blendDstAlpha int
+	blendModeRGB, blendModeAlpha int
+	depthFunc                    int
+	depthMask                    int
+	cullFace                     int
+	frontFace                    int
+
+	viewport      [4]int
+	viewportKnown bool
+	scissor       [4]int
+	scissorKnown  bool
+}
+
+func newStateCache() *stateCache {
+	s := new(stateCache)
+	s.reset()
+	return s
+}
+
+// reset forgets everything that has been tracked, so that the next call
+// for each piece of state goes through to JS.
+func (s *stateCache) reset() {
+	s.arrayBuffer = js.Value{}
+	s.elementArrayBuffer = js.Value{}
+	s.framebuffer = js.Value{}
+	s.renderbuffer = js.Value{}
+	s.program = js.Value{}
+	s.activeTexture = unknown
+	s.textures = make(map[textureBinding]js.Value)
+	s.caps = make(map[int]bool)
+	s.blendSrcRGB, s.blendDstRGB = unknown, unknown
+	s.blendSrcAlpha, s.blendDstAlpha = unknown, unknown
+	s.blendModeRGB, s.blendModeAlpha = unknown, unknown
+	s.depthFunc = unknown
+	s.depthMask = unknown
+	s.cullFace = unknown
+	s.frontFace = unknown
+	s.viewportKnown = false
+	s.scissorKnown = false
+}
+
+// valueOf turns an optional object argument into the value WebGL sees,
+// with a nil pointer meaning null.
+func valueOf(obj *js.Value) js.Value {
+	if obj == nil {
+		return js.Null()
+	}
+	return *obj
+}
+
+// setObject records obj in slot and reports whether it was already there.
+func (s *stateCache) setObject(slot *js.Value, obj *js.Value) bool {
+	v := valueOf(obj)
+	if slot.Equal(v) {
+		s.saved++
+		return true
+	}
+	*slot = v
+	return false
+}
+
+// setInt records v in slot and reports whether it was already there.
+func (s *stateCache) setInt(slot *int, v int) bool {
+	if *slot == v {
+		s.saved++
+		return true
+	}
+	*slot = v
+	return false
+}
+
+func (s *stateCache) bindBuffer(target int, buffer *js.Value) bool {
+	switch target {
+	case ARRAY_BUFFER:
+		return s.setObject(&s.arrayBuffer, buffer)
+	case ELEMENT_ARRAY_BUFFER:
+		return s.setObject(&s.elementArrayBuffer, buffer)
+	}
+	return false
+}
+
+func (s *stateCache) bindFramebuffer(target int, framebuffer *js.Value) bool {
+	if target != FRAMEBUFFER {
+		return false
+	}
+	return s.setObject(&s.framebuffer, framebuffer)
+}
+
+func (s *stateCache) bindRenderbuffer(target int, renderbuffer *js.Value) bool {
+	if target != RENDERBUFFER {
+		return false
+	}
+	return s.setObject(&s.renderbuffer, renderbuffer)
+}
+
+func (s *stateCache) bindTexture(target int, texture *js.Value) bool {
+	if s.activeTexture == unknown {
+		return false
+	}
+	key := textureBinding{s.activeTexture, target}
+	cur := s.textures[key]
+	skip := s.setObject(&cur, texture)
+	s.textures[key] = cur
+	return skip
+}
+
+func (s *stateCache) useProgram(program *js.Value) bool {
+	return s.setObject(&s.program, program)
+}
+
+func (s *stateCache) setCap(capability int, enabled bool) bool {
+	if cur, ok := s.caps[capability]; ok && cur == enabled {
+		s.saved++
+		return true
+	}
+	s.caps[capability] = enabled
+	return false
+}
+
+func (s *stateCache) blendFunc(srcRGB, dstRGB, srcAlpha, dstAlpha int) bool {
+	if s.blendSrcRGB == srcRGB && s.blendDstRGB == dstRGB &&
+		s.blendSrcAlpha == srcAlpha && s.blendDstAlpha == dstAlpha {
+		s.saved++
+		return true
+	}
+	s.blendSrcRGB, s.blendDstRGB = srcRGB, dstRGB
+	s.blendSrcAlpha, s.blendDstAlpha = srcAlpha, dstAlpha
+	return false
+}
+
+func (s *stateCache) blendEquation(modeRGB, modeAlpha int) bool {
+	if s.blendModeRGB == modeRGB && s.blendModeAlpha == modeAlpha {
+		s.saved++
+		return true
+	}
+	s.blendModeRGB, s.blendModeAlpha = modeRGB, modeAlpha
+	return false
+}
+
+func (s *stateCache) setRect(slot *[4]int, known *bool, x, y, width, height int) bool {
+	r := [4]int{x, y, width, height}
+	if *known && *slot == r {
+		s.saved++
+		return true
+	}
+	*slot = r
+	*known = true
+	return false
+}
+
+// forgetObject drops any binding of obj, because deleting a bound object
+// changes the binding behind our back.
+func (s *stateCache) forgetObject(obj *js.Value) {
+	v := valueOf(obj)
+	for _, slot := range []*js.Value{&s.arrayBuffer, &s.elementArrayBuffer, &s.framebuffer, &s.renderbuffer, &s.program} {
+		if slot.Equal(v) {
+			*slot = js.Value{}
+		}
+	}
+	for key, cur := range s.textures {
+		if cur.Equal(v) {
+			delete(s.textures, key)
+		}
+	}
+}
+
+// EnableStateCache turns on tracking of bound objects, the active texture
+// unit, capabilities, blend, depth and cull state and the viewport and
+// scissor boxes. While enabled, calls that would not change that state are
+// not passed on to WebGL. Only changes made through this Context are seen,
+// so call ResetStateCache after changing the state through Object directly.
+func (c *Context) EnableStateCache() {
+	if c.state == nil {
+		c.state = newStateCache()
+	}
+}
+
+// DisableStateCache turns off state tracking. Every call goes through to
+// WebGL again.
+func (c *Context) DisableStateCache() {
+	c.state = nil
+}
+
+// ResetStateCache forgets all of the tracked state, so the next call for
+// each piece of state goes through to WebGL. The saved call count is kept.
+func (c *Context) ResetStateCache() {
+	if c.state != nil {
+		c.state.reset()
+	}
+}
+
+// Returns how many calls the state cache has skipped since it was enabled.
+func (c *Context) SavedCalls() int {
+	if c.state == nil {
+		return 0
+	}
+	return c.state.saved
+}
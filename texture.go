@@ -0,0 +1,313 @@
+// +build wasm
+
+package webgl
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"syscall/js"
+)
+
+// Texture is a 2D texture together with the size it was uploaded at.
+type Texture struct {
+	Object *js.Value
+	Width  int
+	Height int
+
+	// Mipmapped is true if mipmaps were generated for the texture.
+	Mipmapped bool
+
+	gl *Context
+}
+
+// TextureOptions controls how an image is turned into a texture.
+type TextureOptions struct {
+	// Filters and wrap modes, as passed to TexParameteri.
+	MinFilter int
+	MagFilter int
+	WrapS     int
+	WrapT     int
+
+	// If Mipmaps is true, mipmaps are generated after uploading.
+	Mipmaps bool
+
+	// WebGL 1 can not generate mipmaps for, or repeat, textures whose
+	// sides are not a power of two. If ResizeToPowerOfTwo is true, such
+	// images are scaled up to the next power of two. Otherwise they are
+	// uploaded as they are, with wrapping clamped to the edge and mipmaps
+	// turned off.
+	ResizeToPowerOfTwo bool
+
+	// If FlipY is true, the image is flipped so its first row ends up at
+	// the bottom of the texture, as texture coordinates expect.
+	FlipY bool
+
+	// Anisotropy is the anisotropic filtering level, clamped to what the
+	// device supports. Levels of 1 or less, and devices without
+	// EXT_texture_filter_anisotropic, get no anisotropic filtering.
+	Anisotropy float32
+}
+
+// Returns the default texture options: trilinear filtering, repeat
+// wrapping and mipmaps, scaling images to a power of two where needed.
+func DefaultTextureOptions() *TextureOptions {
+	return &TextureOptions{
+		MinFilter:          LINEAR_MIPMAP_LINEAR,
+		MagFilter:          LINEAR,
+		WrapS:              REPEAT,
+		WrapT:              REPEAT,
+		Mipmaps:            true,
+		ResizeToPowerOfTwo: true,
+		FlipY:              true,
+	}
+}
+
+// Returns whether n is a power of two.
+func isPowerOfTwo(n int) bool {
+	return n > 0 && n&(n-1) == 0
+}
+
+// Returns the smallest power of two that is not less than n.
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// Returns the min filter to use instead of a mipmap filter when a texture
+// has no mipmaps.
+func withoutMipmaps(minFilter int) int {
+	switch minFilter {
+	case NEAREST_MIPMAP_NEAREST, NEAREST_MIPMAP_LINEAR:
+		return NEAREST
+	case LINEAR_MIPMAP_NEAREST, LINEAR_MIPMAP_LINEAR:
+		return LINEAR
+	}
+	return minFilter
+}
+
+// Returns whether a texture of the given size can have mipmaps with opts.
+func canMipmap(width, height int, opts *TextureOptions) bool {
+	return opts.Mipmaps && isPowerOfTwo(width) && isPowerOfTwo(height)
+}
+
+// Sets the filters and wrap modes of the texture bound to TEXTURE_2D,
+// turning off what the texture size does not allow and replacing mipmap
+// filters if the texture has no mipmaps.
+func (c *Context) setTextureParameters(width, height int, opts *TextureOptions, mipmapped bool) {
+	minFilter, wrapS, wrapT := opts.MinFilter, opts.WrapS, opts.WrapT
+	if !isPowerOfTwo(width) || !isPowerOfTwo(height) {
+		wrapS, wrapT = CLAMP_TO_EDGE, CLAMP_TO_EDGE
+	}
+	if !mipmapped {
+		minFilter = withoutMipmaps(minFilter)
+	}
+	c.TexParameteri(TEXTURE_2D, TEXTURE_MIN_FILTER, minFilter)
+	c.TexParameteri(TEXTURE_2D, TEXTURE_MAG_FILTER, opts.MagFilter)
+	c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_S, wrapS)
+	c.TexParameteri(TEXTURE_2D, TEXTURE_WRAP_T, wrapT)
+	c.SetAnisotropy(TEXTURE_2D, opts.Anisotropy)
+}
+
+// Sets the parameters of the texture bound to TEXTURE_2D and generates its
+// mipmaps if opts asks for them and the size allows it. Returns whether
+// mipmaps were generated.
+func (c *Context) applyTextureOptions(width, height int, opts *TextureOptions) bool {
+	mipmaps := canMipmap(width, height, opts)
+	c.setTextureParameters(width, height, opts, mipmaps)
+	if mipmaps {
+		c.GenerateMipmap(TEXTURE_2D)
+	}
+	return mipmaps
+}
+
+// NewTextureFromBytes decodes a PNG or JPEG image in Go and uploads it as
+// a texture. If opts is nil, DefaultTextureOptions is used.
+func (c *Context) NewTextureFromBytes(data []byte, opts *TextureOptions) (*Texture, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return c.NewTextureFromImage(img, opts)
+}
+
+// NewTextureFromImage uploads an image as an RGBA texture. If opts is nil,
+// DefaultTextureOptions is used.
+func (c *Context) NewTextureFromImage(img image.Image, opts *TextureOptions) (*Texture, error) {
+	if opts == nil {
+		opts = DefaultTextureOptions()
+	}
+	b := img.Bounds()
+	if b.Empty() {
+		return nil, errors.New("image is empty")
+	}
+	width, height := b.Dx(), b.Dy()
+	if opts.ResizeToPowerOfTwo {
+		width, height = nextPowerOfTwo(width), nextPowerOfTwo(height)
+	}
+	rgba := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	if width != b.Dx() || height != b.Dy() {
+		rgba = resizeNRGBA(rgba, width, height)
+	}
+	if opts.FlipY {
+		flipRows(rgba.Pix, rgba.Stride)
+	}
+
+	tex := &Texture{Object: c.CreateTexture(), Width: width, Height: height, gl: c}
+	c.BindTexture(TEXTURE_2D, tex.Object)
+	c.TexImage2DPixels(TEXTURE_2D, 0, RGBA, width, height, 0, RGBA, UNSIGNED_BYTE, rgba.Pix)
+	tex.Mipmapped = c.applyTextureOptions(width, height, opts)
+	return tex, nil
+}
+
+// NewTextureFromImageBitmap uploads an ImageBitmap, such as one returned
+// by DecodeImageBitmap, as an RGBA texture. Resizing and flipping are done
+// by the browser. If opts is nil, DefaultTextureOptions is used.
+func (c *Context) NewTextureFromImageBitmap(bitmap js.Value, opts *TextureOptions) (*Texture, error) {
+	if opts == nil {
+		opts = DefaultTextureOptions()
+	}
+	width, height := bitmap.Get("width").Int(), bitmap.Get("height").Int()
+	if width == 0 || height == 0 {
+		return nil, errors.New("image is empty")
+	}
+	options := map[string]interface{}{}
+	if opts.ResizeToPowerOfTwo && (!isPowerOfTwo(width) || !isPowerOfTwo(height)) {
+		width, height = nextPowerOfTwo(width), nextPowerOfTwo(height)
+		options["resizeWidth"] = width
+		options["resizeHeight"] = height
+		options["resizeQuality"] = "high"
+	}
+	// UNPACK_FLIP_Y_WEBGL does not apply to ImageBitmaps.
+	if opts.FlipY {
+		options["imageOrientation"] = "flipY"
+	}
+	var converted js.Value
+	if len(options) > 0 {
+		var err error
+		converted, err = awaitPromise(js.Global().Call("createImageBitmap", bitmap, options))
+		if err != nil {
+			return nil, err
+		}
+		bitmap = converted
+	}
+
+	tex := &Texture{Object: c.CreateTexture(), Width: width, Height: height, gl: c}
+	c.BindTexture(TEXTURE_2D, tex.Object)
+	c.TexImage2D(TEXTURE_2D, 0, RGBA, RGBA, UNSIGNED_BYTE, &bitmap)
+	if !converted.IsUndefined() {
+		// Free the bitmap made here now rather than when it is garbage
+		// collected, once the upload has run. The caller's bitmap is left
+		// alone.
+		c.FlushBatch()
+		converted.Call("close")
+	}
+	tex.Mipmapped = c.applyTextureOptions(width, height, opts)
+	return tex, nil
+}
+
+// Delete deletes the texture.
+func (t *Texture) Delete() {
+	if t.Object != nil {
+		t.gl.DeleteTexture(t.Object)
+		t.Object = nil
+	}
+}
+
+// DecodeImageBitmap has the browser decode an encoded image, such as a PNG
+// or JPEG file, into an ImageBitmap. It blocks until decoding is done.
+func DecodeImageBitmap(data []byte) (js.Value, error) {
+	arr := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(arr, data)
+	blob := js.Global().Get("Blob").New([]interface{}{arr})
+	return awaitPromise(js.Global().Call("createImageBitmap", blob))
+}
+
+// Waits for a promise to settle, returning its value or its rejection
+// reason as an error.
+func awaitPromise(promise js.Value) (js.Value, error) {
+	type result struct {
+		value js.Value
+		err   error
+	}
+	ch := make(chan result, 1)
+	resolve := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		ch <- result{value: args[0]}
+		return nil
+	})
+	reject := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		ch <- result{err: errors.New(args[0].Call("toString").String())}
+		return nil
+	})
+	promise.Call("then", resolve, reject)
+	r := <-ch
+	resolve.Release()
+	reject.Release()
+	return r.value, r.err
+}
+
+// Flips the rows of an image in place.
+func flipRows(pix []uint8, stride int) {
+	rows := len(pix) / stride
+	tmp := make([]uint8, stride)
+	for top, bottom := 0, rows-1; top < bottom; top, bottom = top+1, bottom-1 {
+		t := pix[top*stride : (top+1)*stride]
+		b := pix[bottom*stride : (bottom+1)*stride]
+		copy(tmp, t)
+		copy(t, b)
+		copy(b, tmp)
+	}
+}
+
+// Returns src scaled to the given size with bilinear filtering.
+func resizeNRGBA(src *image.NRGBA, width, height int) *image.NRGBA {
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+	for y := 0; y < height; y++ {
+		fy := (float32(y)+0.5)*float32(sh)/float32(height) - 0.5
+		y0, wy := splitCoord(fy, sh)
+		y1 := y0 + 1
+		if y1 >= sh {
+			y1 = sh - 1
+		}
+		for x := 0; x < width; x++ {
+			fx := (float32(x)+0.5)*float32(sw)/float32(width) - 0.5
+			x0, wx := splitCoord(fx, sw)
+			x1 := x0 + 1
+			if x1 >= sw {
+				x1 = sw - 1
+			}
+			p00 := src.Pix[y0*src.Stride+x0*4:]
+			p10 := src.Pix[y0*src.Stride+x1*4:]
+			p01 := src.Pix[y1*src.Stride+x0*4:]
+			p11 := src.Pix[y1*src.Stride+x1*4:]
+			d := dst.Pix[y*dst.Stride+x*4:]
+			for i := 0; i < 4; i++ {
+				top := float32(p00[i])*(1-wx) + float32(p10[i])*wx
+				bottom := float32(p01[i])*(1-wx) + float32(p11[i])*wx
+				d[i] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// Splits a sample coordinate into the index of the texel before it and
+// the weight of the texel after it, clamped to [0, size).
+func splitCoord(f float32, size int) (int, float32) {
+	if f <= 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= size-1 {
+		return size - 1, 0
+	}
+	return i, f - float32(i)
+}
@@ -0,0 +1,68 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+
+	"github.com/justinclift/webgl/glmath"
+)
+
+// scratchArrays is a small Float32Array that uniform values are copied
+// into, with views of every length up to 16 floats, so setting a uniform
+// does not create new typed arrays.
+type scratchArrays struct {
+	bytes  js.Value
+	floats [17]js.Value
+}
+
+// Returns data as a Float32Array. Outside of batched mode this reuses the
+// context's scratch array; batched calls keep their arguments until the
+// batch is flushed, so they get an array of their own.
+func (c *Context) float32Array(data []float32) js.Value {
+	if c.batch != nil || len(data) >= len(scratchArrays{}.floats) {
+		return SliceToTypedArray(data)
+	}
+	if c.scratch == nil {
+		buf := js.Global().Get("ArrayBuffer").New(4 * (len(scratchArrays{}.floats) - 1))
+		c.scratch = &scratchArrays{bytes: js.Global().Get("Uint8Array").New(buf)}
+	}
+	view := c.scratch.floats[len(data)]
+	if view.IsUndefined() {
+		buf := c.scratch.bytes.Get("buffer")
+		view = js.Global().Get("Float32Array").New(buf, 0, len(data))
+		c.scratch.floats[len(data)] = view
+	}
+	js.CopyBytesToJS(c.scratch.bytes, sliceToByteSlice(data))
+	return view
+}
+
+// Sets a mat2 uniform.
+func (c *Context) UniformMat2(location *js.Value, m *glmath.Mat2) {
+	c.call("uniformMatrix2fv", location, false, c.float32Array(m[:]))
+}
+
+// Sets a mat3 uniform.
+func (c *Context) UniformMat3(location *js.Value, m *glmath.Mat3) {
+	c.call("uniformMatrix3fv", location, false, c.float32Array(m[:]))
+}
+
+// Sets a mat4 uniform.
+func (c *Context) UniformMat4(location *js.Value, m *glmath.Mat4) {
+	c.call("uniformMatrix4fv", location, false, c.float32Array(m[:]))
+}
+
+// Sets a vec2 uniform.
+func (c *Context) UniformVec2(location *js.Value, v glmath.Vec2) {
+	c.Uniform2f(location, v[0], v[1])
+}
+
+// Sets a vec3 uniform.
+func (c *Context) UniformVec3(location *js.Value, v glmath.Vec3) {
+	c.Uniform3f(location, v[0], v[1], v[2])
+}
+
+// Sets a vec4 uniform.
+func (c *Context) UniformVec4(location *js.Value, v glmath.Vec4) {
+	c.Uniform4f(location, v[0], v[1], v[2], v[3])
+}
@@ -0,0 +1,286 @@
+// +build wasm
+
+package webgl
+
+import (
+	"syscall/js"
+)
+
+// OES_vertex_array_object
+const VERTEX_ARRAY_BINDING_OES = 0x85B5
+
+// VertexArray is a vertex array object: the enabled vertex attributes,
+// their pointers and divisors, and the element array buffer, which are
+// all set again by a single bind.
+//
+// VertexArrays use OES_vertex_array_object where it is supported. Where it
+// is not, the Context records the attribute calls made while a vertex
+// array is bound and replays them when it is bound again, so the same code
+// works either way, if with more calls into JS.
+type VertexArray struct {
+	// Object is the vertex array object, or nil if it is emulated.
+	Object *js.Value
+
+	state *vertexArrayState
+	gl    *Context
+}
+
+// vertexAttrib is the recorded state of one vertex attribute.
+type vertexAttrib struct {
+	enabled    bool
+	pointer    bool
+	buffer     js.Value
+	size       int
+	typ        int
+	normalized bool
+	stride     int
+	offset     int
+	divisor    int
+}
+
+// vertexArrayState is the recorded state of an emulated vertex array. An
+// undefined elementBuffer is one that has not been recorded, which is only
+// the case for the default vertex array.
+type vertexArrayState struct {
+	attribs       map[int]*vertexAttrib
+	elementBuffer js.Value
+}
+
+func newVertexArrayState() *vertexArrayState {
+	return &vertexArrayState{attribs: make(map[int]*vertexAttrib), elementBuffer: js.Null()}
+}
+
+func (s *vertexArrayState) attrib(index int) *vertexAttrib {
+	a := s.attribs[index]
+	if a == nil {
+		a = &vertexAttrib{}
+		s.attribs[index] = a
+	}
+	return a
+}
+
+// vertexArrayEmulation tracks the vertex array state of a Context once it
+// has created an emulated vertex array.
+type vertexArrayEmulation struct {
+	bound       *vertexArrayState
+	defaults    *vertexArrayState
+	arrayBuffer js.Value
+	replaying   bool
+}
+
+// Returns the state calls should be recorded into, or nil if they are
+// being replayed.
+func (e *vertexArrayEmulation) recording() *vertexArrayState {
+	if e == nil || e.replaying {
+		return nil
+	}
+	return e.bound
+}
+
+func (e *vertexArrayEmulation) bindBuffer(target int, buffer *js.Value) {
+	switch target {
+	case ARRAY_BUFFER:
+		e.arrayBuffer = valueOf(buffer)
+	case ELEMENT_ARRAY_BUFFER:
+		if s := e.recording(); s != nil {
+			s.elementBuffer = valueOf(buffer)
+		}
+	}
+}
+
+func (e *vertexArrayEmulation) enable(index int, enabled bool) {
+	if s := e.recording(); s != nil {
+		s.attrib(index).enabled = enabled
+	}
+}
+
+func (e *vertexArrayEmulation) pointer(index, size, typ int, normalized bool, stride, offset int) {
+	if s := e.recording(); s != nil {
+		a := s.attrib(index)
+		a.pointer = true
+		a.buffer = e.arrayBuffer
+		a.size, a.typ, a.normalized = size, typ, normalized
+		a.stride, a.offset = stride, offset
+	}
+}
+
+func (e *vertexArrayEmulation) divisor(index, divisor int) {
+	if s := e.recording(); s != nil {
+		s.attrib(index).divisor = divisor
+	}
+}
+
+// Returns the OES_vertex_array_object extension, or the zero js.Value if
+// it is not supported.
+func (c *Context) vertexArrayExtension() js.Value {
+	ext, _ := c.extension("OES_vertex_array_object")
+	return ext
+}
+
+// Creates a vertex array, emulated if OES_vertex_array_object is not
+// supported.
+func (c *Context) CreateVertexArray() *VertexArray {
+	va := &VertexArray{gl: c}
+	if ext := c.vertexArrayExtension(); !isNullish(ext) {
+		c.FlushBatch()
+		obj := ext.Call("createVertexArrayOES")
+		va.Object = &obj
+		return va
+	}
+	if c.vao == nil {
+		defaults := c.defaultVertexArrayState()
+		c.vao = &vertexArrayEmulation{
+			bound:       defaults,
+			defaults:    defaults,
+			arrayBuffer: c.callResult("getParameter", ARRAY_BUFFER_BINDING),
+		}
+	}
+	va.state = newVertexArrayState()
+	return va
+}
+
+// defaultVertexArrayState reads the state of the attributes that are
+// enabled when emulation starts, so binding the default vertex array
+// restores them.
+func (c *Context) defaultVertexArrayState() *vertexArrayState {
+	s := newVertexArrayState()
+	s.elementBuffer = js.Value{}
+	max := c.callResult("getParameter", MAX_VERTEX_ATTRIBS)
+	if max.Type() != js.TypeNumber {
+		return s
+	}
+	for i := 0; i < max.Int(); i++ {
+		if !c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_ENABLED).Truthy() {
+			continue
+		}
+		a := s.attrib(i)
+		a.enabled = true
+		buffer := c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_BUFFER_BINDING)
+		if isNullish(buffer) {
+			continue
+		}
+		a.pointer = true
+		a.buffer = buffer
+		a.size = c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_SIZE).Int()
+		a.typ = c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_TYPE).Int()
+		a.normalized = c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_NORMALIZED).Truthy()
+		a.stride = c.callResult("getVertexAttrib", i, VERTEX_ATTRIB_ARRAY_STRIDE).Int()
+		a.offset = c.callResult("getVertexAttribOffset", i, VERTEX_ATTRIB_ARRAY_POINTER).Int()
+	}
+	return s
+}
+
+// Binds a vertex array, or the default one if va is nil.
+func (c *Context) BindVertexArray(va *VertexArray) {
+	if c.vao == nil {
+		if ext := c.vertexArrayExtension(); !isNullish(ext) {
+			var obj *js.Value
+			if va != nil {
+				obj = va.Object
+			}
+			c.FlushBatch()
+			ext.Call("bindVertexArrayOES", valueOf(obj))
+			// The element array buffer binding belongs to the vertex array.
+			if c.state != nil {
+				c.state.elementArrayBuffer = js.Value{}
+			}
+			return
+		}
+	}
+	if c.vao == nil {
+		// Nothing has been recorded, so there is nothing to switch.
+		return
+	}
+	next := c.vao.defaults
+	if va != nil && va.state != nil {
+		next = va.state
+	}
+	c.replayVertexArray(c.vao.bound, next)
+	c.vao.bound = next
+}
+
+// replayVertexArray switches the attribute state from that of one
+// emulated vertex array to that of another.
+func (c *Context) replayVertexArray(from, to *vertexArrayState) {
+	if from == to {
+		return
+	}
+	c.vao.replaying = true
+	defer func() { c.vao.replaying = false }()
+	arrayBuffer := c.vao.arrayBuffer
+
+	var instanced *InstancedArrays
+	setDivisor := func(index, divisor int) {
+		if instanced == nil {
+			var err error
+			if instanced, err = c.InstancedArrays(); err != nil {
+				return
+			}
+		}
+		instanced.VertexAttribDivisor(index, divisor)
+	}
+
+	for index, a := range from.attribs {
+		b := to.attribs[index]
+		if a.enabled && (b == nil || !b.enabled) {
+			c.DisableVertexAttribArray(index)
+		}
+		if a.divisor != 0 && (b == nil || b.divisor == 0) {
+			setDivisor(index, 0)
+		}
+	}
+	for index, b := range to.attribs {
+		a := from.attribs[index]
+		if b.pointer {
+			buf := b.buffer
+			c.BindBuffer(ARRAY_BUFFER, &buf)
+			c.VertexAttribPointer(index, b.size, b.typ, b.normalized, b.stride, b.offset)
+		}
+		if b.enabled && (a == nil || !a.enabled) {
+			c.EnableVertexAttribArray(index)
+		}
+		if b.divisor != 0 && (a == nil || a.divisor != b.divisor) {
+			setDivisor(index, b.divisor)
+		}
+	}
+	if elements := to.elementBuffer; !elements.IsUndefined() {
+		c.BindBuffer(ELEMENT_ARRAY_BUFFER, &elements)
+	}
+	c.BindBuffer(ARRAY_BUFFER, &arrayBuffer)
+}
+
+// Returns whether the vertex array is emulated in Go.
+func (va *VertexArray) Emulated() bool {
+	return va.Object == nil
+}
+
+// Bind binds the vertex array.
+func (va *VertexArray) Bind() {
+	va.gl.BindVertexArray(va)
+}
+
+// Unbind binds the default vertex array.
+func (va *VertexArray) Unbind() {
+	va.gl.BindVertexArray(nil)
+}
+
+// Delete deletes the vertex array. If it is bound, the default vertex
+// array is bound instead.
+func (va *VertexArray) Delete() {
+	c := va.gl
+	if va.Object != nil {
+		if ext := c.vertexArrayExtension(); !isNullish(ext) {
+			c.FlushBatch()
+			ext.Call("deleteVertexArrayOES", *va.Object)
+		}
+		va.Object = nil
+		if c.state != nil {
+			c.state.elementArrayBuffer = js.Value{}
+		}
+		return
+	}
+	if va.state != nil && c.vao != nil && c.vao.bound == va.state {
+		c.BindVertexArray(nil)
+	}
+	va.state = nil
+}
@@ -350,9 +350,25 @@ func DefaultAttributes() *ContextAttributes {
 
 type Context struct {
 	Object js.Value
+
+	// Canvas is the HTML canvas element or OffscreenCanvas the context
+	// was created from.
+	Canvas js.Value
+
+	state   *stateCache
+	batch   *batch
+	exts    map[string]js.Value
+	scratch *scratchArrays
+	vao     *vertexArrayEmulation
+	loss    *contextLoss
+
+	// savedErrors holds errors read from the context on the caller's
+	// behalf, which GetError reports before asking the context.
+	savedErrors []int
 }
 
 // NewContext takes an HTML5 canvas object and optional context attributes.
+// An OffscreenCanvas can be passed as well, see NewOffscreenContext.
 // If an error is returned it means you won't have access to WebGL
 // functionality.
 func NewContext(canvas *js.Value, ca *ContextAttributes) (*Context, error) {
@@ -362,23 +378,26 @@ func NewContext(canvas *js.Value, ca *ContextAttributes) (*Context, error) {
 
 	// Info on Context Attributes: https://developer.mozilla.org/en-US/docs/Web/API/HTMLCanvasElement/getContext
 	// (search for "WebGL context attributes" on the page)
-	attrStr := "{" +
-		"alpha: " + boolStr(ca.Alpha) +
-		", depth: " + boolStr(ca.Depth) +
-		", stencil: " + boolStr(ca.Stencil) +
-		", antialias: " + boolStr(ca.Antialias) +
-		", premultipliedAlpha: " + boolStr(ca.PremultipliedAlpha) +
-		", preserveDrawingBuffer: " + boolStr(ca.PreserveDrawingBuffer) +
-		"}"
-	gl := canvas.Call("getContext", "webgl", attrStr)
-	if gl.Equal(js.Undefined()) {
-		gl = canvas.Call("getContext", "experimental-webgl", attrStr)
-		if gl.Equal(js.Undefined()) {
-			return nil, errors.New("creating a webgl context has failed")
-		}
+	attrs := map[string]interface{}{
+		"alpha":                 ca.Alpha,
+		"depth":                 ca.Depth,
+		"stencil":               ca.Stencil,
+		"antialias":             ca.Antialias,
+		"premultipliedAlpha":    ca.PremultipliedAlpha,
+		"preserveDrawingBuffer": ca.PreserveDrawingBuffer,
+	}
+	gl := canvas.Call("getContext", "webgl", attrs)
+	// OffscreenCanvas throws for context types it does not know, and has
+	// never supported the experimental name.
+	if isNullish(gl) && !isOffscreenCanvas(*canvas) {
+		gl = canvas.Call("getContext", "experimental-webgl", attrs)
+	}
+	if isNullish(gl) {
+		return nil, errors.New("creating a webgl context has failed")
 	}
 	ctx := new(Context)
 	ctx.Object = gl
+	ctx.Canvas = *canvas
 	return ctx, nil
 }
 
@@ -386,7 +405,7 @@ func NewContext(canvas *js.Value, ca *ContextAttributes) (*Context, error) {
 // be different than what was requested on context creation if the
 // browser's implementation doesn't support a feature.
 func (c *Context) GetContextAttributes() ContextAttributes {
-	ca := c.Object.Call("getContextAttributes")
+	ca := c.callResult("getContextAttributes")
 	return ContextAttributes{
 		ca.Get("alpha").Bool(),
 		ca.Get("depth").Bool(),
@@ -399,181 +418,231 @@ func (c *Context) GetContextAttributes() ContextAttributes {
 
 // Specifies the active texture unit.
 func (c *Context) ActiveTexture(texture int) {
-	c.Object.Call("activeTexture", texture)
+	if c.state != nil && c.state.setInt(&c.state.activeTexture, texture) {
+		return
+	}
+	c.call("activeTexture", texture)
 }
 
 // Attaches a WebGLShader object to a WebGLProgram object.
 func (c *Context) AttachShader(program *js.Value, shader *js.Value) {
-	c.Object.Call("attachShader", program, shader)
+	c.call("attachShader", program, shader)
 }
 
 // Binds a generic vertex index to a user-defined attribute variable.
 func (c *Context) BindAttribLocation(program *js.Value, index int, name string) {
-	c.Object.Call("bindAttribLocation", program, index, name)
+	c.call("bindAttribLocation", program, index, name)
 }
 
 // Associates a buffer with a buffer target.
 func (c *Context) BindBuffer(target int, buffer *js.Value) {
-	c.Object.Call("bindBuffer", target, buffer)
+	if c.vao != nil {
+		c.vao.bindBuffer(target, buffer)
+	}
+	if c.state != nil && c.state.bindBuffer(target, buffer) {
+		return
+	}
+	c.call("bindBuffer", target, buffer)
 }
 
 // Associates a WebGLFramebuffer object with the FRAMEBUFFER bind target.
 func (c *Context) BindFramebuffer(target int, framebuffer *js.Value) {
-	c.Object.Call("bindFramebuffer", target, framebuffer)
+	if c.state != nil && c.state.bindFramebuffer(target, framebuffer) {
+		return
+	}
+	c.call("bindFramebuffer", target, framebuffer)
 }
 
 // Binds a WebGLRenderbuffer object to be used for rendering.
 func (c *Context) BindRenderbuffer(target int, renderbuffer *js.Value) {
-	c.Object.Call("bindRenderbuffer", target, renderbuffer)
+	if c.state != nil && c.state.bindRenderbuffer(target, renderbuffer) {
+		return
+	}
+	c.call("bindRenderbuffer", target, renderbuffer)
 }
 
 // Binds a named texture object to a target.
 func (c *Context) BindTexture(target int, texture *js.Value) {
-	c.Object.Call("bindTexture", target, texture)
+	if c.state != nil && c.state.bindTexture(target, texture) {
+		return
+	}
+	c.call("bindTexture", target, texture)
 }
 
 // The GL_BLEND_COLOR may be used to calculate the source and destination blending factors.
 func (c *Context) BlendColor(r, g, b, a float64) {
-	c.Object.Call("blendColor", r, g, b, a)
+	c.call("blendColor", r, g, b, a)
 }
 
 // Sets the equation used to blend RGB and Alpha values of an incoming source
 // fragment with a destination values as stored in the fragment's frame buffer.
 func (c *Context) BlendEquation(mode int) {
-	c.Object.Call("blendEquation", mode)
+	if c.state != nil && c.state.blendEquation(mode, mode) {
+		return
+	}
+	c.call("blendEquation", mode)
 }
 
 // Controls the blending of an incoming source fragment's R, G, B, and A values
 // with a destination R, G, B, and A values as stored in the fragment's WebGLFramebuffer.
 func (c *Context) BlendEquationSeparate(modeRGB, modeAlpha int) {
-	c.Object.Call("blendEquationSeparate", modeRGB, modeAlpha)
+	if c.state != nil && c.state.blendEquation(modeRGB, modeAlpha) {
+		return
+	}
+	c.call("blendEquationSeparate", modeRGB, modeAlpha)
 }
 
 // Sets the blending factors used to combine source and destination pixels.
 func (c *Context) BlendFunc(sfactor, dfactor int) {
-	c.Object.Call("blendFunc", sfactor, dfactor)
+	if c.state != nil && c.state.blendFunc(sfactor, dfactor, sfactor, dfactor) {
+		return
+	}
+	c.call("blendFunc", sfactor, dfactor)
 }
 
 // Sets the weighting factors that are used by blendEquationSeparate.
 func (c *Context) BlendFuncSeparate(srcRGB, dstRGB, srcAlpha, dstAlpha int) {
-	c.Object.Call("blendFuncSeparate", srcRGB, dstRGB, srcAlpha, dstAlpha)
+	if c.state != nil && c.state.blendFunc(srcRGB, dstRGB, srcAlpha, dstAlpha) {
+		return
+	}
+	c.call("blendFuncSeparate", srcRGB, dstRGB, srcAlpha, dstAlpha)
 }
 
 // Creates a buffer in memory and initializes it with array data.
 // If no array is provided, the contents of the buffer is initialized to 0.
 func (c *Context) BufferData(target int, data interface{}, usage int) {
-	c.Object.Call("bufferData", target, data, usage)
+	c.call("bufferData", target, data, usage)
 }
 
 // Used to modify or update some or all of a data store for a bound buffer object.
 func (c *Context) BufferSubData(target int, offset int, data interface{}) {
-	c.Object.Call("bufferSubData", target, offset, data)
+	c.call("bufferSubData", target, offset, data)
 }
 
 // Returns whether the currently bound WebGLFramebuffer is complete.
 // If not complete, returns the reason why.
 func (c *Context) CheckFramebufferStatus(target int) int {
-	return c.Object.Call("checkFramebufferStatus", target).Int()
+	return c.callResult("checkFramebufferStatus", target).Int()
 }
 
 // Sets all pixels in a specific buffer to the same value.
 func (c *Context) Clear(flags int) {
-	c.Object.Call("clear", flags)
+	c.call("clear", flags)
 }
 
 // Specifies color values to use by the clear method to clear the color buffer.
 func (c *Context) ClearColor(r, g, b, a float32) {
-	c.Object.Call("clearColor", r, g, b, a)
+	c.call("clearColor", r, g, b, a)
 }
 
 // Clears the depth buffer to a specific value.
 func (c *Context) ClearDepth(depth float64) {
-	c.Object.Call("clearDepth", depth)
+	c.call("clearDepth", depth)
 }
 
 func (c *Context) ClearStencil(s int) {
-	c.Object.Call("clearStencil", s)
+	c.call("clearStencil", s)
 }
 
 // Lets you set whether individual colors can be written when
 // drawing or rendering to a framebuffer.
 func (c *Context) ColorMask(r, g, b, a bool) {
-	c.Object.Call("colorMask", r, g, b, a)
+	c.call("colorMask", r, g, b, a)
 }
 
 // Compiles the GLSL shader source into binary data used by the WebGLProgram object.
 func (c *Context) CompileShader(shader *js.Value) {
-	c.Object.Call("compileShader", shader)
+	c.call("compileShader", shader)
+}
+
+// Loads compressed image data into a texture. The format has to be one
+// provided by an enabled compressed texture extension.
+func (c *Context) CompressedTexImage2D(target, level, internalFormat, width, height, border int, data interface{}) {
+	c.call("compressedTexImage2D", target, level, internalFormat, width, height, border, typedArrayOf(data))
+}
+
+// Replaces a portion of an existing compressed texture image.
+func (c *Context) CompressedTexSubImage2D(target, level, xoffset, yoffset, width, height, format int, data interface{}) {
+	c.call("compressedTexSubImage2D", target, level, xoffset, yoffset, width, height, format, typedArrayOf(data))
 }
 
 // Copies a rectangle of pixels from the current WebGLFramebuffer into a texture image.
 func (c *Context) CopyTexImage2D(target, level, internal, x, y, w, h, border int) {
-	c.Object.Call("copyTexImage2D", target, level, internal, x, y, w, h, border)
+	c.call("copyTexImage2D", target, level, internal, x, y, w, h, border)
 }
 
 // Replaces a portion of an existing 2D texture image with data from the current framebuffer.
 func (c *Context) CopyTexSubImage2D(target, level, xoffset, yoffset, x, y, w, h int) {
-	c.Object.Call("copyTexSubImage2D", target, level, xoffset, yoffset, x, y, w, h)
+	c.call("copyTexSubImage2D", target, level, xoffset, yoffset, x, y, w, h)
 }
 
 // Creates and initializes a WebGLBuffer.
 func (c *Context) CreateBuffer() *js.Value {
-	z := c.Object.Call("createBuffer")
+	z := c.callResult("createBuffer")
 	return &z
 }
 
 // Creates and initializes a WebGL Array Buffer.
 func (c *Context) CreateArrayBuffer() *js.Value {
-	z := c.Object.Call("createBuffer", ARRAY_BUFFER)
+	z := c.callResult("createBuffer", ARRAY_BUFFER)
 	return &z
 }
 
 // Returns a WebGLFramebuffer object.
 func (c *Context) CreateFramebuffer() *js.Value {
-	z := c.Object.Call("createFramebuffer")
+	z := c.callResult("createFramebuffer")
 	return &z
 }
 
 // Creates an empty WebGLProgram object to which vector and fragment
 // WebGLShader objects can be bound.
 func (c *Context) CreateProgram() *js.Value {
-	z := c.Object.Call("createProgram")
+	z := c.callResult("createProgram")
 	return &z
 }
 
 // Creates and returns a WebGLRenderbuffer object.
 func (c *Context) CreateRenderbuffer() *js.Value {
-	z := c.Object.Call("createRenderbuffer")
+	z := c.callResult("createRenderbuffer")
 	return &z
 }
 
 // Returns an empty vertex or fragment shader object based on the type specified.
 func (c *Context) CreateShader(typ int) *js.Value {
-	z := c.Object.Call("createShader", typ)
+	z := c.callResult("createShader", typ)
 	return &z
 }
 
 // Used to generate a WebGLTexture object to which images can be bound.
 func (c *Context) CreateTexture() *js.Value {
-	z := c.Object.Call("createTexture")
+	z := c.callResult("createTexture")
 	return &z
 }
 
 // Sets whether or not front, back, or both facing facets are able to be culled.
 func (c *Context) CullFace(mode int) {
-	c.Object.Call("cullFace", mode)
+	if c.state != nil && c.state.setInt(&c.state.cullFace, mode) {
+		return
+	}
+	c.call("cullFace", mode)
 }
 
 // Delete a specific buffer.
 func (c *Context) DeleteBuffer(buffer *js.Value) {
-	c.Object.Call("deleteBuffer", buffer)
+	if c.state != nil {
+		c.state.forgetObject(buffer)
+	}
+	c.call("deleteBuffer", buffer)
 }
 
 // Deletes a specific WebGLFramebuffer object. If you delete the
 // currently bound framebuffer, the default framebuffer will be bound.
 // Deleting a framebuffer detaches all of its attachments.
 func (c *Context) DeleteFramebuffer(framebuffer *js.Value) {
-	c.Object.Call("deleteFramebuffer", framebuffer)
+	if c.state != nil {
+		c.state.forgetObject(framebuffer)
+	}
+	c.call("deleteFramebuffer", framebuffer)
 }
 
 // Flags a specific WebGLProgram object for deletion if currently active.
@@ -581,126 +650,156 @@ func (c *Context) DeleteFramebuffer(framebuffer *js.Value) {
 // Any shader objects associated with the program will be detached.
 // They will be deleted if they were already flagged for deletion.
 func (c *Context) DeleteProgram(program *js.Value) {
-	c.Object.Call("deleteProgram", program)
+	if c.state != nil {
+		c.state.forgetObject(program)
+	}
+	c.call("deleteProgram", program)
 }
 
 // Deletes the specified renderbuffer object. If the renderbuffer is
 // currently bound, it will become unbound. If the renderbuffer is
 // attached to the currently bound framebuffer, it is detached.
 func (c *Context) DeleteRenderbuffer(renderbuffer *js.Value) {
-	c.Object.Call("deleteRenderbuffer", renderbuffer)
+	if c.state != nil {
+		c.state.forgetObject(renderbuffer)
+	}
+	c.call("deleteRenderbuffer", renderbuffer)
 }
 
 // Deletes a specific shader object.
 func (c *Context) DeleteShader(shader *js.Value) {
-	c.Object.Call("deleteShader", shader)
+	c.call("deleteShader", shader)
 }
 
 // Deletes a specific texture object.
 func (c *Context) DeleteTexture(texture *js.Value) {
-	c.Object.Call("deleteTexture", texture)
+	if c.state != nil {
+		c.state.forgetObject(texture)
+	}
+	c.call("deleteTexture", texture)
 }
 
 // Sets a function to use to compare incoming pixel depth to the
 // current depth buffer value.
 func (c *Context) DepthFunc(fun int) {
-	c.Object.Call("depthFunc", fun)
+	if c.state != nil && c.state.setInt(&c.state.depthFunc, fun) {
+		return
+	}
+	c.call("depthFunc", fun)
 }
 
 // Sets whether or not you can write to the depth buffer.
 func (c *Context) DepthMask(flag bool) {
-	c.Object.Call("depthMask", flag)
+	if c.state != nil && c.state.setInt(&c.state.depthMask, boolInt(flag)) {
+		return
+	}
+	c.call("depthMask", flag)
 }
 
 // Sets the depth range for normalized coordinates to canvas or viewport depth coordinates.
 func (c *Context) DepthRange(zNear, zFar float64) {
-	c.Object.Call("depthRange", zNear, zFar)
+	c.call("depthRange", zNear, zFar)
 }
 
 // Detach a shader object from a program object.
 func (c *Context) DetachShader(program, shader *js.Value) {
-	c.Object.Call("detachShader", program, shader)
+	c.call("detachShader", program, shader)
 }
 
 // Turns off specific WebGL capabilities for this context.
 func (c *Context) Disable(cap int) {
-	c.Object.Call("disable", cap)
+	if c.state != nil && c.state.setCap(cap, false) {
+		return
+	}
+	c.call("disable", cap)
 }
 
 // Turns off a vertex attribute array at a specific index position.
 func (c *Context) DisableVertexAttribArray(index int) {
-	c.Object.Call("disableVertexAttribArray", index)
+	if c.vao != nil {
+		c.vao.enable(index, false)
+	}
+	c.call("disableVertexAttribArray", index)
 }
 
 // Render geometric primitives from bound and enabled vertex data.
 func (c *Context) DrawArrays(mode, first, count int) {
-	c.Object.Call("drawArrays", mode, first, count)
+	c.call("drawArrays", mode, first, count)
 }
 
 // Renders geometric primitives indexed by element array data.
 func (c *Context) DrawElements(mode, count, typ, offset int) {
-	c.Object.Call("drawElements", mode, count, typ, offset)
+	c.call("drawElements", mode, count, typ, offset)
 }
 
 // Turns on specific WebGL capabilities for this context.
 func (c *Context) Enable(cap int) {
-	c.Object.Call("enable", cap)
+	if c.state != nil && c.state.setCap(cap, true) {
+		return
+	}
+	c.call("enable", cap)
 }
 
 // Turns on a vertex attribute at a specific index position in
 // a vertex attribute array.
 func (c *Context) EnableVertexAttribArray(index int) {
-	c.Object.Call("enableVertexAttribArray", index)
+	if c.vao != nil {
+		c.vao.enable(index, true)
+	}
+	c.call("enableVertexAttribArray", index)
 }
 
 func (c *Context) Finish() {
-	c.Object.Call("finish")
+	c.call("finish")
 }
 
 func (c *Context) Flush() {
-	c.Object.Call("flush")
+	c.call("flush")
 }
 
 // Attaches a WebGLRenderbuffer object as a logical buffer to the
 // currently bound WebGLFramebuffer object.
 func (c *Context) FrameBufferRenderBuffer(target, attachment, renderbufferTarget int, renderbuffer *js.Value) {
-	c.Object.Call("framebufferRenderBuffer", target, attachment, renderbufferTarget, renderbuffer)
+	c.call("framebufferRenderbuffer", target, attachment, renderbufferTarget, renderbuffer)
 }
 
 // Attaches a texture to a WebGLFramebuffer object.
 func (c *Context) FramebufferTexture2D(target, attachment, textarget int, texture *js.Value, level int) {
-	c.Object.Call("framebufferTexture2D", target, attachment, textarget, texture, level)
+	c.call("framebufferTexture2D", target, attachment, textarget, texture, level)
 }
 
 // Sets whether or not polygons are considered front-facing based
 // on their winding direction.
 func (c *Context) FrontFace(mode int) {
-	c.Object.Call("frontFace", mode)
+	if c.state != nil && c.state.setInt(&c.state.frontFace, mode) {
+		return
+	}
+	c.call("frontFace", mode)
 }
 
 // Creates a set of textures for a WebGLTexture object with image
 // dimensions from the original size of the image down to a 1x1 image.
 func (c *Context) GenerateMipmap(target int) {
-	c.Object.Call("generateMipmap", target)
+	c.call("generateMipmap", target)
 }
 
 // Returns an WebGLActiveInfo object containing the size, type, and name
 // of a vertex attribute at a specific index position in a program object.
 func (c *Context) GetActiveAttrib(program *js.Value, index int) *js.Value {
-	z := c.Object.Call("getActiveAttrib", program, index)
+	z := c.callResult("getActiveAttrib", program, index)
 	return &z
 }
 
 // Returns an WebGLActiveInfo object containing the size, type, and name
 // of a uniform attribute at a specific index position in a program object.
 func (c *Context) GetActiveUniform(program *js.Value, index int) *js.Value {
-	z := c.Object.Call("getActiveUniform", program, index)
+	z := c.callResult("getActiveUniform", program, index)
 	return &z
 }
 
 // Returns a slice of WebGLShaders bound to a WebGLProgram.
 func (c *Context) GetAttachedShaders(program *js.Value) []*js.Value {
-	objs := c.Object.Call("getAttachedShaders", program)
+	objs := c.callResult("getAttachedShaders", program)
 	shaders := make([]*js.Value, objs.Length())
 	for i := 0; i < objs.Length(); i++ {
 		z := objs.Index(i)
@@ -711,92 +810,97 @@ func (c *Context) GetAttachedShaders(program *js.Value) []*js.Value {
 
 // Returns an index to the location in a program of a named attribute variable.
 func (c *Context) GetAttribLocation(program *js.Value, name string) int {
-	return c.Object.Call("getAttribLocation", program, name).Int()
+	return c.callResult("getAttribLocation", program, name).Int()
 }
 
 // TODO: Create type specific variations.
 // Returns the type of a parameter for a given buffer.
 func (c *Context) GetBufferParameter(target, pname int) *js.Value {
-	z := c.Object.Call("getBufferParameter", target, pname)
+	z := c.callResult("getBufferParameter", target, pname)
 	return &z
 }
 
 // TODO: Create type specific variations.
 // Returns the natural type value for a constant parameter.
 func (c *Context) GetParameter(pname int) *js.Value {
-	z := c.Object.Call("getParameter", pname)
+	z := c.callResult("getParameter", pname)
 	return &z
 }
 
 // Returns a value for the WebGL error flag and clears the flag.
 func (c *Context) GetError() int {
-	return c.Object.Call("getError").Int()
+	if len(c.savedErrors) > 0 {
+		err := c.savedErrors[0]
+		c.savedErrors = c.savedErrors[1:]
+		return err
+	}
+	return c.callResult("getError").Int()
 }
 
 // TODO: Create type specific variations.
 // Enables a passed extension, otherwise returns null.
 func (c *Context) GetExtension(name string) *js.Value {
-	z := c.Object.Call("getExtension", name)
+	z := c.callResult("getExtension", name)
 	return &z
 }
 
 // TODO: Create type specific variations.
 // Gets a parameter value for a given target and attachment.
 func (c *Context) GetFramebufferAttachmentParameter(target, attachment, pname int) *js.Value {
-	z := c.Object.Call("getFramebufferAttachmentParameter", target, attachment, pname)
+	z := c.callResult("getFramebufferAttachmentParameter", target, attachment, pname)
 	return &z
 }
 
 // Returns the value of the program parameter that corresponds to a supplied pname
 // which is interpreted as an int.
 func (c *Context) GetProgramParameteri(program *js.Value, pname int) int {
-	return c.Object.Call("getProgramParameter", program, pname).Int()
+	return c.callResult("getProgramParameter", program, pname).Int()
 }
 
 // Returns the value of the program parameter that corresponds to a supplied pname
 // which is interpreted as a bool.
 func (c *Context) GetProgramParameterb(program *js.Value, pname int) bool {
-	return c.Object.Call("getProgramParameter", program, pname).Bool()
+	return c.callResult("getProgramParameter", program, pname).Bool()
 }
 
 // Returns information about the last error that occurred during
 // the failed linking or validation of a WebGL program object.
 func (c *Context) GetProgramInfoLog(program *js.Value) string {
-	return c.Object.Call("getProgramInfoLog", program).String()
+	return c.callResult("getProgramInfoLog", program).String()
 }
 
 // TODO: Create type specific variations.
 // Returns a renderbuffer parameter from the currently bound WebGLRenderbuffer object.
 func (c *Context) GetRenderbufferParameter(target, pname int) *js.Value {
-	z := c.Object.Call("getRenderbufferParameter", target, pname)
+	z := c.callResult("getRenderbufferParameter", target, pname)
 	return &z
 }
 
 // TODO: Create type specific variations.
 // Returns the value of the parameter associated with pname for a shader object.
 func (c *Context) GetShaderParameter(shader *js.Value, pname int) *js.Value {
-	z := c.Object.Call("getShaderParameter", shader, pname)
+	z := c.callResult("getShaderParameter", shader, pname)
 	return &z
 }
 
 // Returns the value of the parameter associated with pname for a shader object.
 func (c *Context) GetShaderParameterb(shader *js.Value, pname int) bool {
-	return c.Object.Call("getShaderParameter", shader, pname).Bool()
+	return c.callResult("getShaderParameter", shader, pname).Bool()
 }
 
 // Returns errors which occur when compiling a shader.
 func (c *Context) GetShaderInfoLog(shader *js.Value) string {
-	return c.Object.Call("getShaderInfoLog", shader).String()
+	return c.callResult("getShaderInfoLog", shader).String()
 }
 
 // Returns source code string associated with a shader object.
 func (c *Context) GetShaderSource(shader *js.Value) string {
-	return c.Object.Call("getShaderSource", shader).String()
+	return c.callResult("getShaderSource", shader).String()
 }
 
 // Returns a slice of supported extension strings.
 func (c *Context) GetSupportedExtensions() []string {
-	ext := c.Object.Call("getSupportedExtensions")
+	ext := c.callResult("getSupportedExtensions")
 	extensions := make([]string, ext.Length())
 	for i := 0; i < ext.Length(); i++ {
 		extensions[i] = ext.Index(i).String()
@@ -807,21 +911,21 @@ func (c *Context) GetSupportedExtensions() []string {
 // TODO: Create type specific variations.
 // Returns the value for a parameter on an active texture unit.
 func (c *Context) GetTexParameter(target, pname int) *js.Value {
-	z := c.Object.Call("getTexParameter", target, pname)
+	z := c.callResult("getTexParameter", target, pname)
 	return &z
 }
 
 // TODO: Create type specific variations.
 // Gets the uniform value for a specific location in a program.
 func (c *Context) GetUniform(program, location *js.Value) *js.Value {
-	z := c.Object.Call("getUniform", program, location)
+	z := c.callResult("getUniform", program, location)
 	return &z
 }
 
 // Returns a WebGLUniformLocation object for the location
 // of a uniform variable within a WebGLProgram object.
 func (c *Context) GetUniformLocation(program *js.Value, name string) *js.Value {
-	z := c.Object.Call("getUniformLocation", program, name)
+	z := c.callResult("getUniformLocation", program, name)
 	return &z
 }
 
@@ -829,90 +933,96 @@ func (c *Context) GetUniformLocation(program *js.Value, name string) *js.Value {
 // Returns data for a particular characteristic of a vertex
 // attribute at an index in a vertex attribute array.
 func (c *Context) GetVertexAttrib(index, pname int) *js.Value {
-	z := c.Object.Call("getVertexAttrib", index, pname)
+	z := c.callResult("getVertexAttrib", index, pname)
 	return &z
 }
 
 // Returns the address of a specified vertex attribute.
 func (c *Context) GetVertexAttribOffset(index, pname int) int {
-	return c.Object.Call("getVertexAttribOffset", index, pname).Int()
+	return c.callResult("getVertexAttribOffset", index, pname).Int()
 }
 
 // public function hint(target:GLenum, mode:GLenum) : Void;
 
 // Returns true if buffer is valid, false otherwise.
 func (c *Context) IsBuffer(buffer *js.Value) bool {
-	return c.Object.Call("isBuffer", buffer).Bool()
+	return c.callResult("isBuffer", buffer).Bool()
 }
 
 // Returns whether the WebGL context has been lost.
 func (c *Context) IsContextLost() bool {
-	return c.Object.Call("isContextLost").Bool()
+	return c.callResult("isContextLost").Bool()
 }
 
 // Returns true if buffer is valid, false otherwise.
 func (c *Context) IsFramebuffer(framebuffer *js.Value) bool {
-	return c.Object.Call("isFramebuffer", framebuffer).Bool()
+	return c.callResult("isFramebuffer", framebuffer).Bool()
 }
 
 // Returns true if program object is valid, false otherwise.
 func (c *Context) IsProgram(program *js.Value) bool {
-	return c.Object.Call("isProgram", program).Bool()
+	return c.callResult("isProgram", program).Bool()
 }
 
 // Returns true if buffer is valid, false otherwise.
 func (c *Context) IsRenderbuffer(renderbuffer *js.Value) bool {
-	return c.Object.Call("isRenderbuffer", renderbuffer).Bool()
+	return c.callResult("isRenderbuffer", renderbuffer).Bool()
 }
 
 // Returns true if shader is valid, false otherwise.
 func (c *Context) IsShader(shader *js.Value) bool {
-	return c.Object.Call("isShader", shader).Bool()
+	return c.callResult("isShader", shader).Bool()
 }
 
 // Returns true if texture is valid, false otherwise.
 func (c *Context) IsTexture(texture *js.Value) bool {
-	return c.Object.Call("isTexture", texture).Bool()
+	return c.callResult("isTexture", texture).Bool()
 }
 
 // Returns whether or not a WebGL capability is enabled for this context.
 func (c *Context) IsEnabled(capability int) bool {
-	return c.Object.Call("isEnabled", capability).Bool()
+	if c.state != nil {
+		if enabled, ok := c.state.caps[capability]; ok {
+			c.state.saved++
+			return enabled
+		}
+	}
+	return c.callResult("isEnabled", capability).Bool()
 }
 
 // Sets the width of lines in WebGL.
 func (c *Context) LineWidth(width float64) {
-	c.Object.Call("lineWidth", width)
+	c.call("lineWidth", width)
 }
 
 // Links an attached vertex shader and an attached fragment shader
 // to a program so it can be used by the graphics processing unit (GPU).
 func (c *Context) LinkProgram(program *js.Value) {
-	c.Object.Call("linkProgram", program)
+	c.call("linkProgram", program)
 }
 
 // Sets pixel storage modes for readPixels and unpacking of textures
 // with texImage2D and texSubImage2D.
 func (c *Context) PixelStorei(pname, param int) {
-	c.Object.Call("pixelStorei", pname, param)
+	c.call("pixelStorei", pname, param)
 }
 
 // Sets the implementation-specific units and scale factor
 // used to calculate fragment depth values.
 func (c *Context) PolygonOffset(factor, units float64) {
-	c.Object.Call("polygonOffset", factor, units)
+	c.call("polygonOffset", factor, units)
 }
 
 // TODO: Figure out if pixels should be a slice.
 // Reads pixel data into an ArrayBufferView object from a
 // rectangular area in the color buffer of the active frame buffer.
 func (c *Context) ReadPixels(x, y, width, height, format, typ int, pixels *js.Value) {
-	c.Object.Call("readPixels", x, y, width, height, format, typ, pixels)
+	c.call("readPixels", x, y, width, height, format, typ, pixels)
 }
 
 // Creates or replaces the data store for the currently bound WebGLRenderbuffer object.
 func (c *Context) RenderbufferStorage(target, internalFormat, width, height int) {
-	c.Object.Call("renderbufferStorage", target, internalFormat, width, height)
+	c.call("renderbufferStorage", target, internalFormat, width, height)
 }
 
 //func (c *Context) SampleCoverage(value float64, invert bool) {
@@ -921,12 +1031,15 @@ func (c *Context) RenderbufferStorage(target, internalFormat, width, height int)
 
 // Sets the dimensions of the scissor box.
 func (c *Context) Scissor(x, y, width, height int) {
-	c.Object.Call("scissor", x, y, width, height)
+	if c.state != nil && c.state.setRect(&c.state.scissor, &c.state.scissorKnown, x, y, width, height) {
+		return
+	}
+	c.call("scissor", x, y, width, height)
 }
 
 // Sets and replaces shader source code in a shader object.
 func (c *Context) ShaderSource(shader *js.Value, source string) {
-	c.Object.Call("shaderSource", shader, source)
+	c.call("shaderSource", shader, source)
 }
 
 // public function stencilFunc(func:GLenum, ref:GLint, mask:GLuint) : Void;
@@ -938,57 +1051,69 @@ func (c *Context) ShaderSource(shader *js.Value, source string) {
 
 // Loads the supplied pixel data into a texture.
 func (c *Context) TexImage2D(target, level, internalFormat, format, kind int, image *js.Value) {
-	c.Object.Call("texImage2D", target, level, internalFormat, format, kind, image)
+	c.call("texImage2D", target, level, internalFormat, format, kind, image)
+}
+
+// Loads pixel data of the given size into a texture. Pixels can be a typed
+// array, a Go slice of numbers, or nil to allocate the texture without
+// initialising its contents.
+func (c *Context) TexImage2DPixels(target, level, internalFormat, width, height, border, format, typ int, pixels interface{}) {
+	c.call("texImage2D", target, level, internalFormat, width, height, border, format, typ, typedArrayOf(pixels))
+}
+
+// Sets floating point texture parameters for the current texture unit.
+func (c *Context) TexParameterf(target int, pname int, param float32) {
+	c.call("texParameterf", target, pname, param)
 }
 
 // Sets texture parameters for the current texture unit.
 func (c *Context) TexParameteri(target int, pname int, param int) {
-	c.Object.Call("texParameteri", target, pname, param)
+	c.call("texParameteri", target, pname, param)
 }
 
 // Replaces a portion of an existing 2D texture image with all of another image.
 func (c *Context) TexSubImage2D(target, level, xoffset, yoffset, format, typ int, image *js.Value) {
-	c.Object.Call("texSubImage2D", target, level, xoffset, yoffset, format, typ, image)
+	c.call("texSubImage2D", target, level, xoffset, yoffset, format, typ, image)
 }
 
 // Assigns a floating point value to a uniform variable for the current program object.
 func (c *Context) Uniform1f(location *js.Value, x float32) {
-	c.Object.Call("uniform1f", location, x)
+	c.call("uniform1f", location, x)
 }
 
 // Assigns a integer value to a uniform variable for the current program object.
 func (c *Context) Uniform1i(location *js.Value, x int) {
-	c.Object.Call("uniform1i", location, x)
+	c.call("uniform1i", location, x)
 }
 
 // Assigns 2 floating point values to a uniform variable for the current program object.
 func (c *Context) Uniform2f(location *js.Value, x, y float32) {
-	c.Object.Call("uniform2f", location, x, y)
+	c.call("uniform2f", location, x, y)
 }
 
 // Assigns 2 integer values to a uniform variable for the current program object.
 func (c *Context) Uniform2i(location *js.Value, x, y int) {
-	c.Object.Call("uniform2i", location, x, y)
+	c.call("uniform2i", location, x, y)
 }
 
 // Assigns 3 floating point values to a uniform variable for the current program object.
 func (c *Context) Uniform3f(location *js.Value, x, y, z float32) {
-	c.Object.Call("uniform3f", location, x, y, z)
+	c.call("uniform3f", location, x, y, z)
 }
 
 // Assigns 3 integer values to a uniform variable for the current program object.
 func (c *Context) Uniform3i(location *js.Value, x, y, z int) {
-	c.Object.Call("uniform3i", location, x, y, z)
+	c.call("uniform3i", location, x, y, z)
 }
 
 // Assigns 4 floating point values to a uniform variable for the current program object.
 func (c *Context) Uniform4f(location *js.Value, x, y, z, w float32) {
-	c.Object.Call("uniform4f", location, x, y, z, w)
+	c.call("uniform4f", location, x, y, z, w)
 }
 
 // Assigns 4 integer values to a uniform variable for the current program object.
 func (c *Context) Uniform4i(location *js.Value, x, y, z, w int) {
-	c.Object.Call("uniform4i", location, x, y, z, w)
+	c.call("uniform4i", location, x, y, z, w)
 }
 
 // public function uniform1fv(location:WebGLUniformLocation, v:ArrayAccess<Float>) : Void;
@@ -1003,33 +1128,39 @@ func (c *Context) Uniform4i(location *js.Value, x, y, z, w int) {
 // Sets values for a 2x2 floating point vector matrix into a
 // uniform location as a matrix or a matrix array.
 func (c *Context) UniformMatrix2fv(location *js.Value, transpose bool, value []float32) {
-	c.Object.Call("uniformMatrix2fv", location, transpose, SliceToTypedArray(value))
+	c.call("uniformMatrix2fv", location, transpose, SliceToTypedArray(value))
 }
 
 // Sets values for a 3x3 floating point vector matrix into a
 // uniform location as a matrix or a matrix array.
 func (c *Context) UniformMatrix3fv(location *js.Value, transpose bool, value []float32) {
-	c.Object.Call("uniformMatrix3fv", location, transpose, SliceToTypedArray(value))
+	c.call("uniformMatrix3fv", location, transpose, SliceToTypedArray(value))
 }
 
 // Sets values for a 4x4 floating point vector matrix into a
 // uniform location as a matrix or a matrix array.
 func (c *Context) UniformMatrix4fv(location *js.Value, transpose bool, value []float32) {
-	c.Object.Call("uniformMatrix4fv", location, transpose, SliceToTypedArray(value))
+	c.call("uniformMatrix4fv", location, transpose, SliceToTypedArray(value))
 }
 
 // Set the program object to use for rendering.
 func (c *Context) UseProgram(program *js.Value) {
-	c.Object.Call("useProgram", program)
+	if c.state != nil && c.state.useProgram(program) {
+		return
+	}
+	c.call("useProgram", program)
 }
 
 // Returns whether a given program can run in the current WebGL state.
 func (c *Context) ValidateProgram(program *js.Value) {
-	c.Object.Call("validateProgram", program)
+	c.call("validateProgram", program)
 }
 
 func (c *Context) VertexAttribPointer(index, size, typ int, normal bool, stride int, offset int) {
-	c.Object.Call("vertexAttribPointer", index, size, typ, normal, stride, offset)
+	if c.vao != nil {
+		c.vao.pointer(index, size, typ, normal, stride, offset)
+	}
+	c.call("vertexAttribPointer", index, size, typ, normal, stride, offset)
 }
 
 // public function vertexAttrib1f(indx:GLuint, x:GLfloat) : Void;
@@ -1044,15 +1175,63 @@ func (c *Context) VertexAttribPointer(index, size, typ int, normal bool, stride
 // Represents a rectangular viewable area that contains
 // the rendering results of the drawing buffer.
 func (c *Context) Viewport(x, y, width, height int) {
-	c.Object.Call("viewport", x, y, width, height)
+	if c.state != nil && c.state.setRect(&c.state.viewport, &c.state.viewportKnown, x, y, width, height) {
+		return
+	}
+	c.call("viewport", x, y, width, height)
+}
+
+// Makes a call that does not return anything. In batched mode the call is
+// recorded for the next flush instead, if the method can be batched.
+func (c *Context) call(name string, args ...interface{}) {
+	if c.batch != nil {
+		if op, ok := batchOpIndex[name]; ok {
+			c.batch.record(op, args)
+			if c.batch.full() {
+				c.batch.flush()
+			}
+			return
+		}
+	}
+	c.callResult(name, args...)
+}
+
+// Makes a call straight away and returns its result. In batched mode any
+// recorded calls are run first, so the result reflects them.
+func (c *Context) callResult(name string, args ...interface{}) js.Value {
+	if c.batch != nil {
+		c.batch.flush()
+	}
+	for i, arg := range args {
+		if obj, ok := arg.(*js.Value); ok {
+			args[i] = valueOf(obj)
+		}
+	}
+	return c.Object.Call(name, args...)
 }
 
-// Returns true or false value as a string
-func boolStr(b bool) string {
+// Returns data as a value that can be passed where WebGL expects an
+// ArrayBufferView, converting Go slices to typed arrays.
+func typedArrayOf(data interface{}) interface{} {
+	switch data := data.(type) {
+	case nil, js.Value, *js.Value:
+		return data
+	default:
+		return SliceToTypedArray(data)
+	}
+}
+
+// Returns 1 for true and 0 for false
+func boolInt(b bool) int {
 	if b {
-		return "true"
+		return 1
 	}
-	return "false"
+	return 0
+}
+
+// Returns whether v is null or undefined
+func isNullish(v js.Value) bool {
+	return v.IsNull() || v.IsUndefined()
 }
 
 type SliceHeader struct {